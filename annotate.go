@@ -0,0 +1,65 @@
+package pkcs8
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"time"
+)
+
+// annotation returns the comment lines of Opts.Annotate for priv, encoded
+// into der.
+func annotation(priv interface{}, der []byte) []byte {
+	var b bytes.Buffer
+	info, err := describeContainer(der)
+	if err != nil {
+		info = &KeyInfo{}
+	}
+	var algorithm asn1.ObjectIdentifier
+	if pkey, err := MarshalPKCS8PrivateKey(priv); err == nil {
+		var pki privateKeyInfo
+		if _, err := asn1.Unmarshal(pkey, &pki); err == nil {
+			algorithm = pki.PrivateKeyAlgorithm.Algorithm
+		}
+		zero(pkey)
+	}
+	info.describeKey(Public(priv), algorithm)
+
+	key := info.KeyType
+	if info.Curve != "" {
+		key += " " + info.Curve
+	} else if info.Bits != 0 {
+		key += fmt.Sprintf(" %d bits", info.Bits)
+	}
+	fmt.Fprintf(&b, "# Key: %s\n", key)
+	if pin, err := SPKIPin(priv); err == nil {
+		fmt.Fprintf(&b, "# SPKI SHA-256: %s\n", pin)
+	}
+	fmt.Fprintf(&b, "# Encryption: %s\n", info.encryptionSummary())
+	fmt.Fprintf(&b, "# Created: %s\n", time.Now().UTC().Format(time.RFC3339))
+	return b.Bytes()
+}
+
+// encryptionSummary describes the encryption fields of info on one line.
+func (info *KeyInfo) encryptionSummary() string {
+	if !info.Encrypted {
+		return "none"
+	}
+	s := info.Scheme
+	if info.Cipher != "" {
+		s += " " + info.Cipher
+	}
+	if info.KDF != "" {
+		s += ", " + info.KDF
+	}
+	if info.PRF != "" {
+		s += " " + info.PRF
+	}
+	switch {
+	case info.Iterations != 0:
+		s += fmt.Sprintf(", %d iterations", info.Iterations)
+	case info.ScryptN != 0:
+		s += fmt.Sprintf(", N=%d r=%d p=%d", info.ScryptN, info.ScryptR, info.ScryptP)
+	}
+	return s
+}
@@ -0,0 +1,322 @@
+package pkcs8
+
+import (
+	"encoding/asn1"
+	"errors"
+	"time"
+	"unicode/utf16"
+)
+
+// PKCS#9 attribute types, see RFC 2985.
+var (
+	OIDFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	OIDLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+)
+
+// Microsoft attribute types attached to keys exported from the Windows
+// certificate store. OIDMicrosoftCSPName holds the name of the key's
+// cryptographic service provider and OIDMicrosoftLocalMachineKeyset marks a
+// key of the machine key set rather than the user's.
+var (
+	OIDMicrosoftCSPName            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 17, 1}
+	OIDMicrosoftLocalMachineKeyset = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 17, 2}
+)
+
+// oidMicrosoftKeyAttributes is the arc of the Microsoft key attributes.
+var oidMicrosoftKeyAttributes = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 17}
+
+// oidPrivateArc is the arc of the attributes private to this package. It is
+// derived from the GUID 4e6cda4c-123d-4189-b126-c618ab2a79f1 under
+// 1.2.840.113556.1.8000.2554, the arc Microsoft delegates to identifiers
+// built from a GUID, rather than under the 2.25 arc of ITU-T X.667, whose
+// 128-bit component encoding/asn1 cannot encode.
+var oidPrivateArc = asn1.ObjectIdentifier{1, 2, 840, 113556, 1, 8000, 2554, 20076, 55884, 4669, 16777, 45350, 12982443, 2783729}
+
+// oidKeyLifecycle identifies the key lifecycle attribute.
+var oidKeyLifecycle = append(oidPrivateArc[:len(oidPrivateArc):len(oidPrivateArc)], 1)
+
+// KeyLifecycleOID returns the identifier of the key lifecycle attribute
+// written by SetKeyLifecycle.
+func KeyLifecycleOID() asn1.ObjectIdentifier {
+	return append(asn1.ObjectIdentifier(nil), oidKeyLifecycle...)
+}
+
+// Attribute is an attribute of a PKCS#8 private key, as defined in RFC 5958.
+type Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// ParsePrivateKeyAttributes returns the attributes of a DER-encoded PKCS#8
+// private key, decrypting it first if a password is given.
+// Password can be nil.
+func ParsePrivateKeyAttributes(der []byte, password []byte) ([]Attribute, error) {
+	decryptedKey, kdfParams, err := decryptPrivateKeyInfo(der, password, nil)
+	if err != nil {
+		return nil, err
+	}
+	if kdfParams != nil {
+		defer zero(decryptedKey)
+	}
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(decryptedKey, &info); err != nil {
+		if kdfParams != nil {
+			return nil, errors.New("pkcs8: incorrect password")
+		}
+		return nil, errors.New("pkcs8: invalid private key info")
+	}
+	if kdfParams != nil {
+		// The values point into the decrypted key, which is zeroed.
+		for i := range info.Attributes {
+			for j, v := range info.Attributes[i].Values {
+				full := append([]byte(nil), v.FullBytes...)
+				v.Bytes = full[len(full)-len(v.Bytes):]
+				v.FullBytes = full
+				info.Attributes[i].Values[j] = v
+			}
+		}
+	}
+	return info.Attributes, nil
+}
+
+// MarshalPrivateKeyWithAttributes encodes a private key and the given
+// attributes into DER-encoded PKCS#8 with the given options.
+// Password can be nil.
+func MarshalPrivateKeyWithAttributes(priv interface{}, attrs []Attribute, password []byte, opts *Opts) ([]byte, error) {
+	pkey, err := marshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pkey, err = setPrivateKeyInfoAttributes(pkey, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return encryptPrivateKeyInfo(pkey, password, opts)
+}
+
+// setPrivateKeyInfoAttributes replaces the attributes of a DER-encoded
+// PrivateKeyInfo.
+func setPrivateKeyInfoAttributes(pkey []byte, attrs []Attribute) ([]byte, error) {
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &info); err != nil {
+		return nil, errors.New("pkcs8: invalid private key info")
+	}
+	info.Attributes = attrs
+	return asn1.Marshal(info)
+}
+
+// SetAttribute returns attrs with the attribute of the given type replaced by
+// one holding values, or added if not yet present.
+func SetAttribute(attrs []Attribute, typ asn1.ObjectIdentifier, values ...asn1.RawValue) []Attribute {
+	result := make([]Attribute, 0, len(attrs)+1)
+	for _, attr := range attrs {
+		if !attr.Type.Equal(typ) {
+			result = append(result, attr)
+		}
+	}
+	return append(result, Attribute{Type: typ, Values: values})
+}
+
+// GetAttribute returns the first value of the attribute of the given type.
+func GetAttribute(attrs []Attribute, typ asn1.ObjectIdentifier) (asn1.RawValue, bool) {
+	for _, attr := range attrs {
+		if attr.Type.Equal(typ) && len(attr.Values) > 0 {
+			return attr.Values[0], true
+		}
+	}
+	return asn1.RawValue{}, false
+}
+
+// KeyLifecycle is metadata about the creation and rotation of a key, stored
+// in the attribute identified by KeyLifecycleOID.
+type KeyLifecycle struct {
+	// Created is the time the key was created.
+	Created time.Time
+	// RotateBy is the time by which the key should be rotated, if any.
+	RotateBy time.Time
+	// Origin names the tool or process that created the key.
+	Origin string
+}
+
+type keyLifecycle struct {
+	Created  time.Time `asn1:"generalized"`
+	RotateBy time.Time `asn1:"generalized,optional,explicit,tag:0"`
+	Origin   string    `asn1:"utf8,optional,explicit,tag:1"`
+}
+
+// SetKeyLifecycle returns attrs with the key lifecycle attribute set to l.
+// Times are stored in UTC with a precision of one second.
+func SetKeyLifecycle(attrs []Attribute, l KeyLifecycle) ([]Attribute, error) {
+	if l.Created.IsZero() {
+		return nil, errors.New("pkcs8: key creation time is required")
+	}
+	value := keyLifecycle{
+		Created: l.Created.UTC().Truncate(time.Second),
+		Origin:  l.Origin,
+	}
+	if !l.RotateBy.IsZero() {
+		value.RotateBy = l.RotateBy.UTC().Truncate(time.Second)
+	}
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return SetAttribute(attrs, oidKeyLifecycle, asn1.RawValue{FullBytes: der}), nil
+}
+
+// GetKeyLifecycle returns the key lifecycle attribute from attrs.
+// The boolean is false if the attribute is not present.
+func GetKeyLifecycle(attrs []Attribute) (KeyLifecycle, bool, error) {
+	raw, ok := GetAttribute(attrs, oidKeyLifecycle)
+	if !ok {
+		return KeyLifecycle{}, false, nil
+	}
+	var value keyLifecycle
+	if rest, err := asn1.Unmarshal(raw.FullBytes, &value); err != nil || len(rest) > 0 {
+		return KeyLifecycle{}, true, errors.New("pkcs8: invalid key lifecycle attribute")
+	}
+	return KeyLifecycle{
+		Created:  value.Created,
+		RotateBy: value.RotateBy,
+		Origin:   value.Origin,
+	}, true, nil
+}
+
+// SetFriendlyName returns attrs with the PKCS#9 friendlyName attribute set to
+// name, encoded as a BMPString as PKCS#12 implementations expect.
+func SetFriendlyName(attrs []Attribute, name string) []Attribute {
+	return SetAttribute(attrs, OIDFriendlyName, bmpString(name))
+}
+
+// GetFriendlyName returns the PKCS#9 friendlyName attribute from attrs.
+// The boolean is false if the attribute is not present.
+func GetFriendlyName(attrs []Attribute) (string, bool, error) {
+	raw, ok := GetAttribute(attrs, OIDFriendlyName)
+	if !ok {
+		return "", false, nil
+	}
+	name, err := parseBMPString(raw)
+	if err != nil {
+		return "", true, errors.New("pkcs8: invalid friendlyName attribute")
+	}
+	return name, true, nil
+}
+
+// bmpString encodes s as an ASN.1 BMPString.
+func bmpString(s string) asn1.RawValue {
+	units := utf16.Encode([]rune(s))
+	bmp := make([]byte, 2*len(units))
+	for i, u := range units {
+		bmp[2*i] = byte(u >> 8)
+		bmp[2*i+1] = byte(u)
+	}
+	return asn1.RawValue{
+		Class: asn1.ClassUniversal,
+		Tag:   asn1.TagBMPString,
+		Bytes: bmp,
+	}
+}
+
+// parseBMPString decodes an ASN.1 BMPString.
+func parseBMPString(raw asn1.RawValue) (string, error) {
+	if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagBMPString || len(raw.Bytes)%2 != 0 {
+		return "", errors.New("pkcs8: invalid BMPString")
+	}
+	units := make([]uint16, len(raw.Bytes)/2)
+	for i := range units {
+		units[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// SetLocalKeyID returns attrs with the PKCS#9 localKeyId attribute set to id.
+// PKCS#12 tools use it to match a key with its certificate.
+func SetLocalKeyID(attrs []Attribute, id []byte) []Attribute {
+	return SetAttribute(attrs, OIDLocalKeyID, asn1.RawValue{
+		Class: asn1.ClassUniversal,
+		Tag:   asn1.TagOctetString,
+		Bytes: id,
+	})
+}
+
+// GetLocalKeyID returns the PKCS#9 localKeyId attribute from attrs.
+// The boolean is false if the attribute is not present.
+func GetLocalKeyID(attrs []Attribute) ([]byte, bool, error) {
+	raw, ok := GetAttribute(attrs, OIDLocalKeyID)
+	if !ok {
+		return nil, false, nil
+	}
+	if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagOctetString {
+		return nil, true, errors.New("pkcs8: invalid localKeyId attribute")
+	}
+	return raw.Bytes, true, nil
+}
+
+// SetMicrosoftCSPName returns attrs with the Microsoft CSP name attribute set
+// to name, e.g. "Microsoft Enhanced RSA and AES Cryptographic Provider".
+func SetMicrosoftCSPName(attrs []Attribute, name string) []Attribute {
+	return SetAttribute(attrs, OIDMicrosoftCSPName, bmpString(name))
+}
+
+// GetMicrosoftCSPName returns the Microsoft CSP name attribute from attrs.
+// The boolean is false if the attribute is not present.
+func GetMicrosoftCSPName(attrs []Attribute) (string, bool, error) {
+	raw, ok := GetAttribute(attrs, OIDMicrosoftCSPName)
+	if !ok {
+		return "", false, nil
+	}
+	name, err := parseBMPString(raw)
+	if err != nil {
+		return "", true, errors.New("pkcs8: invalid Microsoft CSP name attribute")
+	}
+	return name, true, nil
+}
+
+// SetMicrosoftLocalMachineKeyset returns attrs with the Microsoft local
+// machine key set attribute added if machine is true, or removed otherwise.
+// Windows imports keys carrying it into the machine key set.
+func SetMicrosoftLocalMachineKeyset(attrs []Attribute, machine bool) []Attribute {
+	if machine {
+		return SetAttribute(attrs, OIDMicrosoftLocalMachineKeyset, asn1.NullRawValue)
+	}
+	return RemoveAttribute(attrs, OIDMicrosoftLocalMachineKeyset)
+}
+
+// IsMicrosoftLocalMachineKeyset reports whether attrs mark a key of the
+// Windows machine key set.
+func IsMicrosoftLocalMachineKeyset(attrs []Attribute) bool {
+	for _, attr := range attrs {
+		if attr.Type.Equal(OIDMicrosoftLocalMachineKeyset) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripMicrosoftAttributes returns attrs without the Microsoft key attributes
+// (1.3.6.1.4.1.311.17.*), for keys that should not carry their Windows key
+// store placement along. They are otherwise preserved by ReEncrypt.
+func StripMicrosoftAttributes(attrs []Attribute) []Attribute {
+	var result []Attribute
+	for _, attr := range attrs {
+		if !hasOIDPrefix(attr.Type, oidMicrosoftKeyAttributes) {
+			result = append(result, attr)
+		}
+	}
+	return result
+}
+
+// RemoveAttribute returns attrs without the attributes of the given type.
+func RemoveAttribute(attrs []Attribute, typ asn1.ObjectIdentifier) []Attribute {
+	var result []Attribute
+	for _, attr := range attrs {
+		if !attr.Type.Equal(typ) {
+			result = append(result, attr)
+		}
+	}
+	return result
+}
+
+func hasOIDPrefix(oid, prefix asn1.ObjectIdentifier) bool {
+	return len(oid) > len(prefix) && oid[:len(prefix)].Equal(prefix)
+}
@@ -0,0 +1,164 @@
+package pkcs8
+
+import (
+	"errors"
+)
+
+// berMaxDepth bounds the nesting of BER values converted by berToDER.
+const berMaxDepth = 32
+
+var errInvalidBER = errors.New("pkcs8: invalid BER encoding")
+
+// berToDER converts a BER encoding, such as produced by Java and some HSMs,
+// into DER: indefinite and non-minimal lengths are replaced by minimal
+// definite lengths and constructed OCTET STRINGs are flattened into
+// primitive ones. SET OF values are not re-sorted, encoding/asn1 does not
+// check their order.
+func berToDER(ber []byte) ([]byte, error) {
+	der, rest, err := berConvert(ber, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("pkcs8: trailing data after BER value")
+	}
+	return der, nil
+}
+
+// berConvert converts the first BER value of b into DER and returns the rest
+// of b.
+func berConvert(b []byte, depth int) ([]byte, []byte, error) {
+	if depth > berMaxDepth {
+		return nil, nil, errInvalidBER
+	}
+	identifier, constructed, _, b, err := berIdentifier(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil, errInvalidBER
+	}
+
+	var content []byte
+	if b[0] == 0x80 {
+		// Indefinite length: the contents end with an end-of-contents marker.
+		if !constructed {
+			return nil, nil, errInvalidBER
+		}
+		b = b[1:]
+		for {
+			if len(b) >= 2 && b[0] == 0 && b[1] == 0 {
+				b = b[2:]
+				break
+			}
+			if len(b) == 0 {
+				return nil, nil, errInvalidBER
+			}
+			var child []byte
+			if child, b, err = berConvert(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			content = append(content, child...)
+		}
+	} else {
+		var length int
+		if length, b, err = berLength(b); err != nil {
+			return nil, nil, err
+		}
+		if length > len(b) {
+			return nil, nil, errInvalidBER
+		}
+		content, b = b[:length], b[length:]
+		if constructed {
+			var children []byte
+			for rest := content; len(rest) > 0; {
+				var child []byte
+				if child, rest, err = berConvert(rest, depth+1); err != nil {
+					return nil, nil, err
+				}
+				children = append(children, child...)
+			}
+			content = children
+		}
+	}
+
+	// A constructed OCTET STRING is the concatenation of its segments.
+	if len(identifier) == 1 && identifier[0] == 0x24 {
+		var octets []byte
+		for rest := content; len(rest) > 0; {
+			_, segConstructed, segTag, r, err := berIdentifier(rest)
+			if err != nil || segConstructed || segTag != 4 {
+				return nil, nil, errInvalidBER
+			}
+			length, r, err := berLength(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			octets = append(octets, r[:length]...)
+			rest = r[length:]
+		}
+		identifier, content = []byte{0x04}, octets
+	}
+
+	der := append(identifier, derLength(len(content))...)
+	return append(der, content...), b, nil
+}
+
+// berIdentifier parses the identifier octets of a BER value.
+func berIdentifier(b []byte) (identifier []byte, constructed bool, tag int, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, false, 0, nil, errInvalidBER
+	}
+	constructed = b[0]&0x20 != 0
+	tag = int(b[0] & 0x1f)
+	n := 1
+	if tag == 0x1f {
+		tag = 0
+		for {
+			if n >= len(b) || n > 4 {
+				return nil, false, 0, nil, errInvalidBER
+			}
+			tag = tag<<7 | int(b[n]&0x7f)
+			n++
+			if b[n-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	identifier = append([]byte(nil), b[:n]...)
+	return identifier, constructed, tag, b[n:], nil
+}
+
+// berLength parses definite length octets.
+func berLength(b []byte) (int, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, errInvalidBER
+	}
+	if b[0] < 0x80 {
+		return int(b[0]), b[1:], nil
+	}
+	n := int(b[0] & 0x7f)
+	if n == 0 || n > 4 || len(b) < 1+n {
+		return 0, nil, errInvalidBER
+	}
+	length := 0
+	for _, c := range b[1 : 1+n] {
+		length = length<<8 | int(c)
+	}
+	if length < 0 || length > len(b)-1-n {
+		return 0, nil, errInvalidBER
+	}
+	return length, b[1+n:], nil
+}
+
+// derLength encodes a length in its minimal DER form.
+func derLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var b []byte
+	for l := length; l > 0; l >>= 8 {
+		b = append([]byte{byte(l)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
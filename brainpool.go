@@ -0,0 +1,262 @@
+package pkcs8
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"math/big"
+	"sync"
+)
+
+var (
+	oidNamedCurveBrainpoolP256r1 = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}
+	oidNamedCurveBrainpoolP384r1 = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}
+	oidNamedCurveBrainpoolP512r1 = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}
+)
+
+var (
+	brainpoolOnce   sync.Once
+	brainpoolP256r1 *weierstrassCurve
+	brainpoolP384r1 *weierstrassCurve
+	brainpoolP512r1 *weierstrassCurve
+)
+
+func init() {
+	registerCurve(oidNamedCurveBrainpoolP256r1, BrainpoolP256r1())
+	registerCurve(oidNamedCurveBrainpoolP384r1, BrainpoolP384r1())
+	registerCurve(oidNamedCurveBrainpoolP512r1, BrainpoolP512r1())
+}
+
+func initBrainpool() {
+	brainpoolP256r1 = newWeierstrassCurve("brainpoolP256r1", 256,
+		"a9fb57dba1eea9bc3e660a909d838d726e3bf623d52620282013481d1f6e5377",
+		"7d5a0975fc2c3057eef67530417affe7fb8055c126dc5c6ce94a4b44f330b5d9",
+		"26dc5c6ce94a4b44f330b5d9bbd77cbf958416295cf7e1ce6bccdc18ff8c07b6",
+		"8bd2aeb9cb7e57cb2c4b482ffc81b7afb9de27e1e3bd23c23a4453bd9ace3262",
+		"547ef835c3dac4fd97f8461a14611dc9c27745132ded8e545c1d54c72f046997",
+		"a9fb57dba1eea9bc3e660a909d838d718c397aa3b561a6f7901e0e82974856a7")
+	brainpoolP384r1 = newWeierstrassCurve("brainpoolP384r1", 384,
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b412b1da197fb71123acd3a729901d1a71874700133107ec53",
+		"7bc382c63d8c150c3c72080ace05afa0c2bea28e4fb22787139165efba91f90f8aa5814a503ad4eb04a8c7dd22ce2826",
+		"04a8c7dd22ce28268b39b55416f0447c2fb77de107dcd2a62e880ea53eeb62d57cb4390295dbc9943ab78696fa504c11",
+		"1d1c64f068cf45ffa2a63a81b7c13f6b8847a3e77ef14fe3db7fcafe0cbd10e8e826e03436d646aaef87b2e247d4af1e",
+		"8abe1d7520f9c2a45cb1eb8e95cfd55262b70b29feec5864e19c054ff99129280e4646217791811142820341263c5315",
+		"8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b31f166e6cac0425a7cf3ab6af6b7fc3103b883202e9046565")
+	brainpoolP512r1 = newWeierstrassCurve("brainpoolP512r1", 512,
+		"aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca703308717d4d9b009bc66842aecda12ae6a380e62881ff2f2d82c68528aa6056583a48f3",
+		"7830a3318b603b89e2327145ac234cc594cbdd8d3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94ca",
+		"3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94cadc083e67984050b75ebae5dd2809bd638016f723",
+		"81aee4bdd82ed9645a21322e9c4c6a9385ed9f70b5d916c1b43b62eef4d0098eff3b1f78e2d0d48d50d1687b93b97d5f7c6d5047406a5e688b352209bcb9f822",
+		"7dde385d566332ecc0eabfa9cf7822fdf209f70024a57b1aa000c55b881f8111b2dcde494a5f485e5bca4bd88a2763aed1ca2b2fa8f0540678cd1e0f3ad80892",
+		"aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca70330870553e5c414ca92619418661197fac10471db1d381085ddaddb58796829ca90069")
+}
+
+// BrainpoolP256r1 returns the brainpoolP256r1 curve of RFC 5639. EC keys on
+// the Brainpool curves are parsed into *ecdsa.PrivateKey and
+// *ecdsa.PublicKey on these curves, and such keys are accepted by the
+// marshal functions.
+//
+// The curves are implemented with math/big, which is not constant time: use
+// them to convert and check keys, or to sign where timing is not
+// observable.
+func BrainpoolP256r1() elliptic.Curve {
+	brainpoolOnce.Do(initBrainpool)
+	return brainpoolP256r1
+}
+
+// BrainpoolP384r1 returns the brainpoolP384r1 curve of RFC 5639, see
+// BrainpoolP256r1.
+func BrainpoolP384r1() elliptic.Curve {
+	brainpoolOnce.Do(initBrainpool)
+	return brainpoolP384r1
+}
+
+// BrainpoolP512r1 returns the brainpoolP512r1 curve of RFC 5639, see
+// BrainpoolP256r1.
+func BrainpoolP512r1() elliptic.Curve {
+	brainpoolOnce.Do(initBrainpool)
+	return brainpoolP512r1
+}
+
+// weierstrassCurve is a short Weierstrass curve y² = x³ + ax + b whose a is
+// not -3, which elliptic.CurveParams assumes. The point at infinity is (0,
+// 0), as in crypto/elliptic.
+type weierstrassCurve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+}
+
+func newWeierstrassCurve(name string, bitSize int, p, a, b, gx, gy, n string) *weierstrassCurve {
+	hex := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 16)
+		return n
+	}
+	return &weierstrassCurve{
+		params: &elliptic.CurveParams{
+			Name:    name,
+			BitSize: bitSize,
+			P:       hex(p),
+			N:       hex(n),
+			B:       hex(b),
+			Gx:      hex(gx),
+			Gy:      hex(gy),
+		},
+		a: hex(a),
+	}
+}
+
+func (c *weierstrassCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *weierstrassCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Add(rhs, c.a)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.params.B)
+	rhs.Mod(rhs, p)
+	lhs := new(big.Int).Mul(y, y)
+	return lhs.Mod(lhs, p).Cmp(rhs) == 0
+}
+
+// jacobianPoint is a point in Jacobian coordinates, (X/Z², Y/Z³), the point
+// at infinity if Z is zero.
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func (c *weierstrassCurve) toJacobian(x, y *big.Int) jacobianPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	return jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (c *weierstrassCurve) fromJacobian(q jacobianPoint) (*big.Int, *big.Int) {
+	if q.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	p := c.params.P
+	zinv := new(big.Int).ModInverse(q.z, p)
+	zinv2 := new(big.Int).Mul(zinv, zinv)
+	x := new(big.Int).Mul(q.x, zinv2)
+	x.Mod(x, p)
+	y := zinv2.Mul(zinv2, zinv)
+	y.Mul(y, q.y)
+	y.Mod(y, p)
+	return x, y
+}
+
+func (c *weierstrassCurve) double(q jacobianPoint) jacobianPoint {
+	p := c.params.P
+	if q.z.Sign() == 0 || q.y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	yy := new(big.Int).Mul(q.y, q.y)
+	yy.Mod(yy, p)
+	// s = 4·x·y², m = 3·x² + a·z⁴
+	s := new(big.Int).Mul(q.x, yy)
+	s.Lsh(s, 2)
+	s.Mod(s, p)
+	zz := new(big.Int).Mul(q.z, q.z)
+	zz.Mod(zz, p)
+	m := new(big.Int).Mul(zz, zz)
+	m.Mul(m, c.a)
+	xx := new(big.Int).Mul(q.x, q.x)
+	m.Add(m, xx.Mul(xx, big.NewInt(3)))
+	m.Mod(m, p)
+	x3 := new(big.Int).Mul(m, m)
+	x3.Sub(x3, new(big.Int).Lsh(s, 1))
+	x3.Mod(x3, p)
+	y3 := new(big.Int).Sub(s, x3)
+	y3.Mul(y3, m)
+	yy.Mul(yy, yy)
+	y3.Sub(y3, yy.Lsh(yy, 3))
+	y3.Mod(y3, p)
+	z3 := new(big.Int).Mul(q.y, q.z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+	return jacobianPoint{x3, y3, z3}
+}
+
+func (c *weierstrassCurve) add(q1, q2 jacobianPoint) jacobianPoint {
+	if q1.z.Sign() == 0 {
+		return q2
+	}
+	if q2.z.Sign() == 0 {
+		return q1
+	}
+	p := c.params.P
+	z1z1 := new(big.Int).Mul(q1.z, q1.z)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(q2.z, q2.z)
+	z2z2.Mod(z2z2, p)
+	u1 := new(big.Int).Mul(q1.x, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(q2.x, z1z1)
+	u2.Mod(u2, p)
+	s1 := new(big.Int).Mul(q1.y, q2.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(q2.y, q1.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.double(q1)
+		}
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	hh := new(big.Int).Mul(h, h)
+	hh.Mod(hh, p)
+	hhh := new(big.Int).Mul(hh, h)
+	hhh.Mod(hhh, p)
+	v := new(big.Int).Mul(u1, hh)
+	v.Mod(v, p)
+	// x3 = r² - h³ - 2·u1·h², y3 = r·(u1·h² - x3) - s1·h³, z3 = z1·z2·h
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hhh)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, s1.Mul(s1, hhh))
+	y3.Mod(y3, p)
+	z3 := new(big.Int).Mul(q1.z, q2.z)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+	return jacobianPoint{x3, y3, z3}
+}
+
+func (c *weierstrassCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.fromJacobian(c.add(c.toJacobian(x1, y1), c.toJacobian(x2, y2)))
+}
+
+func (c *weierstrassCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.fromJacobian(c.double(c.toJacobian(x1, y1)))
+}
+
+func (c *weierstrassCurve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	base := c.toJacobian(x1, y1)
+	q := jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			q = c.double(q)
+			if b>>uint(bit)&1 == 1 {
+				q = c.add(q, base)
+			}
+		}
+	}
+	return c.fromJacobian(q)
+}
+
+func (c *weierstrassCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
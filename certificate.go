@@ -0,0 +1,142 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+)
+
+// CreateCertificateRequest decrypts a DER-encoded PKCS#8 private key with
+// the given password and uses it to sign a PEM-encoded certificate signing
+// request built from template. Password can be nil.
+//
+// The subject, SANs and extensions are taken from template, as with
+// x509.CreateCertificateRequest. RSA, ECDSA and Ed25519 keys are supported.
+func CreateCertificateRequest(der []byte, password []byte, template *x509.CertificateRequest) ([]byte, error) {
+	signer, err := parseSigner(der, password)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, signer)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}), nil
+}
+
+func parseSigner(der []byte, password []byte) (crypto.Signer, error) {
+	key, _, err := ParsePrivateKey(der, password)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("pkcs8: key cannot be used for signing")
+	}
+	return signer, nil
+}
+
+// SelfSignedOpts contains options for creating a self-signed certificate.
+type SelfSignedOpts struct {
+	// Key is the private key to certify. If nil, a new ECDSA P-256 key is
+	// generated.
+	Key crypto.Signer
+	// CommonName is the subject common name of the certificate.
+	CommonName string
+	// DNSNames and IPAddresses are the subject alternative names.
+	DNSNames    []string
+	IPAddresses []net.IP
+	// Lifetime is the validity period of the certificate, starting now.
+	// It defaults to one year.
+	Lifetime time.Duration
+	// IsCA marks the certificate as a certificate authority.
+	IsCA bool
+	// KeyOpts are the options used to encrypt the key. DefaultOpts are used if nil.
+	KeyOpts *Opts
+}
+
+// CreateSelfSigned creates a self-signed certificate for opts.Key, or for a
+// freshly generated key, and returns the key as PEM-encoded PKCS#8 encrypted
+// with password, along with the PEM-encoded certificate.
+// Password can be nil, in which case the key is returned unencrypted.
+func CreateSelfSigned(password []byte, opts SelfSignedOpts) (keyPEM, certPEM []byte, err error) {
+	key := opts.Key
+	if key == nil {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	cert, err := selfSignedCertificate(key, &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err = MarshalPrivateKeyPEM(key, password, opts.KeyOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPEM, cert, nil
+}
+
+// CreateSelfSignedFromKey decrypts a DER-encoded PKCS#8 private key with the
+// given password and returns a PEM-encoded self-signed certificate for it,
+// built as CreateSelfSigned does. opts.Key must be nil and opts.KeyOpts is
+// ignored, the key being left as it is. Password can be nil.
+func CreateSelfSignedFromKey(der []byte, password []byte, opts SelfSignedOpts) ([]byte, error) {
+	if opts.Key != nil {
+		return nil, errors.New("pkcs8: SelfSignedOpts.Key must be nil when the key is given encoded")
+	}
+	signer, err := parseSigner(der, password)
+	if err != nil {
+		return nil, err
+	}
+	return selfSignedCertificate(signer, &opts)
+}
+
+// selfSignedCertificate returns a PEM-encoded self-signed certificate for
+// key built from opts.
+func selfSignedCertificate(key crypto.Signer, opts *SelfSignedOpts) ([]byte, error) {
+	lifetime := opts.Lifetime
+	if lifetime == 0 {
+		lifetime = 365 * 24 * time.Hour
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Minute)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(lifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  opts.IsCA,
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if opts.IsCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), nil
+}
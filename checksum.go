@@ -0,0 +1,128 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// oidChecksum identifies the integrity checksum attribute. Like the key
+// lifecycle attribute, it is private to this package.
+var oidChecksum = append(oidPrivateArc[:len(oidPrivateArc):len(oidPrivateArc)], 2)
+
+// ChecksumOID returns the identifier of the integrity checksum attribute
+// written when Opts.Checksum is set.
+func ChecksumOID() asn1.ObjectIdentifier {
+	return append(asn1.ObjectIdentifier(nil), oidChecksum...)
+}
+
+// errCorrupted is returned when the checksum of a decrypted key does not
+// match: the password was right, but the encrypted data was altered.
+var errCorrupted = errors.New("pkcs8: corrupted file: integrity checksum mismatch")
+
+// checksumAttribute returns the encoding of a checksum attribute whose
+// value is all zeros. The checksum is the last checksumSize bytes.
+func checksumAttribute() []byte {
+	attr, err := asn1.Marshal(Attribute{
+		Type:   oidChecksum,
+		Values: []asn1.RawValue{{Tag: asn1.TagOctetString, Bytes: make([]byte, sha256.Size)}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return attr
+}
+
+// checksumKey derives the HMAC key of the checksum from the key that
+// encrypts the PrivateKeyInfo, so that it costs no extra KDF work.
+func checksumKey(key []byte) []byte {
+	macKey := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("pkcs8 integrity checksum")), macKey); err != nil {
+		panic(err)
+	}
+	return macKey
+}
+
+// addChecksum returns the DER-encoded PrivateKeyInfo pkey with a checksum
+// attribute holding the HMAC-SHA256, keyed from key, of the PrivateKeyInfo
+// with a zero checksum.
+func addChecksum(pkey, key []byte) ([]byte, error) {
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &info); err != nil {
+		return nil, errors.New("pkcs8: invalid private key info")
+	}
+	attr := checksumAttribute()
+	var zeroed Attribute
+	if _, err := asn1.Unmarshal(attr, &zeroed); err != nil {
+		return nil, err
+	}
+	info.Attributes = SetAttribute(info.Attributes, oidChecksum, zeroed.Values...)
+	out, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.Index(out, attr)
+	if i < 0 {
+		return nil, errors.New("pkcs8: checksum attribute not found")
+	}
+	macKey := checksumKey(key)
+	defer zero(macKey)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(out)
+	copy(out[i+len(attr)-sha256.Size:], mac.Sum(nil))
+	return out, nil
+}
+
+// hasChecksum reports whether the DER-encoded PrivateKeyInfo pkey has a
+// checksum attribute.
+func hasChecksum(pkey []byte) bool {
+	attr := checksumAttribute()
+	return bytes.Contains(pkey, attr[:len(attr)-sha256.Size])
+}
+
+// removeChecksum returns pkey without its checksum attribute, which would
+// not match once pkey is encrypted with another key.
+func removeChecksum(pkey []byte) ([]byte, error) {
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &info); err != nil {
+		return nil, errors.New("pkcs8: invalid private key info")
+	}
+	var kept []Attribute
+	for _, attr := range info.Attributes {
+		if !attr.Type.Equal(oidChecksum) {
+			kept = append(kept, attr)
+		}
+	}
+	info.Attributes = kept
+	return asn1.Marshal(info)
+}
+
+// verifyChecksum checks the checksum attribute of the decrypted data, if
+// it has one. It is found by its encoding rather than by parsing the data,
+// so that corruption elsewhere is told apart from a wrong password, which
+// leaves no trace of the attribute. The checksum is zeroed in place while
+// the HMAC is computed.
+func verifyChecksum(decrypted, key []byte) error {
+	attr := checksumAttribute()
+	i := bytes.Index(decrypted, attr[:len(attr)-sha256.Size])
+	if i < 0 || i+len(attr) > len(decrypted) {
+		return nil
+	}
+	sum := decrypted[i+len(attr)-sha256.Size : i+len(attr)]
+	stored := append([]byte(nil), sum...)
+	zero(sum)
+	macKey := checksumKey(key)
+	defer zero(macKey)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(decrypted)
+	copy(sum, stored)
+	if !hmac.Equal(mac.Sum(nil), stored) {
+		return errCorrupted
+	}
+	return nil
+}
@@ -2,10 +2,85 @@ package pkcs8
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/aes"
 	"crypto/cipher"
+	"crypto/subtle"
+	"crypto/x509/pkix"
 	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sort"
 )
 
+// CipherInfo describes a cipher of a registry.
+type CipherInfo struct {
+	// Name is the name of the cipher as returned by OIDName.
+	Name string
+	OID  asn1.ObjectIdentifier
+	// KeySize and IVSize are in bytes.
+	KeySize int
+	IVSize  int
+	// BlockSize is the block size in bytes, or 0 if unknown or the cipher
+	// is a stream cipher.
+	BlockSize int
+	// AEAD reports whether the cipher authenticates the key material.
+	AEAD bool
+	// Legacy reports whether the cipher is marked as legacy.
+	Legacy bool
+}
+
+// newCipherInfo describes c. Ciphers can report their block size and
+// whether they are AEADs by implementing BlockSize() int and AEAD() bool.
+func newCipherInfo(c Cipher, legacy bool) CipherInfo {
+	info := CipherInfo{
+		Name:    OIDName(c.OID()),
+		OID:     c.OID(),
+		KeySize: c.KeySize(),
+		IVSize:  c.IVSize(),
+		Legacy:  legacy,
+	}
+	if b, ok := c.(interface{ BlockSize() int }); ok {
+		info.BlockSize = b.BlockSize()
+	}
+	if a, ok := c.(interface{ AEAD() bool }); ok {
+		info.AEAD = a.AEAD()
+	}
+	return info
+}
+
+// Ciphers describes the ciphers of the default registry, sorted by name.
+func Ciphers() []CipherInfo {
+	return defaultRegistry.Ciphers()
+}
+
+// LookupCipher describes the cipher of the default registry with the given
+// OID.
+func LookupCipher(oid asn1.ObjectIdentifier) (CipherInfo, bool) {
+	return defaultRegistry.LookupCipher(oid)
+}
+
+// Ciphers describes the ciphers of r, sorted by name.
+func (r *Registry) Ciphers() []CipherInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var infos []CipherInfo
+	for oid, newCipher := range r.ciphers {
+		infos = append(infos, newCipherInfo(newCipher(), r.legacy[oid]))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// LookupCipher describes the cipher of r with the given OID.
+func (r *Registry) LookupCipher(oid asn1.ObjectIdentifier) (CipherInfo, bool) {
+	newCipher, ok := r.lookupCipher(oid.String())
+	if !ok {
+		return CipherInfo{}, false
+	}
+	return newCipherInfo(newCipher(), r.IsLegacy(oid)), true
+}
+
 type cipherWithBlock struct {
 	oid      asn1.ObjectIdentifier
 	ivSize   int
@@ -25,6 +100,20 @@ func (c cipherWithBlock) OID() asn1.ObjectIdentifier {
 	return c.oid
 }
 
+// BlockSize returns the block size of the cipher, in bytes.
+func (c cipherWithBlock) BlockSize() int {
+	block, err := c.newBlock(make([]byte, c.keySize))
+	if err != nil {
+		return 0
+	}
+	return block.BlockSize()
+}
+
+// AEAD reports whether the cipher is an AEAD. CBC ciphers are not.
+func (c cipherWithBlock) AEAD() bool {
+	return false
+}
+
 func (c cipherWithBlock) Encrypt(key, iv, plaintext []byte) ([]byte, error) {
 	block, err := c.newBlock(key)
 	if err != nil {
@@ -41,6 +130,14 @@ func (c cipherWithBlock) Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
 	return cbcDecrypt(block, key, iv, ciphertext)
 }
 
+func (c cipherWithBlock) decryptInPlace(key, iv, buf []byte) ([]byte, error) {
+	block, err := c.newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return cbcDecryptInPlace(block, iv, buf)
+}
+
 func cbcEncrypt(block cipher.Block, key, iv, plaintext []byte) ([]byte, error) {
 	mode := cipher.NewCBCEncrypter(block, iv)
 	paddingLen := block.BlockSize() - (len(plaintext) % block.BlockSize())
@@ -52,9 +149,233 @@ func cbcEncrypt(block cipher.Block, key, iv, plaintext []byte) ([]byte, error) {
 }
 
 func cbcDecrypt(block cipher.Block, key, iv, ciphertext []byte) ([]byte, error) {
-	mode := cipher.NewCBCDecrypter(block, iv)
-	plaintext := make([]byte, len(ciphertext))
-	mode.CryptBlocks(plaintext, ciphertext)
-	// TODO: remove padding
+	return cbcDecryptInPlace(block, iv, append([]byte(nil), ciphertext...))
+}
+
+// aeadCipher is implemented by AEAD ciphers that can authenticate
+// additional data, used to bind the encryption parameters to the key, see
+// Opts.BindParameters.
+type aeadCipher interface {
+	encryptWithAAD(key, iv, plaintext, aad []byte) ([]byte, error)
+	decryptWithAAD(key, iv, ciphertext, aad []byte) ([]byte, error)
+}
+
+// inPlaceDecrypter is implemented by ciphers that can decrypt into the
+// buffer holding the ciphertext, saving a copy of the plaintext.
+type inPlaceDecrypter interface {
+	decryptInPlace(key, iv, buf []byte) ([]byte, error)
+}
+
+// cbcDecryptInPlace decrypts buf in place and removes the padding. Buf is
+// zeroed if the padding is invalid.
+func cbcDecryptInPlace(block cipher.Block, iv, buf []byte) ([]byte, error) {
+	blockSize := block.BlockSize()
+	if len(buf) == 0 || len(buf)%blockSize != 0 {
+		return nil, errors.New("pkcs8: invalid ciphertext length")
+	}
+	if len(iv) != blockSize {
+		return nil, errors.New("pkcs8: invalid IV length")
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(buf, buf)
+	plaintext, err := unpad(buf, blockSize)
+	if err != nil {
+		zero(buf)
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// unpad removes PKCS#7 padding.
+func unpad(b []byte, blockSize int) ([]byte, error) {
+	paddingLen := int(b[len(b)-1])
+	if paddingLen == 0 || paddingLen > blockSize || paddingLen > len(b) {
+		return nil, errors.New("pkcs8: invalid padding")
+	}
+	for _, p := range b[len(b)-paddingLen:] {
+		if int(p) != paddingLen {
+			return nil, errors.New("pkcs8: invalid padding")
+		}
+	}
+	return b[:len(b)-paddingLen], nil
+}
+
+// gcmParams are the GCMParameters of RFC 5084, section 3.2.
+type gcmParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+// cipherWithGCM is a block cipher in GCM mode. The ICV is appended to the
+// ciphertext.
+type cipherWithGCM struct {
+	oid       asn1.ObjectIdentifier
+	nonceSize int
+	icvSize   int
+	keySize   int
+	newBlock  func(key []byte) (cipher.Block, error)
+}
+
+func (c cipherWithGCM) IVSize() int {
+	return c.nonceSize
+}
+
+func (c cipherWithGCM) KeySize() int {
+	return c.keySize
+}
+
+func (c cipherWithGCM) OID() asn1.ObjectIdentifier {
+	return c.oid
+}
+
+func (c cipherWithGCM) BlockSize() int {
+	return 16
+}
+
+func (c cipherWithGCM) AEAD() bool {
+	return true
+}
+
+// withNonceSize returns c using nonces of the given size, 12 or 16 bytes.
+func (c cipherWithGCM) withNonceSize(size int) (cipherWithGCM, error) {
+	if size != 12 && size != 16 {
+		return c, errors.New("pkcs8: GCM nonce size must be 12 or 16 bytes")
+	}
+	c.nonceSize = size
+	return c, nil
+}
+
+// aead returns the GCM AEAD with c's nonce size and a 16-byte tag, which is
+// truncated to the ICV size by Encrypt and Decrypt.
+func (c cipherWithGCM) aead(key, nonce []byte) (cipher.AEAD, error) {
+	if len(nonce) == 0 {
+		return nil, errors.New("pkcs8: invalid GCM nonce length")
+	}
+	if c.icvSize < 12 || c.icvSize > 16 {
+		return nil, errors.New("pkcs8: invalid GCM ICV length")
+	}
+	block, err := c.newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, len(nonce))
+}
+
+func (c cipherWithGCM) Encrypt(key, nonce, plaintext []byte) ([]byte, error) {
+	return c.encryptWithAAD(key, nonce, plaintext, nil)
+}
+
+func (c cipherWithGCM) Decrypt(key, nonce, ciphertext []byte) ([]byte, error) {
+	return c.decryptWithAAD(key, nonce, ciphertext, nil)
+}
+
+func (c cipherWithGCM) encryptWithAAD(key, nonce, plaintext, aad []byte) ([]byte, error) {
+	aead, err := c.aead(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+	return sealed[:len(plaintext)+c.icvSize], nil
+}
+
+func (c cipherWithGCM) decryptWithAAD(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := c.aead(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < c.icvSize {
+		return nil, errors.New("pkcs8: invalid ciphertext length")
+	}
+	if c.icvSize == aead.Overhead() {
+		plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+		if err != nil {
+			return nil, errors.New("pkcs8: incorrect password")
+		}
+		return plaintext, nil
+	}
+
+	// crypto/cipher cannot combine a custom nonce size with a truncated
+	// tag: recover the plaintext from the key stream, sealing zeros, then
+	// check the truncated ICV against a full seal of the plaintext.
+	// The key stream is sealed in place so that the plaintext is decrypted
+	// into a single buffer.
+	n := len(ciphertext) - c.icvSize
+	plaintext := make([]byte, n, n+aead.Overhead())
+	plaintext = aead.Seal(plaintext[:0], nonce, plaintext, nil)[:n]
+	xorBytes(plaintext, plaintext, ciphertext)
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+	if subtle.ConstantTimeCompare(sealed[:n+c.icvSize], ciphertext) != 1 {
+		zero(plaintext)
+		return nil, errors.New("pkcs8: incorrect password")
+	}
 	return plaintext, nil
 }
+
+func (c cipherWithGCM) MarshalParameters(nonce []byte) ([]byte, error) {
+	return asn1.Marshal(gcmParams{Nonce: nonce, ICVLen: c.icvSize})
+}
+
+func (c cipherWithGCM) UnmarshalParameters(der []byte) (Cipher, []byte, error) {
+	var params gcmParams
+	if rest, err := asn1.Unmarshal(der, &params); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	if params.ICVLen < 12 || params.ICVLen > 16 {
+		return nil, nil, fmt.Errorf("pkcs8: invalid GCM ICV length %d", params.ICVLen)
+	}
+	c.nonceSize, c.icvSize = len(params.Nonce), params.ICVLen
+	return c, params.Nonce, nil
+}
+
+// parseGCMAsCBC parses encryptionScheme as a CBC cipher if it is labelled
+// as AES-GCM but has an IV as parameters. Versions of this package before
+// AES-GCM was implemented wrote such keys: their AES128GCM, AES192GCM and
+// AES256GCM ciphers encrypted in CBC mode. The OCTET STRING of such an IV
+// cannot be mistaken for GCMParameters, a SEQUENCE, so the callers try this
+// whenever the parameters are not GCMParameters.
+func (r *Registry) parseGCMAsCBC(encryptionScheme pkix.AlgorithmIdentifier) (Cipher, []byte, bool) {
+	newCipher, ok := r.lookupCipher(encryptionScheme.Algorithm.String())
+	if !ok {
+		return nil, nil, false
+	}
+	c, ok := newCipher().(cipherWithGCM)
+	der := encryptionScheme.Parameters.FullBytes
+	if !ok || len(der) == 0 || der[0] != asn1.TagOctetString {
+		return nil, nil, false
+	}
+	var iv []byte
+	if rest, err := asn1.Unmarshal(der, &iv); err != nil || len(rest) != 0 || len(iv) != aes.BlockSize {
+		return nil, nil, false
+	}
+	return cipherWithBlock{oid: c.oid, ivSize: len(iv), keySize: c.keySize, newBlock: c.newBlock}, iv, true
+}
+
+// ParseDecryptedPayload parses a PrivateKeyInfo decrypted outside of this
+// package, e.g. inside an HSM, that still carries the padding of the cipher
+// described by scheme, as returned by LookupCipher. The padding is checked and
+// removed for block ciphers other than AEADs.
+func ParseDecryptedPayload(payload []byte, scheme CipherInfo) (crypto.PrivateKey, error) {
+	if scheme.BlockSize > 0 && !scheme.AEAD {
+		if len(payload) == 0 || len(payload)%scheme.BlockSize != 0 {
+			return nil, errors.New("pkcs8: invalid payload length")
+		}
+		var err error
+		if payload, err = unpad(payload, scheme.BlockSize); err != nil {
+			return nil, err
+		}
+	}
+	pkey, _, changed := normalizeKeyAlgorithm(payload)
+	if changed {
+		defer zero(pkey)
+	}
+	return parsePKCS8PrivateKey(pkey)
+}
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for i < len(dst). a and b must be at
+// least as long as dst.
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
@@ -5,9 +5,7 @@ import (
 	"encoding/asn1"
 )
 
-var (
-	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
-)
+var oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
 
 func init() {
 	RegisterCipher(oidDESEDE3CBC, func() Cipher {
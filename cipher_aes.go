@@ -43,12 +43,14 @@ var AES128CBC = cipherWithBlock{
 	oid:      oidAES128CBC,
 }
 
-// AES128GCM is the 128-bit key AES cipher in GCM mode.
-var AES128GCM = cipherWithBlock{
-	ivSize:   aes.BlockSize,
-	keySize:  16,
-	newBlock: aes.NewCipher,
-	oid:      oidAES128GCM,
+// AES128GCM is the 128-bit key AES cipher in GCM mode with a 12-byte nonce
+// and a 16-byte ICV.
+var AES128GCM = cipherWithGCM{
+	nonceSize: 12,
+	icvSize:   16,
+	keySize:   16,
+	newBlock:  aes.NewCipher,
+	oid:       oidAES128GCM,
 }
 
 // AES192CBC is the 192-bit key AES cipher in CBC mode.
@@ -59,12 +61,14 @@ var AES192CBC = cipherWithBlock{
 	oid:      oidAES192CBC,
 }
 
-// AES192GCM is the 912-bit key AES cipher in GCM mode.
-var AES192GCM = cipherWithBlock{
-	ivSize:   aes.BlockSize,
-	keySize:  24,
-	newBlock: aes.NewCipher,
-	oid:      oidAES192GCM,
+// AES192GCM is the 192-bit key AES cipher in GCM mode with a 12-byte nonce
+// and a 16-byte ICV.
+var AES192GCM = cipherWithGCM{
+	nonceSize: 12,
+	icvSize:   16,
+	keySize:   24,
+	newBlock:  aes.NewCipher,
+	oid:       oidAES192GCM,
 }
 
 // AES256CBC is the 256-bit key AES cipher in CBC mode.
@@ -75,10 +79,12 @@ var AES256CBC = cipherWithBlock{
 	oid:      oidAES256CBC,
 }
 
-// AES256GCM is the 256-bit key AES cipher in GCM mode.
-var AES256GCM = cipherWithBlock{
-	ivSize:   aes.BlockSize,
-	keySize:  32,
-	newBlock: aes.NewCipher,
-	oid:      oidAES256GCM,
+// AES256GCM is the 256-bit key AES cipher in GCM mode with a 12-byte nonce
+// and a 16-byte ICV.
+var AES256GCM = cipherWithGCM{
+	nonceSize: 12,
+	icvSize:   16,
+	keySize:   32,
+	newBlock:  aes.NewCipher,
+	oid:       oidAES256GCM,
 }
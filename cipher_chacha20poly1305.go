@@ -0,0 +1,75 @@
+package pkcs8
+
+import (
+	"encoding/asn1"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// oidChaCha20Poly1305 is id-alg-AEADChaCha20Poly1305 of RFC 8103, whose
+// parameters are the 12-byte nonce as an OCTET STRING.
+var oidChaCha20Poly1305 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 3, 18}
+
+func init() {
+	RegisterCipher(oidChaCha20Poly1305, func() Cipher {
+		return ChaCha20Poly1305
+	})
+}
+
+// ChaCha20Poly1305 is the ChaCha20-Poly1305 AEAD of RFC 8439, with a
+// 12-byte nonce and a 16-byte tag appended to the ciphertext. It is faster
+// than AES-GCM on CPUs without AES instructions.
+var ChaCha20Poly1305 = chaCha20Poly1305{}
+
+type chaCha20Poly1305 struct{}
+
+func (c chaCha20Poly1305) IVSize() int {
+	return chacha20poly1305.NonceSize
+}
+
+func (c chaCha20Poly1305) KeySize() int {
+	return chacha20poly1305.KeySize
+}
+
+func (c chaCha20Poly1305) OID() asn1.ObjectIdentifier {
+	return oidChaCha20Poly1305
+}
+
+func (c chaCha20Poly1305) AEAD() bool {
+	return true
+}
+
+func (c chaCha20Poly1305) Encrypt(key, nonce, plaintext []byte) ([]byte, error) {
+	return c.encryptWithAAD(key, nonce, plaintext, nil)
+}
+
+func (c chaCha20Poly1305) Decrypt(key, nonce, ciphertext []byte) ([]byte, error) {
+	return c.decryptWithAAD(key, nonce, ciphertext, nil)
+}
+
+func (c chaCha20Poly1305) encryptWithAAD(key, nonce, plaintext, aad []byte) ([]byte, error) {
+	if len(nonce) != chacha20poly1305.NonceSize {
+		return nil, errors.New("pkcs8: invalid ChaCha20-Poly1305 nonce length")
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func (c chaCha20Poly1305) decryptWithAAD(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	if len(nonce) != chacha20poly1305.NonceSize {
+		return nil, errors.New("pkcs8: invalid ChaCha20-Poly1305 nonce length")
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, errors.New("pkcs8: incorrect password")
+	}
+	return plaintext, nil
+}
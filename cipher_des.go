@@ -0,0 +1,23 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import "crypto/des"
+
+func init() {
+	RegisterCipher(oidDESCBC, func() Cipher {
+		return DESCBC
+	})
+	defaultRegistry.SetLegacy(oidDESCBC, true)
+}
+
+// DESCBC is the 56-bit key DES cipher in CBC mode.
+// It is a legacy cipher: keys using it are only parsed if
+// ParseOpts.AllowLegacy is set.
+var DESCBC = cipherWithBlock{
+	ivSize:   des.BlockSize,
+	keySize:  8,
+	newBlock: des.NewCipher,
+	oid:      oidDESCBC,
+}
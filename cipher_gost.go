@@ -0,0 +1,258 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"crypto/cipher"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+func init() {
+	RegisterCipher(oidGOST28147, func() Cipher {
+		return GOST28147CFB
+	})
+	defaultRegistry.SetLegacy(oidGOST28147, true)
+}
+
+// GOST28147CFB is GOST 28147-89 in CFB mode, with CryptoPro key meshing
+// for the parameter sets that call for it, as used by CryptoPro exports.
+// It is registered for the id-Gost28147-89 OID and, being a legacy cipher,
+// only parses keys if ParseOpts.AllowLegacy is set.
+var GOST28147CFB Cipher = gost28147{}
+
+// GOST28147CBC is GOST 28147-89 in CBC mode with PKCS#7 padding. The mode
+// is not part of the encryption scheme, so keys exported in CBC mode are
+// parsed by registering it for the id-Gost28147-89 OID in a Registry.
+var GOST28147CBC Cipher = gost28147{cbc: true}
+
+// gost28147 is only supported for decryption. The parameter set defaults to
+// CryptoPro-A until it is read from the encryption scheme.
+type gost28147 struct {
+	cbc      bool
+	paramSet asn1.ObjectIdentifier
+}
+
+// gost28147Params are the Gost28147-89-Parameters of RFC 4357, section 10.4.
+type gost28147Params struct {
+	IV       []byte
+	ParamSet asn1.ObjectIdentifier
+}
+
+func (c gost28147) IVSize() int                { return gost28147BlockSize }
+func (c gost28147) KeySize() int               { return 32 }
+func (c gost28147) AEAD() bool                 { return false }
+func (c gost28147) OID() asn1.ObjectIdentifier { return oidGOST28147 }
+
+func (c gost28147) Encrypt(key, iv, plaintext []byte) ([]byte, error) {
+	return nil, errors.New("pkcs8: GOST 28147-89 is only supported for decryption")
+}
+
+func (c gost28147) Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	paramSet := c.paramSet
+	if paramSet == nil {
+		paramSet = oidGOST28147ParamSetA
+	}
+	params, ok := gost28147ParamSets[paramSet.String()]
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported GOST 28147-89 parameter set %s", paramSet)
+	}
+	block, err := newGOST28147Cipher(key, &params.sbox)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != gost28147BlockSize {
+		return nil, errors.New("pkcs8: invalid IV length")
+	}
+	if c.cbc {
+		return cbcDecrypt(block, key, iv, ciphertext)
+	}
+	return gost28147CFBDecrypt(block, iv, ciphertext, params.keyMeshing), nil
+}
+
+func (c gost28147) MarshalParameters(iv []byte) ([]byte, error) {
+	paramSet := c.paramSet
+	if paramSet == nil {
+		paramSet = oidGOST28147ParamSetA
+	}
+	return asn1.Marshal(gost28147Params{IV: iv, ParamSet: paramSet})
+}
+
+func (c gost28147) UnmarshalParameters(der []byte) (Cipher, []byte, error) {
+	var params gost28147Params
+	if rest, err := asn1.Unmarshal(der, &params); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	if _, ok := gost28147ParamSets[params.ParamSet.String()]; !ok {
+		return nil, nil, fmt.Errorf("pkcs8: unsupported GOST 28147-89 parameter set %s", params.ParamSet)
+	}
+	c.paramSet = params.ParamSet
+	return c, params.IV, nil
+}
+
+// gost28147CFBDecrypt decrypts in CFB mode. With key meshing, the key and
+// the feedback register are replaced after every 1024 bytes as described in
+// RFC 4357, section 2.3.2.
+func gost28147CFBDecrypt(block *gost28147Cipher, iv, ciphertext []byte, keyMeshing bool) []byte {
+	plaintext := make([]byte, len(ciphertext))
+	var register, keystream [gost28147BlockSize]byte
+	copy(register[:], iv)
+	for i := 0; i < len(ciphertext); i += gost28147BlockSize {
+		if keyMeshing && i > 0 && i%1024 == 0 {
+			block = block.meshed()
+			block.Encrypt(register[:], register[:])
+		}
+		block.Encrypt(keystream[:], register[:])
+		n := copy(register[:], ciphertext[i:])
+		for j := 0; j < n; j++ {
+			plaintext[i+j] = ciphertext[i+j] ^ keystream[j]
+		}
+	}
+	return plaintext
+}
+
+const gost28147BlockSize = 8
+
+// gost28147ParamSet is an encryption parameter set of RFC 4357, section
+// 11.2: the S-box and whether CryptoPro key meshing is used.
+type gost28147ParamSet struct {
+	keyMeshing bool
+	sbox       [8][16]byte
+}
+
+var gost28147ParamSets = map[string]*gost28147ParamSet{
+	oidGOST28147TestParamSet.String(): {keyMeshing: false, sbox: [8][16]byte{
+		{0x4, 0x2, 0xf, 0x5, 0x9, 0x1, 0x0, 0x8, 0xe, 0x3, 0xb, 0xc, 0xd, 0x7, 0xa, 0x6},
+		{0xc, 0x9, 0xf, 0xe, 0x8, 0x1, 0x3, 0xa, 0x2, 0x7, 0x4, 0xd, 0x6, 0x0, 0xb, 0x5},
+		{0xd, 0x8, 0xe, 0xc, 0x7, 0x3, 0x9, 0xa, 0x1, 0x5, 0x2, 0x4, 0x6, 0xf, 0x0, 0xb},
+		{0xe, 0x9, 0xb, 0x2, 0x5, 0xf, 0x7, 0x1, 0x0, 0xd, 0xc, 0x6, 0xa, 0x4, 0x3, 0x8},
+		{0x3, 0xe, 0x5, 0x9, 0x6, 0x8, 0x0, 0xd, 0xa, 0xb, 0x7, 0xc, 0x2, 0x1, 0xf, 0x4},
+		{0x8, 0xf, 0x6, 0xb, 0x1, 0x9, 0xc, 0x5, 0xd, 0x3, 0x7, 0xa, 0x0, 0xe, 0x2, 0x4},
+		{0x9, 0xb, 0xc, 0x0, 0x3, 0x6, 0x7, 0x5, 0x4, 0x8, 0xe, 0xf, 0x1, 0xa, 0x2, 0xd},
+		{0xc, 0x6, 0x5, 0x2, 0xb, 0x0, 0x9, 0xd, 0x3, 0xe, 0x7, 0xa, 0xf, 0x4, 0x1, 0x8},
+	}},
+	oidGOST28147ParamSetA.String(): {keyMeshing: true, sbox: [8][16]byte{
+		{0x9, 0x6, 0x3, 0x2, 0x8, 0xb, 0x1, 0x7, 0xa, 0x4, 0xe, 0xf, 0xc, 0x0, 0xd, 0x5},
+		{0x3, 0x7, 0xe, 0x9, 0x8, 0xa, 0xf, 0x0, 0x5, 0x2, 0x6, 0xc, 0xb, 0x4, 0xd, 0x1},
+		{0xe, 0x4, 0x6, 0x2, 0xb, 0x3, 0xd, 0x8, 0xc, 0xf, 0x5, 0xa, 0x0, 0x7, 0x1, 0x9},
+		{0xe, 0x7, 0xa, 0xc, 0xd, 0x1, 0x3, 0x9, 0x0, 0x2, 0xb, 0x4, 0xf, 0x8, 0x5, 0x6},
+		{0xb, 0x5, 0x1, 0x9, 0x8, 0xd, 0xf, 0x0, 0xe, 0x4, 0x2, 0x3, 0xc, 0x7, 0xa, 0x6},
+		{0x3, 0xa, 0xd, 0xc, 0x1, 0x2, 0x0, 0xb, 0x7, 0x5, 0x9, 0x4, 0x8, 0xf, 0xe, 0x6},
+		{0x1, 0xd, 0x2, 0x9, 0x7, 0xa, 0x6, 0x0, 0x8, 0xc, 0x4, 0x5, 0xf, 0x3, 0xb, 0xe},
+		{0xb, 0xa, 0xf, 0x5, 0x0, 0xc, 0xe, 0x8, 0x6, 0x2, 0x3, 0x9, 0x1, 0x7, 0xd, 0x4},
+	}},
+	oidGOST28147ParamSetB.String(): {keyMeshing: true, sbox: [8][16]byte{
+		{0x8, 0x4, 0xb, 0x1, 0x3, 0x5, 0x0, 0x9, 0x2, 0xe, 0xa, 0xc, 0xd, 0x6, 0x7, 0xf},
+		{0x0, 0x1, 0x2, 0xa, 0x4, 0xd, 0x5, 0xc, 0x9, 0x7, 0x3, 0xf, 0xb, 0x8, 0x6, 0xe},
+		{0xe, 0xc, 0x0, 0xa, 0x9, 0x2, 0xd, 0xb, 0x7, 0x5, 0x8, 0xf, 0x3, 0x6, 0x1, 0x4},
+		{0x7, 0x5, 0x0, 0xd, 0xb, 0x6, 0x1, 0x2, 0x3, 0xa, 0xc, 0xf, 0x4, 0xe, 0x9, 0x8},
+		{0x2, 0x7, 0xc, 0xf, 0x9, 0x5, 0xa, 0xb, 0x1, 0x4, 0x0, 0xd, 0x6, 0x8, 0xe, 0x3},
+		{0x8, 0x3, 0x2, 0x6, 0x4, 0xd, 0xe, 0xb, 0xc, 0x1, 0x7, 0xf, 0xa, 0x0, 0x9, 0x5},
+		{0x5, 0x2, 0xa, 0xb, 0x9, 0x1, 0xc, 0x3, 0x7, 0x4, 0xd, 0x0, 0x6, 0xf, 0x8, 0xe},
+		{0x0, 0x4, 0xb, 0xe, 0x8, 0x3, 0x7, 0x1, 0xa, 0x2, 0x9, 0x6, 0xf, 0xd, 0x5, 0xc},
+	}},
+	oidGOST28147ParamSetC.String(): {keyMeshing: true, sbox: [8][16]byte{
+		{0x1, 0xb, 0xc, 0x2, 0x9, 0xd, 0x0, 0xf, 0x4, 0x5, 0x8, 0xe, 0xa, 0x7, 0x6, 0x3},
+		{0x0, 0x1, 0x7, 0xd, 0xb, 0x4, 0x5, 0x2, 0x8, 0xe, 0xf, 0xc, 0x9, 0xa, 0x6, 0x3},
+		{0x8, 0x2, 0x5, 0x0, 0x4, 0x9, 0xf, 0xa, 0x3, 0x7, 0xc, 0xd, 0x6, 0xe, 0x1, 0xb},
+		{0x3, 0x6, 0x0, 0x1, 0x5, 0xd, 0xa, 0x8, 0xb, 0x2, 0x9, 0x7, 0xe, 0xf, 0xc, 0x4},
+		{0x8, 0xd, 0xb, 0x0, 0x4, 0x5, 0x1, 0x2, 0x9, 0x3, 0xc, 0xe, 0x6, 0xf, 0xa, 0x7},
+		{0xc, 0x9, 0xb, 0x1, 0x8, 0xe, 0x2, 0x4, 0x7, 0x3, 0x6, 0x5, 0xa, 0x0, 0xf, 0xd},
+		{0xa, 0x9, 0x6, 0x8, 0xd, 0xe, 0x2, 0x0, 0xf, 0x3, 0x5, 0xb, 0x4, 0x1, 0xc, 0x7},
+		{0x7, 0x4, 0x0, 0x5, 0xa, 0x2, 0xf, 0xe, 0xc, 0x6, 0x1, 0xb, 0xd, 0x9, 0x3, 0x8},
+	}},
+	oidGOST28147ParamSetD.String(): {keyMeshing: true, sbox: [8][16]byte{
+		{0xf, 0xc, 0x2, 0xa, 0x6, 0x4, 0x5, 0x0, 0x7, 0x9, 0xe, 0xd, 0x1, 0xb, 0x8, 0x3},
+		{0xb, 0x6, 0x3, 0x4, 0xc, 0xf, 0xe, 0x2, 0x7, 0xd, 0x8, 0x0, 0x5, 0xa, 0x9, 0x1},
+		{0x1, 0xc, 0xb, 0x0, 0xf, 0xe, 0x6, 0x5, 0xa, 0xd, 0x4, 0x8, 0x9, 0x3, 0x7, 0x2},
+		{0x1, 0x5, 0xe, 0xc, 0xa, 0x7, 0x0, 0xd, 0x6, 0x2, 0xb, 0x4, 0x9, 0x3, 0xf, 0x8},
+		{0x0, 0xc, 0x8, 0x9, 0xd, 0x2, 0xa, 0xb, 0x7, 0x3, 0x6, 0x5, 0x4, 0xe, 0xf, 0x1},
+		{0x8, 0x0, 0xf, 0x3, 0x2, 0x5, 0xe, 0xb, 0x1, 0xa, 0x4, 0x7, 0xc, 0x9, 0xd, 0x6},
+		{0x3, 0x0, 0x6, 0xf, 0x1, 0xe, 0x9, 0x2, 0xd, 0x8, 0xc, 0x4, 0xb, 0xa, 0x5, 0x7},
+		{0x1, 0xa, 0x6, 0x8, 0xf, 0xb, 0x0, 0x4, 0xc, 0x3, 0x5, 0x9, 0x7, 0xd, 0x2, 0xe},
+	}},
+	oidGOST28147ParamSetZ.String(): {keyMeshing: true, sbox: [8][16]byte{
+		{0xc, 0x4, 0x6, 0x2, 0xa, 0x5, 0xb, 0x9, 0xe, 0x8, 0xd, 0x7, 0x0, 0x3, 0xf, 0x1},
+		{0x6, 0x8, 0x2, 0x3, 0x9, 0xa, 0x5, 0xc, 0x1, 0xe, 0x4, 0x7, 0xb, 0xd, 0x0, 0xf},
+		{0xb, 0x3, 0x5, 0x8, 0x2, 0xf, 0xa, 0xd, 0xe, 0x1, 0x7, 0x4, 0xc, 0x9, 0x6, 0x0},
+		{0xc, 0x8, 0x2, 0x1, 0xd, 0x4, 0xf, 0x6, 0x7, 0x0, 0xa, 0x5, 0x3, 0xe, 0x9, 0xb},
+		{0x7, 0xf, 0x5, 0xa, 0x8, 0x1, 0x6, 0xd, 0x0, 0x9, 0x3, 0xe, 0xb, 0x4, 0x2, 0xc},
+		{0x5, 0xd, 0xf, 0x6, 0x9, 0x2, 0xc, 0xa, 0xb, 0x7, 0x8, 0x1, 0x4, 0x3, 0xe, 0x0},
+		{0x8, 0xe, 0x2, 0x5, 0x6, 0x9, 0x1, 0xc, 0xf, 0x4, 0xb, 0x0, 0xd, 0xa, 0x3, 0x7},
+		{0x1, 0x7, 0xe, 0xd, 0x0, 0x5, 0x8, 0x3, 0x4, 0xf, 0xa, 0x6, 0x9, 0xc, 0xb, 0x2},
+	}},
+}
+
+// gost28147MeshingKey is the constant C of RFC 4357, section 2.3.2.
+var gost28147MeshingKey = []byte{
+	0x69, 0x00, 0x72, 0x22, 0x64, 0xc9, 0x04, 0x23,
+	0x8d, 0x3a, 0xdb, 0x96, 0x46, 0xe9, 0x2a, 0xc4,
+	0x18, 0xfe, 0xac, 0x94, 0x00, 0xed, 0x07, 0x12,
+	0xc0, 0x86, 0xdc, 0xc2, 0xef, 0x4c, 0xa9, 0x2b,
+}
+
+// gost28147Cipher is the GOST 28147-89 block cipher with little-endian
+// key and block words, the byte order used by CryptoPro and OpenSSL.
+type gost28147Cipher struct {
+	key  [8]uint32
+	sbox *[8][16]byte
+}
+
+func newGOST28147Cipher(key []byte, sbox *[8][16]byte) (*gost28147Cipher, error) {
+	if len(key) != 32 {
+		return nil, errors.New("pkcs8: invalid GOST 28147-89 key length")
+	}
+	c := &gost28147Cipher{sbox: sbox}
+	for i := range c.key {
+		c.key[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	return c, nil
+}
+
+func (c *gost28147Cipher) BlockSize() int { return gost28147BlockSize }
+
+func (c *gost28147Cipher) f(x uint32) uint32 {
+	var y uint32
+	for i := 0; i < 8; i++ {
+		y |= uint32(c.sbox[i][(x>>(4*i))&0xf]) << (4 * i)
+	}
+	return y<<11 | y>>21
+}
+
+func (c *gost28147Cipher) crypt(dst, src []byte, decrypt bool) {
+	n1 := binary.LittleEndian.Uint32(src[0:4])
+	n2 := binary.LittleEndian.Uint32(src[4:8])
+	for round := 0; round < 32; round++ {
+		// The key words are used in order three times and then in
+		// reverse to encrypt, and in order once and then in reverse
+		// three times to decrypt.
+		k := round % 8
+		if decrypt && round >= 8 || !decrypt && round >= 24 {
+			k = 7 - k
+		}
+		n1, n2 = n2^c.f(n1+c.key[k]), n1
+	}
+	binary.LittleEndian.PutUint32(dst[0:4], n2)
+	binary.LittleEndian.PutUint32(dst[4:8], n1)
+}
+
+func (c *gost28147Cipher) Encrypt(dst, src []byte) { c.crypt(dst, src, false) }
+func (c *gost28147Cipher) Decrypt(dst, src []byte) { c.crypt(dst, src, true) }
+
+// meshed returns the cipher under the CryptoPro meshed key, the decryption
+// of gost28147MeshingKey under the current key.
+func (c *gost28147Cipher) meshed() *gost28147Cipher {
+	key := make([]byte, 32)
+	for i := 0; i < len(key); i += gost28147BlockSize {
+		c.Decrypt(key[i:], gost28147MeshingKey[i:])
+	}
+	next, _ := newGOST28147Cipher(key, c.sbox)
+	return next
+}
+
+var _ cipher.Block = (*gost28147Cipher)(nil)
@@ -0,0 +1,121 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+func init() {
+	RegisterCipher(oidRC2CBC, func() Cipher {
+		return RC2CBC
+	})
+	defaultRegistry.SetLegacy(oidRC2CBC, true)
+}
+
+// RC2CBC is the RC2 cipher in CBC mode with a 128-bit key. Keys encrypted
+// with other key sizes and effective key lengths are decrypted according to
+// their parameters; use NewRC2CBC to encrypt with them. It is a legacy
+// cipher: keys using it are only parsed if ParseOpts.AllowLegacy is set.
+var RC2CBC Cipher = rc2CBC{keySize: 16, effectiveBits: 128}
+
+// NewRC2CBC returns the RC2 cipher in CBC mode with a keySize-byte key and
+// the given effective key length in bits, e.g. 5 and 40 for OpenSSL's
+// rc2-40-cbc.
+func NewRC2CBC(keySize, effectiveBits int) (Cipher, error) {
+	if keySize < 1 || keySize > 128 {
+		return nil, errors.New("pkcs8: invalid RC2 key size")
+	}
+	if _, err := rc2ParameterVersion(effectiveBits); err != nil {
+		return nil, err
+	}
+	return rc2CBC{keySize: keySize, effectiveBits: effectiveBits}, nil
+}
+
+type rc2CBC struct {
+	keySize       int
+	effectiveBits int
+}
+
+// rc2CBCParams are the RC2-CBC-Parameter of RFC 2898, appendix B.2.3.
+type rc2CBCParams struct {
+	Version int `asn1:"optional"`
+	IV      []byte
+}
+
+// rc2Versions maps the effective key lengths in use to their parameter
+// version, see RFC 2268, section 6. Lengths of 256 bits and more are their
+// own version.
+var rc2Versions = map[int]int{40: 160, 56: 52, 64: 120, 128: 58}
+
+func rc2ParameterVersion(effectiveBits int) (int, error) {
+	if effectiveBits >= 256 && effectiveBits <= 1024 {
+		return effectiveBits, nil
+	}
+	if version, ok := rc2Versions[effectiveBits]; ok {
+		return version, nil
+	}
+	return 0, fmt.Errorf("pkcs8: unsupported RC2 effective key length %d", effectiveBits)
+}
+
+func (c rc2CBC) IVSize() int                { return rc2BlockSize }
+func (c rc2CBC) KeySize() int               { return c.keySize }
+func (c rc2CBC) BlockSize() int             { return rc2BlockSize }
+func (c rc2CBC) AEAD() bool                 { return false }
+func (c rc2CBC) OID() asn1.ObjectIdentifier { return oidRC2CBC }
+
+// keySizes returns the key sizes RC2 accepts, see RFC 2268, section 2.
+func (c rc2CBC) keySizes() (min, max int) { return 1, 128 }
+
+func (c rc2CBC) Encrypt(key, iv, plaintext []byte) ([]byte, error) {
+	block, err := newRC2Cipher(key, c.effectiveBits)
+	if err != nil {
+		return nil, err
+	}
+	return cbcEncrypt(block, key, iv, plaintext)
+}
+
+func (c rc2CBC) Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := newRC2Cipher(key, c.effectiveBits)
+	if err != nil {
+		return nil, err
+	}
+	return cbcDecrypt(block, key, iv, ciphertext)
+}
+
+func (c rc2CBC) MarshalParameters(iv []byte) ([]byte, error) {
+	version, err := rc2ParameterVersion(c.effectiveBits)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(rc2CBCParams{Version: version, IV: iv})
+}
+
+func (c rc2CBC) UnmarshalParameters(der []byte) (Cipher, []byte, error) {
+	var params rc2CBCParams
+	if rest, err := asn1.Unmarshal(der, &params); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	// An absent version means an effective key length of 32 bits.
+	c.effectiveBits = 32
+	switch {
+	case params.Version >= 256:
+		c.effectiveBits = params.Version
+	case params.Version > 0:
+		c.effectiveBits = 0
+		for bits, version := range rc2Versions {
+			if version == params.Version {
+				c.effectiveBits = bits
+			}
+		}
+		if c.effectiveBits == 0 {
+			return nil, nil, fmt.Errorf("pkcs8: unsupported RC2 parameter version %d", params.Version)
+		}
+	}
+	return c, params.IV, nil
+}
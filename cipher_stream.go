@@ -0,0 +1,70 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/asn1"
+	"errors"
+)
+
+func init() {
+	for _, c := range []cipherWithStream{
+		{oid: oidAES128OFB, keySize: 16, newStream: cipher.NewOFB},
+		{oid: oidAES128CFB, keySize: 16, newStream: cipher.NewCFBDecrypter},
+		{oid: oidAES192OFB, keySize: 24, newStream: cipher.NewOFB},
+		{oid: oidAES192CFB, keySize: 24, newStream: cipher.NewCFBDecrypter},
+		{oid: oidAES256OFB, keySize: 32, newStream: cipher.NewOFB},
+		{oid: oidAES256CFB, keySize: 32, newStream: cipher.NewCFBDecrypter},
+	} {
+		c := c
+		RegisterCipher(c.oid, func() Cipher {
+			return c
+		})
+		defaultRegistry.SetLegacy(c.oid, true)
+	}
+}
+
+// cipherWithStream is AES in a feedback mode, as emitted by some appliances
+// in PBES2 schemes: the IV is the parameter and the data is not padded. It
+// is only supported for parsing, and only if ParseOpts.AllowLegacy is set.
+type cipherWithStream struct {
+	oid       asn1.ObjectIdentifier
+	keySize   int
+	newStream func(block cipher.Block, iv []byte) cipher.Stream
+}
+
+func (c cipherWithStream) IVSize() int {
+	return aes.BlockSize
+}
+
+func (c cipherWithStream) KeySize() int {
+	return c.keySize
+}
+
+func (c cipherWithStream) OID() asn1.ObjectIdentifier {
+	return c.oid
+}
+
+func (c cipherWithStream) AEAD() bool {
+	return false
+}
+
+func (c cipherWithStream) Encrypt(key, iv, plaintext []byte) ([]byte, error) {
+	return nil, errors.New("pkcs8: AES-CFB and AES-OFB are only supported for decryption")
+}
+
+func (c cipherWithStream) Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("pkcs8: invalid IV length")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	c.newStream(block, iv).XORKeyStream(plaintext, ciphertext)
+	return plaintext, nil
+}
@@ -0,0 +1,28 @@
+package pkcs8
+
+import (
+	"crypto"
+	"errors"
+)
+
+// SameKeyMaterial reports whether a and b contain the same private key.
+// Each input can be in any format accepted by ParsePrivateKeyPEM, encrypted
+// with its own password, so keys can be compared across formats and
+// encryption parameters. Passwords can be nil.
+func SameKeyMaterial(a, b []byte, passA, passB []byte) (bool, error) {
+	keyA, _, err := ParsePrivateKeyPEM(a, passA)
+	if err != nil {
+		return false, err
+	}
+	keyB, _, err := ParsePrivateKeyPEM(b, passB)
+	if err != nil {
+		return false, err
+	}
+	k, ok := keyA.(interface {
+		Equal(crypto.PrivateKey) bool
+	})
+	if !ok {
+		return false, errors.New("pkcs8: unsupported key type for comparison")
+	}
+	return k.Equal(keyB), nil
+}
@@ -0,0 +1,295 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE key parameters and values, see RFC 9052 and RFC 9053.
+const (
+	coseKeyType  = 1
+	coseAlg      = 3
+	coseCurve    = -1
+	coseX        = -2
+	coseY        = -3
+	coseD        = -4
+	coseKtyOKP   = 1
+	coseKtyEC2   = 2
+	coseP256     = 1
+	coseP384     = 2
+	coseP521     = 3
+	coseEd25519  = 6
+	coseAlgES256 = -7
+	coseAlgES384 = -35
+	coseAlgES512 = -36
+	coseAlgEdDSA = -8
+)
+
+// MarshalCOSEKey encodes a private key as a CBOR COSE_Key (RFC 9052), as used
+// by WebAuthn/FIDO2 and CTAP tooling. ECDSA keys on P-256, P-384 and P-521
+// are encoded as EC2 keys and Ed25519 keys as OKP keys. The "alg" parameter
+// is set to the matching signature algorithm.
+//
+// The output is NOT encrypted; use MarshalPrivateKey to archive the key.
+func MarshalCOSEKey(priv interface{}) ([]byte, error) {
+	var params []cborPair
+	switch k := priv.(type) {
+	case *ecdsa.PrivateKey:
+		var crv, alg int64
+		switch k.Curve {
+		case elliptic.P256():
+			crv, alg = coseP256, coseAlgES256
+		case elliptic.P384():
+			crv, alg = coseP384, coseAlgES384
+		case elliptic.P521():
+			crv, alg = coseP521, coseAlgES512
+		default:
+			return nil, errors.New("pkcs8: unsupported curve for COSE")
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		params = []cborPair{
+			{coseKeyType, int64(coseKtyEC2)},
+			{coseAlg, alg},
+			{coseCurve, crv},
+			{coseX, k.X.FillBytes(make([]byte, size))},
+			{coseY, k.Y.FillBytes(make([]byte, size))},
+			{coseD, k.D.FillBytes(make([]byte, size))},
+		}
+	case ed25519.PrivateKey:
+		params = []cborPair{
+			{coseKeyType, int64(coseKtyOKP)},
+			{coseAlg, int64(coseAlgEdDSA)},
+			{coseCurve, int64(coseEd25519)},
+			{coseX, []byte(k.Public().(ed25519.PublicKey))},
+			{coseD, k.Seed()},
+		}
+	default:
+		return nil, errors.New("pkcs8: unsupported key type for COSE")
+	}
+	return cborEncodeMap(params), nil
+}
+
+// ParseCOSEKey decodes a CBOR COSE_Key holding an EC2 (P-256, P-384, P-521)
+// or OKP (Ed25519) private key into an *ecdsa.PrivateKey or
+// ed25519.PrivateKey, which can then be encrypted with MarshalPrivateKey.
+// The public key x, if present, must match the one derived from d.
+func ParseCOSEKey(data []byte) (crypto.PrivateKey, error) {
+	v, rest, err := cborDecode(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("pkcs8: trailing data after COSE_Key")
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("pkcs8: COSE_Key is not a map")
+	}
+	intParam := func(label int64) (int64, bool) {
+		v, ok := m[label].(int64)
+		return v, ok
+	}
+	bytesParam := func(label int64) ([]byte, bool) {
+		v, ok := m[label].([]byte)
+		return v, ok
+	}
+
+	kty, _ := intParam(coseKeyType)
+	crv, _ := intParam(coseCurve)
+	d, ok := bytesParam(coseD)
+	if !ok {
+		return nil, errors.New("pkcs8: COSE_Key has no private key")
+	}
+	switch kty {
+	case coseKtyEC2:
+		var curve elliptic.Curve
+		switch crv {
+		case coseP256:
+			curve = elliptic.P256()
+		case coseP384:
+			curve = elliptic.P384()
+		case coseP521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("pkcs8: unsupported COSE EC2 curve %d", crv)
+		}
+		if len(d) != (curve.Params().BitSize+7)/8 {
+			return nil, errors.New("pkcs8: invalid COSE EC2 private key")
+		}
+		key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+		key.Curve = curve
+		key.X, key.Y = curve.ScalarBaseMult(d)
+		if x, ok := bytesParam(coseX); ok && new(big.Int).SetBytes(x).Cmp(key.X) != 0 {
+			return nil, errors.New("pkcs8: COSE_Key public key does not match private key")
+		}
+		return key, nil
+	case coseKtyOKP:
+		if crv != coseEd25519 {
+			return nil, fmt.Errorf("pkcs8: unsupported COSE OKP curve %d", crv)
+		}
+		if len(d) != ed25519.SeedSize {
+			return nil, errors.New("pkcs8: invalid COSE Ed25519 private key")
+		}
+		key := ed25519.NewKeyFromSeed(d)
+		if x, ok := bytesParam(coseX); ok && !bytes.Equal(x, key.Public().(ed25519.PublicKey)) {
+			return nil, errors.New("pkcs8: COSE_Key public key does not match private key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("pkcs8: unsupported COSE key type %d", kty)
+}
+
+// cborPair is an entry of a CBOR map with an integer key.
+type cborPair struct {
+	key   int64
+	value interface{}
+}
+
+// cborEncodeMap encodes a map with integer keys and integer or byte string
+// values. Pairs must be given in deterministic order (RFC 8949, section 4.2).
+func cborEncodeMap(pairs []cborPair) []byte {
+	b := cborHead(nil, 5, uint64(len(pairs)))
+	for _, p := range pairs {
+		b = cborEncodeInt(b, p.key)
+		switch v := p.value.(type) {
+		case int64:
+			b = cborEncodeInt(b, v)
+		case []byte:
+			b = cborHead(b, 2, uint64(len(v)))
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+func cborEncodeInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return cborHead(b, 1, uint64(-1-v))
+	}
+	return cborHead(b, 0, uint64(v))
+}
+
+func cborHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= 0xff:
+		return append(b, major|24, byte(n))
+	case n <= 0xffff:
+		return append(b, major|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], n)
+	return append(append(b, major|27), v[:]...)
+}
+
+// cborMaxDepth bounds the nesting of decoded CBOR items.
+const cborMaxDepth = 16
+
+// cborDecode decodes a single CBOR data item with definite lengths into
+// int64, []byte, string, bool, nil, []interface{} or
+// map[interface{}]interface{} values. Tags are skipped.
+func cborDecode(b []byte, depth int) (interface{}, []byte, error) {
+	errInvalid := errors.New("pkcs8: invalid CBOR data")
+	if depth > cborMaxDepth || len(b) == 0 {
+		return nil, nil, errInvalid
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(b) < size {
+			return nil, nil, errInvalid
+		}
+		for _, c := range b[:size] {
+			n = n<<8 | uint64(c)
+		}
+		b = b[size:]
+	default:
+		return nil, nil, errInvalid
+	}
+
+	switch major {
+	case 0, 1:
+		if n > 1<<63-1 {
+			return nil, nil, errInvalid
+		}
+		if major == 1 {
+			return -1 - int64(n), b, nil
+		}
+		return int64(n), b, nil
+	case 2, 3:
+		if uint64(len(b)) < n {
+			return nil, nil, errInvalid
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return append([]byte(nil), b[:n]...), b[n:], nil
+	case 4:
+		if n > uint64(len(b)) {
+			return nil, nil, errInvalid
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			var err error
+			if item, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+	case 5:
+		if n > uint64(len(b)) {
+			return nil, nil, errInvalid
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value interface{}
+			var err error
+			if key, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errInvalid
+			}
+			if value, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, b, nil
+	case 6:
+		return cborDecode(b, depth+1)
+	case 7:
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		}
+	}
+	return nil, nil, errInvalid
+}
@@ -0,0 +1,145 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KDFCost is the estimated cost of deriving the key of an encrypted key.
+type KDFCost struct {
+	// Duration is the expected wall time of the derivation.
+	Duration time.Duration
+	// Memory is the number of bytes allocated by the derivation, besides a
+	// few kilobytes of fixed overhead. It is zero for PBKDF2 and the PKCS#12
+	// KDF.
+	Memory int64
+}
+
+// EstimateKDFCost estimates the cost of deriving the key described by info,
+// as returned by Inspect for an encrypted key, on the current machine.
+//
+// The derivation is not run: the time of a small derivation with the same
+// KDF and PRF is measured once per process and scaled to the parameters of
+// the key. Estimates for scrypt with more memory than the CPU caches tend to
+// be low.
+func EstimateKDFCost(info *KeyInfo) (KDFCost, error) {
+	if !info.Encrypted {
+		return KDFCost{}, errors.New("pkcs8: key is not encrypted")
+	}
+	switch {
+	case info.KDF == "PKCS12KDF":
+		oid, _ := oidFromName(info.Scheme)
+		scheme, ok := defaultRegistry.lookupPBE(oid)
+		if !ok {
+			return KDFCost{}, fmt.Errorf("pkcs8: unsupported encryption scheme %s", info.Scheme)
+		}
+		// The key and the IV are derived separately, one SHA-1 block each.
+		blocks := blocksOf(scheme.keySize, sha1.Size)
+		if scheme.ivSize > 0 {
+			blocks += blocksOf(scheme.ivSize, sha1.Size)
+		}
+		return KDFCost{Duration: scaleCost("pkcs12", float64(info.Iterations)*float64(blocks), calibratePKCS12KDF)}, nil
+	case info.KDF == OIDName(oidPKCS5PBKDF2):
+		h := crypto.SHA1
+		if prf, ok := oidFromName(info.PRF); ok && !prf.Equal(oidHMACWithSHA1) {
+			prfMu.RLock()
+			h, ok = prfHashes[prf.String()]
+			prfMu.RUnlock()
+			if !ok || !h.Available() {
+				return KDFCost{}, errors.New("pkcs8: unsupported hash function")
+			}
+		}
+		cipherOID, _ := oidFromName(info.Cipher)
+		newCipher, ok := defaultRegistry.lookupCipher(cipherOID.String())
+		if !ok {
+			return KDFCost{}, fmt.Errorf("pkcs8: unsupported cipher %s", info.Cipher)
+		}
+		blocks := blocksOf(newCipher().KeySize(), h.Size())
+		units := float64(info.Iterations) * float64(blocks)
+		return KDFCost{Duration: scaleCost("pbkdf2-"+h.String(), units, func() float64 {
+			return calibratePBKDF2(h)
+		})}, nil
+	case info.KDF == OIDName(oidScrypt):
+		if _, ok := defaultRegistry.lookupKDF(oidScrypt.String()); !ok {
+			break
+		}
+		n, r, p := int64(info.ScryptN), int64(info.ScryptR), int64(info.ScryptP)
+		if n <= 1 || r <= 0 || p <= 0 {
+			return KDFCost{}, errors.New("pkcs8: invalid scrypt parameters")
+		}
+		return KDFCost{
+			Duration: scaleCost("scrypt", float64(n)*float64(r)*float64(p), calibrateScrypt),
+			// x/crypto/scrypt allocates the blocks B and V and the work
+			// buffer XY.
+			Memory: 128 * r * (n + p + 2),
+		}, nil
+	}
+	return KDFCost{}, fmt.Errorf("pkcs8: unsupported KDF %s", info.KDF)
+}
+
+// oidFromName returns the OID of a name returned by OIDName, which is the
+// dotted form for unknown OIDs.
+func oidFromName(name string) (asn1.ObjectIdentifier, bool) {
+	if oid, ok := LookupOID(name); ok {
+		return oid, true
+	}
+	return parseDottedOID(name)
+}
+
+// blocksOf returns the number of size-byte blocks needed for n bytes.
+func blocksOf(n, size int) int {
+	return (n + size - 1) / size
+}
+
+// kdfUnitCosts caches the measured time, in nanoseconds, of one unit of work
+// of each KDF: an iteration computing one output block for PBKDF2 and the
+// PKCS#12 KDF, and N·r·p = 1 for scrypt.
+var (
+	kdfUnitCostsMu sync.Mutex
+	kdfUnitCosts   = map[string]float64{}
+)
+
+// scaleCost returns the time of units of work of the KDF named name,
+// measuring the cost of one unit with calibrate on first use.
+func scaleCost(name string, units float64, calibrate func() float64) time.Duration {
+	kdfUnitCostsMu.Lock()
+	unit, ok := kdfUnitCosts[name]
+	if !ok {
+		unit = calibrate()
+		kdfUnitCosts[name] = unit
+	}
+	kdfUnitCostsMu.Unlock()
+	return time.Duration(units * unit)
+}
+
+// Calibration derivations take a few milliseconds at most.
+const (
+	calibrationIterations = 4096
+	calibrationScryptN    = 4096
+	calibrationScryptR    = 8
+)
+
+func calibratePBKDF2(h crypto.Hash) float64 {
+	start := time.Now()
+	pbkdf2.Key([]byte("password"), make([]byte, 16), calibrationIterations, h.Size(), h.New)
+	return float64(time.Since(start)) / calibrationIterations
+}
+
+func calibratePKCS12KDF() float64 {
+	start := time.Now()
+	pkcs12KDF([]byte("password"), make([]byte, 8), calibrationIterations, 1, sha1.Size)
+	return float64(time.Since(start)) / calibrationIterations
+}
+
+func calibrateScrypt() float64 {
+	start := time.Now()
+	scryptKey([]byte("password"), make([]byte, 16), calibrationScryptN, calibrationScryptR, 1, 32)
+	return float64(time.Since(start)) / (calibrationScryptN * calibrationScryptR)
+}
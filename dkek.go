@@ -0,0 +1,193 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SmartCard-HSM key types of DKEK-wrapped key blobs.
+const (
+	dkekKeyTypeRSA    = 5 // not supported
+	dkekKeyTypeRSACRT = 6
+	dkekKeyTypeECC    = 12
+)
+
+// ParseDKEKWrappedKey decodes a key blob exported from a SmartCard-HSM or
+// Nitrokey HSM with "wrap-key", given the DKEK (device key encryption key)
+// shares the device was initialized with. It returns an *rsa.PrivateKey or
+// an *ecdsa.PrivateKey on P-224, P-256, P-384 or P-521, which can then be
+// encrypted with MarshalPrivateKey.
+//
+// Shares are the 32-byte plain DKEK shares, i.e. already decrypted from
+// their .pbe files.
+func ParseDKEKWrappedKey(blob []byte, shares ...[]byte) (crypto.PrivateKey, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("pkcs8: at least one DKEK share is required")
+	}
+	dkek := make([]byte, 32)
+	defer zero(dkek)
+	for _, share := range shares {
+		if len(share) != len(dkek) {
+			return nil, errors.New("pkcs8: DKEK shares must be 32 bytes long")
+		}
+		xorBytes(dkek, dkek, share)
+	}
+
+	// KCV (8) | key type (1) | 4 length-prefixed fields | ciphertext | CMAC (16)
+	if len(blob) < 8+1+4*2+aes.BlockSize+16 {
+		return nil, errors.New("pkcs8: DKEK-wrapped key is too short")
+	}
+	kcv := sha256.Sum256(dkek)
+	if subtle.ConstantTimeCompare(kcv[:8], blob[:8]) != 1 {
+		return nil, errors.New("pkcs8: DKEK does not match the wrapped key")
+	}
+
+	kenc := sha256.Sum256(append(append([]byte(nil), dkek...), 0, 0, 0, 1))
+	kmac := sha256.Sum256(append(append([]byte(nil), dkek...), 0, 0, 0, 2))
+	defer zero(kenc[:])
+	defer zero(kmac[:])
+
+	mac, err := aesCMAC(kmac[:], blob[:len(blob)-16])
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(mac, blob[len(blob)-16:]) != 1 {
+		return nil, errors.New("pkcs8: DKEK-wrapped key failed integrity check")
+	}
+
+	keyType := blob[8]
+	rest := blob[9 : len(blob)-16]
+	// Skip the default algorithm, allowed algorithms, access conditions and
+	// key domain OID.
+	for i := 0; i < 4; i++ {
+		if _, rest, err = dkekField(rest); err != nil {
+			return nil, err
+		}
+	}
+	if len(rest) == 0 || len(rest)%aes.BlockSize != 0 {
+		return nil, errors.New("pkcs8: invalid DKEK-wrapped key length")
+	}
+
+	block, err := aes.NewCipher(kenc[:])
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(rest))
+	defer zero(plaintext)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(plaintext, rest)
+
+	// Random (8) | length (2) | key data | padding
+	if len(plaintext) < 10 {
+		return nil, errors.New("pkcs8: invalid DKEK-wrapped key")
+	}
+	n := int(binary.BigEndian.Uint16(plaintext[8:]))
+	if n > len(plaintext)-10 {
+		return nil, errors.New("pkcs8: invalid DKEK-wrapped key")
+	}
+	return parseDKEKKeyData(keyType, plaintext[10:10+n])
+}
+
+// parseDKEKKeyData decodes the key size and components of a wrapped key.
+func parseDKEKKeyData(keyType byte, data []byte) (interface{}, error) {
+	if len(data) < 2 {
+		return nil, errors.New("pkcs8: invalid DKEK-wrapped key")
+	}
+	data = data[2:] // key size in bits
+	var components []*big.Int
+	var raw [][]byte
+	for len(data) > 0 {
+		var v []byte
+		var err error
+		if v, data, err = dkekField(data); err != nil {
+			return nil, err
+		}
+		raw = append(raw, v)
+		components = append(components, new(big.Int).SetBytes(v))
+	}
+
+	switch keyType {
+	case dkekKeyTypeRSA:
+		// Only D, N and E are stored; without the primes the key cannot be
+		// represented in PKCS#8.
+		return nil, errors.New("pkcs8: DKEK-wrapped RSA keys without CRT components are not supported")
+	case dkekKeyTypeRSACRT:
+		// DP, DQ, P, QInv, Q, N, E
+		if len(components) != 7 {
+			return nil, errors.New("pkcs8: invalid DKEK-wrapped RSA key")
+		}
+		if !components[6].IsInt64() || components[6].Int64() > 1<<31-1 {
+			return nil, errors.New("pkcs8: invalid RSA public exponent")
+		}
+		p, q := components[2], components[4]
+		e := components[6]
+		pMinus1 := new(big.Int).Sub(p, big.NewInt(1))
+		qMinus1 := new(big.Int).Sub(q, big.NewInt(1))
+		phi := new(big.Int).Mul(pMinus1, qMinus1)
+		d := new(big.Int).ModInverse(e, phi)
+		if d == nil {
+			return nil, errors.New("pkcs8: invalid DKEK-wrapped RSA key")
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: components[5], E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+	case dkekKeyTypeECC:
+		// A, B, Prime, Order, G, D, Q
+		if len(components) != 7 {
+			return nil, errors.New("pkcs8: invalid DKEK-wrapped EC key")
+		}
+		var curve elliptic.Curve
+		for _, c := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+			if c.Params().P.Cmp(components[2]) == 0 && c.Params().N.Cmp(components[3]) == 0 {
+				curve = c
+				break
+			}
+		}
+		if curve == nil {
+			return nil, errors.New("pkcs8: unsupported curve in DKEK-wrapped key")
+		}
+		d := components[5]
+		if d.Sign() <= 0 || d.Cmp(curve.Params().N) >= 0 {
+			return nil, errors.New("pkcs8: invalid DKEK-wrapped EC key")
+		}
+		key := &ecdsa.PrivateKey{D: d}
+		key.Curve = curve
+		key.X, key.Y = curve.ScalarBaseMult(d.Bytes())
+		if x, y := elliptic.Unmarshal(curve, raw[6]); x != nil && (x.Cmp(key.X) != 0 || y.Cmp(key.Y) != 0) {
+			return nil, errors.New("pkcs8: DKEK-wrapped public key does not match private key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("pkcs8: unsupported DKEK-wrapped key type %d", keyType)
+}
+
+// dkekField reads a field prefixed with its 2-byte big-endian length.
+func dkekField(b []byte) ([]byte, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("pkcs8: invalid DKEK-wrapped key")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if n > len(b)-2 {
+		return nil, nil, errors.New("pkcs8: invalid DKEK-wrapped key")
+	}
+	return b[2 : 2+n], b[2+n:], nil
+}
@@ -0,0 +1,267 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DNSSEC algorithm numbers, see the IANA "DNS Security Algorithm Numbers"
+// registry.
+const (
+	DNSSECRSASHA1          = 5
+	DNSSECRSASHA1NSEC3SHA1 = 7
+	DNSSECRSASHA256        = 8
+	DNSSECRSASHA512        = 10
+	DNSSECECDSAP256SHA256  = 13
+	DNSSECECDSAP384SHA384  = 14
+	DNSSECED25519          = 15
+)
+
+var dnssecAlgorithmNames = map[uint8]string{
+	DNSSECRSASHA1:          "RSASHA1",
+	DNSSECRSASHA1NSEC3SHA1: "NSEC3RSASHA1",
+	DNSSECRSASHA256:        "RSASHA256",
+	DNSSECRSASHA512:        "RSASHA512",
+	DNSSECECDSAP256SHA256:  "ECDSAP256SHA256",
+	DNSSECECDSAP384SHA384:  "ECDSAP384SHA384",
+	DNSSECED25519:          "ED25519",
+}
+
+// DNSSECKeyFiles holds the contents of a BIND-style DNSSEC key pair.
+type DNSSECKeyFiles struct {
+	// BaseName is the file name without extension, e.g.
+	// "Kexample.com.+013+12345".
+	BaseName string
+	// Public is the content of the .key file, holding the DNSKEY record.
+	Public []byte
+	// Private is the content of the .private file.
+	Private []byte
+	// KeyTag is the key tag of the DNSKEY record.
+	KeyTag uint16
+}
+
+// ExportDNSSECKey converts a private key into BIND-style .key and .private
+// files for the given zone. Flags are those of the DNSKEY record, usually
+// 257 for a key signing key and 256 for a zone signing key.
+// If algorithm is 0, it is chosen from the key type: RSASHA256 for RSA,
+// ECDSAP256SHA256 or ECDSAP384SHA384 for ECDSA and ED25519 for Ed25519.
+//
+// The .private file is NOT encrypted; it is meant to be written only when
+// loading the key into the signer.
+func ExportDNSSECKey(priv interface{}, zone string, flags uint16, algorithm uint8) (*DNSSECKeyFiles, error) {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+
+	var public []byte
+	var private bytes.Buffer
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == 0 {
+			algorithm = DNSSECRSASHA256
+		}
+		switch algorithm {
+		case DNSSECRSASHA1, DNSSECRSASHA1NSEC3SHA1, DNSSECRSASHA256, DNSSECRSASHA512:
+		default:
+			return nil, fmt.Errorf("pkcs8: DNSSEC algorithm %d cannot be used with RSA keys", algorithm)
+		}
+		if len(k.Primes) != 2 {
+			return nil, errors.New("pkcs8: multi-prime RSA keys are not supported by DNSSEC")
+		}
+		k.Precompute()
+		public = dnssecRSAPublicKey(&k.PublicKey)
+		writeDNSSECHeader(&private, algorithm)
+		writeDNSSECField(&private, "Modulus", k.N.Bytes())
+		writeDNSSECField(&private, "PublicExponent", big.NewInt(int64(k.E)).Bytes())
+		writeDNSSECField(&private, "PrivateExponent", k.D.Bytes())
+		writeDNSSECField(&private, "Prime1", k.Primes[0].Bytes())
+		writeDNSSECField(&private, "Prime2", k.Primes[1].Bytes())
+		writeDNSSECField(&private, "Exponent1", k.Precomputed.Dp.Bytes())
+		writeDNSSECField(&private, "Exponent2", k.Precomputed.Dq.Bytes())
+		writeDNSSECField(&private, "Coefficient", k.Precomputed.Qinv.Bytes())
+	case *ecdsa.PrivateKey:
+		var expected uint8
+		switch k.Curve {
+		case elliptic.P256():
+			expected = DNSSECECDSAP256SHA256
+		case elliptic.P384():
+			expected = DNSSECECDSAP384SHA384
+		default:
+			return nil, errors.New("pkcs8: unsupported curve for DNSSEC")
+		}
+		if algorithm == 0 {
+			algorithm = expected
+		}
+		if algorithm != expected {
+			return nil, fmt.Errorf("pkcs8: DNSSEC algorithm %d cannot be used with %s keys", algorithm, k.Curve.Params().Name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		public = append(k.X.FillBytes(make([]byte, size)), k.Y.FillBytes(make([]byte, size))...)
+		writeDNSSECHeader(&private, algorithm)
+		writeDNSSECField(&private, "PrivateKey", k.D.FillBytes(make([]byte, size)))
+	case ed25519.PrivateKey:
+		if algorithm == 0 {
+			algorithm = DNSSECED25519
+		}
+		if algorithm != DNSSECED25519 {
+			return nil, fmt.Errorf("pkcs8: DNSSEC algorithm %d cannot be used with Ed25519 keys", algorithm)
+		}
+		public = k.Public().(ed25519.PublicKey)
+		writeDNSSECHeader(&private, algorithm)
+		writeDNSSECField(&private, "PrivateKey", k.Seed())
+	default:
+		return nil, errors.New("pkcs8: unsupported key type for DNSSEC")
+	}
+
+	rdata := append([]byte{byte(flags >> 8), byte(flags), 3, algorithm}, public...)
+	keyTag := dnssecKeyTag(rdata)
+	return &DNSSECKeyFiles{
+		BaseName: fmt.Sprintf("K%s+%03d+%05d", zone, algorithm, keyTag),
+		Public: []byte(fmt.Sprintf("%s IN DNSKEY %d 3 %d %s\n",
+			zone, flags, algorithm, base64.StdEncoding.EncodeToString(public))),
+		Private: private.Bytes(),
+		KeyTag:  keyTag,
+	}, nil
+}
+
+// ParseDNSSECPrivateKey parses the content of a BIND-style .private file into
+// an *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey, which can then
+// be encrypted with MarshalPrivateKey.
+func ParseDNSSECPrivateKey(data []byte) (crypto.PrivateKey, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, errors.New("pkcs8: invalid DNSSEC private key file")
+		}
+		fields[line[:i]] = strings.TrimSpace(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(fields["Private-key-format"], "v1.") {
+		return nil, errors.New("pkcs8: unsupported DNSSEC private key format")
+	}
+	algorithm, err := strconv.Atoi(strings.Fields(fields["Algorithm"] + " ")[0])
+	if err != nil {
+		return nil, errors.New("pkcs8: invalid DNSSEC algorithm")
+	}
+
+	field := func(name string) ([]byte, error) {
+		v, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("pkcs8: DNSSEC private key field %s is missing", name)
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs8: invalid DNSSEC private key field %s", name)
+		}
+		return b, nil
+	}
+
+	switch algorithm {
+	case DNSSECRSASHA1, DNSSECRSASHA1NSEC3SHA1, DNSSECRSASHA256, DNSSECRSASHA512:
+		values := make(map[string]*big.Int)
+		for _, name := range []string{"Modulus", "PublicExponent", "PrivateExponent", "Prime1", "Prime2"} {
+			b, err := field(name)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = new(big.Int).SetBytes(b)
+		}
+		e := values["PublicExponent"]
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("pkcs8: invalid RSA public exponent")
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: values["Modulus"], E: int(e.Int64())},
+			D:         values["PrivateExponent"],
+			Primes:    []*big.Int{values["Prime1"], values["Prime2"]},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+	case DNSSECECDSAP256SHA256, DNSSECECDSAP384SHA384:
+		curve := elliptic.P256()
+		if algorithm == DNSSECECDSAP384SHA384 {
+			curve = elliptic.P384()
+		}
+		d, err := field("PrivateKey")
+		if err != nil {
+			return nil, err
+		}
+		if len(d) != (curve.Params().BitSize+7)/8 {
+			return nil, errors.New("pkcs8: invalid DNSSEC ECDSA private key")
+		}
+		key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+		key.Curve = curve
+		key.X, key.Y = curve.ScalarBaseMult(d)
+		return key, nil
+	case DNSSECED25519:
+		seed, err := field("PrivateKey")
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, errors.New("pkcs8: invalid DNSSEC Ed25519 private key")
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	return nil, fmt.Errorf("pkcs8: unsupported DNSSEC algorithm %d", algorithm)
+}
+
+func writeDNSSECHeader(b *bytes.Buffer, algorithm uint8) {
+	fmt.Fprintf(b, "Private-key-format: v1.3\nAlgorithm: %d (%s)\n", algorithm, dnssecAlgorithmNames[algorithm])
+}
+
+func writeDNSSECField(b *bytes.Buffer, name string, value []byte) {
+	fmt.Fprintf(b, "%s: %s\n", name, base64.StdEncoding.EncodeToString(value))
+}
+
+// dnssecRSAPublicKey encodes an RSA public key as in RFC 3110, section 2.
+func dnssecRSAPublicKey(pub *rsa.PublicKey) []byte {
+	e := big.NewInt(int64(pub.E)).Bytes()
+	var b []byte
+	if len(e) < 256 {
+		b = append(b, byte(len(e)))
+	} else {
+		b = append(b, 0, byte(len(e)>>8), byte(len(e)))
+	}
+	b = append(b, e...)
+	return append(b, pub.N.Bytes()...)
+}
+
+// dnssecKeyTag computes the key tag of DNSKEY RDATA, see RFC 4034, appendix B.
+func dnssecKeyTag(rdata []byte) uint16 {
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16 & 0xffff
+	return uint16(ac & 0xffff)
+}
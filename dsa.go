@@ -0,0 +1,83 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+var oidPublicKeyDSA = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
+
+// dssParams are the Dss-Parms of RFC 3279, section 2.3.2.
+type dssParams struct {
+	P, Q, G *big.Int
+}
+
+func init() {
+	keyTypes = append(keyTypes, keyType{
+		oid: oidPublicKeyDSA,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			var params dssParams
+			if rest, err := asn1.Unmarshal(pki.PrivateKeyAlgorithm.Parameters.FullBytes, &params); err != nil || len(rest) != 0 {
+				return nil, errors.New("pkcs8: invalid DSA parameters")
+			}
+			x := new(big.Int)
+			if rest, err := asn1.Unmarshal(pki.PrivateKey, &x); err != nil || len(rest) != 0 {
+				return nil, errors.New("pkcs8: invalid DSA private key")
+			}
+			if params.P.Sign() <= 0 || params.Q.Sign() <= 0 || params.G.Sign() <= 0 ||
+				x.Sign() <= 0 || x.Cmp(params.Q) >= 0 {
+				return nil, errors.New("pkcs8: invalid DSA private key")
+			}
+			return &dsa.PrivateKey{
+				PublicKey: dsa.PublicKey{
+					Parameters: dsa.Parameters{P: params.P, Q: params.Q, G: params.G},
+					Y:          new(big.Int).Exp(params.G, x, params.P),
+				},
+				X: x,
+			}, nil
+		},
+		marshalPrivate: func(priv crypto.PrivateKey) (*privateKeyInfo, error) {
+			k, ok := priv.(*dsa.PrivateKey)
+			if !ok {
+				return nil, nil
+			}
+			alg, err := dsaAlgorithm(&k.Parameters)
+			if err != nil {
+				return nil, err
+			}
+			x, err := asn1.Marshal(k.X)
+			if err != nil {
+				return nil, err
+			}
+			return &privateKeyInfo{PrivateKeyAlgorithm: alg, PrivateKey: x}, nil
+		},
+		marshalPublic: func(pub crypto.PublicKey) (*subjectPublicKeyInfo, error) {
+			k, ok := pub.(*dsa.PublicKey)
+			if !ok {
+				return nil, nil
+			}
+			alg, err := dsaAlgorithm(&k.Parameters)
+			if err != nil {
+				return nil, err
+			}
+			y, err := asn1.Marshal(k.Y)
+			if err != nil {
+				return nil, err
+			}
+			return &subjectPublicKeyInfo{Algorithm: alg, PublicKey: asn1.BitString{Bytes: y, BitLength: 8 * len(y)}}, nil
+		},
+	})
+}
+
+// dsaAlgorithm returns the id-dsa AlgorithmIdentifier of params.
+func dsaAlgorithm(params *dsa.Parameters) (pkix.AlgorithmIdentifier, error) {
+	der, err := asn1.Marshal(dssParams{params.P, params.Q, params.G})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyDSA, Parameters: asn1.RawValue{FullBytes: der}}, nil
+}
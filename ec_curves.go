@@ -0,0 +1,99 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// registerCurve adds the id-ecPublicKey keys on a named curve crypto/x509
+// does not support. They are parsed into *ecdsa.PrivateKey and
+// *ecdsa.PublicKey whose Curve is curve.
+func registerCurve(oid asn1.ObjectIdentifier, curve elliptic.Curve) {
+	keyTypes = append(keyTypes, keyType{
+		oid:   oidPublicKeyECDSA,
+		curve: oid,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			return parseCurveECPrivateKey(pki.PrivateKey, curve)
+		},
+		parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+			x, y := elliptic.Unmarshal(curve, spki.PublicKey.RightAlign())
+			if x == nil {
+				return nil, fmt.Errorf("pkcs8: invalid %s public key", curve.Params().Name)
+			}
+			return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+		},
+		marshalPrivate: func(priv crypto.PrivateKey) (*privateKeyInfo, error) {
+			k, ok := priv.(*ecdsa.PrivateKey)
+			if !ok || k.Curve != curve {
+				return nil, nil
+			}
+			return marshalCurveECPrivateKey(oid, k)
+		},
+		marshalPublic: func(pub crypto.PublicKey) (*subjectPublicKeyInfo, error) {
+			k, ok := pub.(*ecdsa.PublicKey)
+			if !ok || k.Curve != curve {
+				return nil, nil
+			}
+			alg, err := curveAlgorithm(oid)
+			if err != nil {
+				return nil, err
+			}
+			point := elliptic.Marshal(curve, k.X, k.Y)
+			return &subjectPublicKeyInfo{Algorithm: alg, PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)}}, nil
+		},
+	})
+}
+
+// parseCurveECPrivateKey parses the ECPrivateKey of RFC 5915 of a key on
+// curve.
+func parseCurveECPrivateKey(der []byte, curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	params := curve.Params()
+	var ec ecPrivateKey
+	if rest, err := asn1.Unmarshal(der, &ec); err != nil || len(rest) != 0 || ec.Version != 1 {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", params.Name)
+	}
+	d := new(big.Int).SetBytes(ec.PrivateKey)
+	if len(ec.PrivateKey) > (params.N.BitLen()+7)/8 || d.Sign() <= 0 || d.Cmp(params.N) >= 0 {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", params.Name)
+	}
+	x, y := curve.ScalarBaseMult(d.Bytes())
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}, nil
+}
+
+// marshalCurveECPrivateKey returns the PrivateKeyInfo of a key on the named
+// curve oid, encoded as crypto/x509 encodes the keys of its curves.
+func marshalCurveECPrivateKey(oid asn1.ObjectIdentifier, k *ecdsa.PrivateKey) (*privateKeyInfo, error) {
+	if k.D == nil || k.X == nil || k.Y == nil || k.D.Sign() <= 0 || k.D.Cmp(k.Curve.Params().N) >= 0 {
+		return nil, errors.New("pkcs8: invalid EC private key")
+	}
+	alg, err := curveAlgorithm(oid)
+	if err != nil {
+		return nil, err
+	}
+	point := elliptic.Marshal(k.Curve, k.X, k.Y)
+	ec, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: k.D.FillBytes(make([]byte, (k.Curve.Params().N.BitLen()+7)/8)),
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &privateKeyInfo{PrivateKeyAlgorithm: alg, PrivateKey: ec}, nil
+}
+
+// curveAlgorithm returns the id-ecPublicKey AlgorithmIdentifier of the
+// named curve oid.
+func curveAlgorithm(oid asn1.ObjectIdentifier) (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
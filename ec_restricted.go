@@ -0,0 +1,54 @@
+package pkcs8
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDH           = asn1.ObjectIdentifier{1, 3, 132, 1, 12}
+	oidECMQV          = asn1.ObjectIdentifier{1, 3, 132, 1, 13}
+)
+
+// ECRestriction is the usage restriction of an EC key, given by the
+// algorithm OID of its PrivateKeyInfo (RFC 5480, section 2.1.2).
+type ECRestriction int
+
+const (
+	// ECUnrestricted is used for id-ecPublicKey keys and non-EC keys.
+	ECUnrestricted ECRestriction = iota
+	// ECDHOnly is used for id-ecDH keys, which may only be used for
+	// Elliptic Curve Diffie-Hellman.
+	ECDHOnly
+	// ECMQVOnly is used for id-ecMQV keys, which may only be used for
+	// Elliptic Curve Menezes-Qu-Vanstone key agreement.
+	ECMQVOnly
+)
+
+func (r ECRestriction) String() string {
+	switch r {
+	case ECDHOnly:
+		return "ECDH"
+	case ECMQVOnly:
+		return "ECMQV"
+	}
+	return "unrestricted"
+}
+
+// ParseECPrivateKeyWithRestriction parses a DER-encoded PKCS#8 EC private key
+// and returns its usage restriction. Keys whose algorithm is id-ecDH or
+// id-ecMQV rather than id-ecPublicKey are also accepted by ParsePrivateKey,
+// which drops the restriction. Password and opts can be nil.
+func ParseECPrivateKeyWithRestriction(der []byte, password []byte, opts *ParseOpts) (*ecdsa.PrivateKey, ECRestriction, error) {
+	key, _, restriction, err := parsePrivateKey(der, password, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, 0, errors.New("pkcs8: key is not an EC key")
+	}
+	return ecKey, restriction, nil
+}
@@ -0,0 +1,77 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+)
+
+const (
+	// Ed448SeedSize is the size of an Ed448 private key seed, see RFC 8032.
+	Ed448SeedSize = 57
+	// Ed448PublicKeySize is the size of an Ed448 public key.
+	Ed448PublicKeySize = 57
+)
+
+// Ed448PublicKey is an Ed448 public key, see RFC 8032.
+type Ed448PublicKey []byte
+
+// Ed448PrivateKey is an Ed448 private key, its RFC 8032 seed. It is
+// returned by the parse functions for id-Ed448 keys and accepted by the
+// marshal functions.
+//
+// The package does not implement Ed448: the key only carries its raw
+// material, to be used with an Ed448 implementation such as
+// github.com/cloudflare/circl/sign/ed448, whose NewKeyFromSeed takes it. It
+// has no Public method.
+type Ed448PrivateKey []byte
+
+var oidPublicKeyEd448 = asn1.ObjectIdentifier{1, 3, 101, 113}
+
+func init() {
+	keyTypes = append(keyTypes, keyType{
+		oid: oidPublicKeyEd448,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			seed, err := parseCurvePrivateKey(pki, Ed448SeedSize)
+			if err != nil {
+				return nil, err
+			}
+			return Ed448PrivateKey(seed), nil
+		},
+		parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+			key, err := parseCurvePublicKey(spki, Ed448PublicKeySize)
+			if err != nil {
+				return nil, err
+			}
+			return Ed448PublicKey(key), nil
+		},
+	})
+}
+
+// Equal reports whether priv and x are the same key.
+func (priv Ed448PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(Ed448PrivateKey)
+	return ok && subtle.ConstantTimeCompare(priv, other) == 1
+}
+
+func (priv Ed448PrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	if len(priv) != Ed448SeedSize {
+		return nil, errors.New("pkcs8: invalid Ed448 private key")
+	}
+	return marshalCurvePrivateKey(oidPublicKeyEd448, priv)
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub Ed448PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(Ed448PublicKey)
+	return ok && bytes.Equal(pub, other)
+}
+
+func (pub Ed448PublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	if len(pub) != Ed448PublicKeySize {
+		return nil, errors.New("pkcs8: invalid Ed448 public key")
+	}
+	return marshalCurvePublicKey(oidPublicKeyEd448, pub), nil
+}
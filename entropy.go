@@ -0,0 +1,74 @@
+package pkcs8
+
+import (
+	"fmt"
+	"io"
+)
+
+// The health tests assume a source of full entropy, 8 bits per byte, as
+// expected of a DRBG, with a false positive rate of 2^-30 (NIST SP 800-90B,
+// section 4.4).
+const (
+	// repetitionCutoff is 1 + ceil(30/8).
+	repetitionCutoff = 5
+	// proportionWindow and proportionCutoff are the window and cutoff of
+	// the adaptive proportion test for non-binary sources.
+	proportionWindow = 512
+	proportionCutoff = 16
+)
+
+// EntropyError is returned when the bytes read from Opts.Rand fail a health
+// test enabled by Opts.HealthCheck, e.g. because the source is stuck.
+type EntropyError struct {
+	// Test is "repetition count" or "adaptive proportion".
+	Test string
+}
+
+func (e *EntropyError) Error() string {
+	return fmt.Sprintf("pkcs8: random source failed the %s health test; refusing to use it for salts and IVs", e.Test)
+}
+
+// healthCheckedReader runs the repetition count and adaptive proportion
+// tests of SP 800-90B on the bytes read from r. Their state spans reads, so
+// that the salt and IV of a key are tested together.
+type healthCheckedReader struct {
+	r io.Reader
+
+	// last is the last byte read and repeated the number of times it was
+	// read in a row.
+	last     byte
+	repeated int
+	// first is the first byte of the current window, seen the number of
+	// times it was read in the window of which window bytes were read.
+	first  byte
+	seen   int
+	window int
+}
+
+func (h *healthCheckedReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	for _, b := range p[:n] {
+		if h.repeated > 0 && b == h.last {
+			h.repeated++
+		} else {
+			h.last, h.repeated = b, 1
+		}
+		if h.repeated >= repetitionCutoff {
+			return 0, &EntropyError{Test: "repetition count"}
+		}
+
+		if h.window == 0 {
+			h.first, h.seen = b, 0
+		}
+		if b == h.first {
+			h.seen++
+		}
+		if h.window++; h.window == proportionWindow {
+			h.window = 0
+		}
+		if h.seen >= proportionCutoff {
+			return 0, &EntropyError{Test: "adaptive proportion"}
+		}
+	}
+	return n, err
+}
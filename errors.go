@@ -0,0 +1,77 @@
+package pkcs8
+
+import "fmt"
+
+// ParseError is returned when an ASN.1 structure of an encrypted key cannot
+// be decoded. It locates the offending field to help debugging keys
+// produced by other implementations.
+type ParseError struct {
+	// Msg describes the error, e.g. "invalid KDF parameters".
+	Msg string
+	// Path is the path of the field that could not be decoded, e.g.
+	// "EncryptedPrivateKeyInfo.encryptionAlgorithm.parameters.keyDerivationFunc.parameters".
+	Path string
+	// Offset is the byte offset of the field in the DER input, or -1 if
+	// unknown.
+	Offset int
+	// Err is the underlying encoding/asn1 error.
+	Err error
+
+	data []byte
+}
+
+func (e *ParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("pkcs8: %s (%s): %s", e.Msg, e.Path, e.Err)
+	}
+	return fmt.Sprintf("pkcs8: %s (%s at offset %d): %s", e.Msg, e.Path, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError returns a ParseError for the field at path, whose encoding
+// data is. The offset is resolved later by locateParseError.
+func newParseError(msg, path string, data []byte, err error) *ParseError {
+	return &ParseError{Msg: msg, Path: path, Offset: -1, Err: err, data: data}
+}
+
+// locateParseError prefixes the path of a ParseError with prefix and
+// computes its offset relative to input, which the field data must be a
+// subslice of. Other errors are returned unchanged.
+func locateParseError(err error, prefix string, input []byte) error {
+	e, ok := err.(*ParseError)
+	if !ok {
+		return err
+	}
+	if prefix != "" {
+		e.Path = prefix + "." + e.Path
+	}
+	// data aliases input, so the difference of their capacities is the
+	// offset of data in input.
+	if offset := cap(input) - cap(e.data); e.data != nil && offset >= 0 && offset < len(input) {
+		e.Offset = offset
+	}
+	return e
+}
+
+// LayeredEncryptionError is returned when a key is found under two layers
+// of encryption, such as PEM encryption over an EncryptedPrivateKeyInfo or
+// an EncryptedPrivateKeyInfo whose content is another encrypted key. The
+// outer layer must be removed before the key can be parsed.
+type LayeredEncryptionError struct {
+	// Outer describes the outer layer, e.g. "PEM encryption (DEK-Info
+	// AES-256-CBC)".
+	Outer string
+	// Inner describes the encrypted structure found under it, e.g.
+	// "EncryptedPrivateKeyInfo".
+	Inner string
+	// Peel names the function that removes the outer layer.
+	Peel string
+}
+
+func (e *LayeredEncryptionError) Error() string {
+	return fmt.Sprintf("pkcs8: key is encrypted twice, %s over %s; remove the outer layer with %s first",
+		e.Outer, e.Inner, e.Peel)
+}
@@ -0,0 +1,74 @@
+package pkcs8
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// ExportPKCS1PEM encodes an RSA private key as a traditional PKCS#1
+// "RSA PRIVATE KEY" PEM block, for legacy consumers that do not accept
+// PKCS#8.
+//
+// WARNING: the output is NOT encrypted. Anyone able to read it has the
+// private key. Prefer MarshalPrivateKeyPEM with a password whenever the
+// consumer supports PKCS#8.
+func ExportPKCS1PEM(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+// ExportSEC1PEM encodes an EC private key as a SEC 1 "EC PRIVATE KEY" PEM
+// block with the named curve OID in its parameters, for devices that do not
+// accept PKCS#8 EC keys.
+//
+// WARNING: the output is NOT encrypted. Anyone able to read it has the
+// private key. Prefer MarshalPrivateKeyPEM with a password whenever the
+// consumer supports PKCS#8.
+func ExportSEC1PEM(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// ConvertToEncryptedPKCS1PEM decrypts the PKCS#8 RSA private key der with
+// password and encrypts it again with newPassword as a traditional
+// "RSA PRIVATE KEY" PEM block with a DEK-Info header, for legacy servers
+// that accept nothing else. The conversion is done in memory, without the
+// unencrypted key ever being written out. If alg is zero,
+// x509.PEMCipherAES256 is used.
+//
+// WARNING: DEK-Info encryption derives the key from the password with a
+// single round of MD5 and does not detect tampering; the output is far
+// easier to brute-force than a PBES2-encrypted key. Only use it for
+// consumers that support nothing else, with a long random newPassword.
+func ConvertToEncryptedPKCS1PEM(der, password, newPassword []byte, alg x509.PEMCipher) ([]byte, error) {
+	if len(newPassword) == 0 {
+		return nil, errors.New("pkcs8: new password required")
+	}
+	if alg == 0 {
+		alg = x509.PEMCipherAES256
+	}
+	key, _, err := ParsePrivateKey(der, password)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("pkcs8: only RSA keys can be converted to PKCS#1")
+	}
+	pkcs1 := x509.MarshalPKCS1PrivateKey(rsaKey)
+	defer zero(pkcs1)
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", pkcs1, newPassword, alg)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
@@ -0,0 +1,21 @@
+//go:build go1.18
+// +build go1.18
+
+package pkcs8_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func FuzzParsePrivateKeyPEM(f *testing.F) {
+	f.Add([]byte(ec256), []byte(""))
+	f.Add([]byte(encryptedEC256aes), []byte("password"))
+	f.Add([]byte("text\r\n  "+strings.ReplaceAll(ec128, "\n", "\r\n  ")), []byte(""))
+	f.Fuzz(func(t *testing.T, data, password []byte) {
+		// Must not panic, whatever the input.
+		pkcs8.ParsePrivateKeyPEM(data, password)
+	})
+}
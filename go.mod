@@ -3,3 +3,5 @@ module github.com/youmark/pkcs8
 go 1.17
 
 require golang.org/x/crypto v0.22.0
+
+require golang.org/x/sys v0.19.0 // indirect
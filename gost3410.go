@@ -0,0 +1,135 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+var (
+	oidGOST3410_2012_256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 1}
+	oidGOST3410_2012_512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 2}
+	oidStreebog256       = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 2}
+	oidStreebog512       = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 3}
+)
+
+// GOSTPrivateKey is a GOST R 34.10-2012 private key, as exported by
+// CryptoPro CSP or the OpenSSL gost engine. It is returned by the parse
+// functions for id-tc26-gost3410-12-256 and id-tc26-gost3410-12-512 keys and
+// accepted by the marshal functions.
+//
+// The package does not implement GOST R 34.10 arithmetic: the key only
+// carries its parameters and raw material, to be used with a GOST
+// implementation. It has no Public method, and CryptoPro keys masked with
+// their key container masks are not supported.
+type GOSTPrivateKey struct {
+	// Algorithm is the key algorithm, id-tc26-gost3410-12-256 or
+	// id-tc26-gost3410-12-512.
+	Algorithm asn1.ObjectIdentifier
+	// ParamSet is the OID of the curve of the key, e.g.
+	// id-tc26-gost-3410-12-256-paramSetA, and DigestParamSet the OID of its
+	// hash function, nil if absent.
+	ParamSet       asn1.ObjectIdentifier
+	DigestParamSet asn1.ObjectIdentifier
+	// Key is the private key, little-endian as in GOST R 34.10 software,
+	// 32 bytes for 256-bit keys and 64 bytes for 512-bit ones.
+	Key []byte
+}
+
+// gostPublicKeyParams are the GostR3410-2012-PublicKeyParameters of RFC
+// 9215, section 4.2.
+type gostPublicKeyParams struct {
+	PublicKeyParamSet asn1.ObjectIdentifier
+	DigestParamSet    asn1.ObjectIdentifier `asn1:"optional"`
+}
+
+func init() {
+	for _, oid := range []asn1.ObjectIdentifier{oidGOST3410_2012_256, oidGOST3410_2012_512} {
+		oid := oid
+		keyTypes = append(keyTypes, keyType{
+			oid: oid,
+			parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+				return parseGOSTPrivateKey(oid, pki)
+			},
+		})
+	}
+}
+
+// gostKeySize returns the size of the keys of the algorithm oid.
+func gostKeySize(oid asn1.ObjectIdentifier) int {
+	if oid.Equal(oidGOST3410_2012_512) {
+		return 64
+	}
+	return 32
+}
+
+func parseGOSTPrivateKey(oid asn1.ObjectIdentifier, pki *privateKeyInfo) (*GOSTPrivateKey, error) {
+	var params gostPublicKeyParams
+	if rest, err := asn1.Unmarshal(pki.PrivateKeyAlgorithm.Parameters.FullBytes, &params); err != nil || len(rest) != 0 {
+		return nil, errors.New("pkcs8: invalid GOST R 34.10-2012 parameters")
+	}
+	size := gostKeySize(oid)
+	key := make([]byte, size)
+	// The gost engine and CryptoPro write a little-endian OCTET STRING, old
+	// gost engine releases a big-endian INTEGER.
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(pki.PrivateKey, &raw); err != nil || len(rest) != 0 || raw.Class != asn1.ClassUniversal {
+		return nil, errors.New("pkcs8: invalid GOST R 34.10-2012 private key")
+	}
+	switch {
+	case raw.Tag == asn1.TagOctetString && !raw.IsCompound && len(raw.Bytes) == size:
+		copy(key, raw.Bytes)
+	case raw.Tag == asn1.TagInteger:
+		var n *big.Int
+		if _, err := asn1.Unmarshal(pki.PrivateKey, &n); err != nil || n.Sign() <= 0 || n.BitLen() > 8*size {
+			return nil, errors.New("pkcs8: invalid GOST R 34.10-2012 private key")
+		}
+		n.FillBytes(key)
+		reverse(key)
+	default:
+		return nil, errors.New("pkcs8: unsupported GOST R 34.10-2012 private key encoding")
+	}
+	return &GOSTPrivateKey{
+		Algorithm:      oid,
+		ParamSet:       params.PublicKeyParamSet,
+		DigestParamSet: params.DigestParamSet,
+		Key:            key,
+	}, nil
+}
+
+func (priv *GOSTPrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	if !priv.Algorithm.Equal(oidGOST3410_2012_256) && !priv.Algorithm.Equal(oidGOST3410_2012_512) {
+		return nil, errors.New("pkcs8: unsupported GOST R 34.10 algorithm")
+	}
+	if len(priv.Key) != gostKeySize(priv.Algorithm) {
+		return nil, errors.New("pkcs8: invalid GOST R 34.10-2012 private key size")
+	}
+	params, err := asn1.Marshal(gostPublicKeyParams{priv.ParamSet, priv.DigestParamSet})
+	if err != nil {
+		return nil, err
+	}
+	key, err := asn1.Marshal(priv.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &privateKeyInfo{
+		PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: priv.Algorithm, Parameters: asn1.RawValue{FullBytes: params}},
+		PrivateKey:          key,
+	}, nil
+}
+
+// Equal reports whether priv and x are the same key.
+func (priv *GOSTPrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*GOSTPrivateKey)
+	return ok && priv.Algorithm.Equal(other.Algorithm) && priv.ParamSet.Equal(other.ParamSet) &&
+		priv.DigestParamSet.Equal(other.DigestParamSet) && subtle.ConstantTimeCompare(priv.Key, other.Key) == 1
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,300 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+)
+
+// KeyInfo describes a PKCS#8 private key and its protection. Algorithms are
+// given by name when known and by dotted OID otherwise.
+type KeyInfo struct {
+	// Encrypted reports whether the key is encrypted.
+	Encrypted bool `json:"encrypted"`
+	// Container is "EncryptedPrivateKeyInfo", "PKCS7EncryptedData",
+	// "PrivateKeyInfo", "SubjectPublicKeyInfo" or "Certificate".
+	Container string `json:"container"`
+	// Scheme is the encryption scheme, e.g. "PBES2".
+	Scheme string `json:"scheme,omitempty"`
+	// Cipher is the cipher of a PBES2 scheme.
+	Cipher     string `json:"cipher,omitempty"`
+	KDF        string `json:"kdf,omitempty"`
+	PRF        string `json:"prf,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	SaltSize   int    `json:"saltSize,omitempty"`
+	// SaltSource is the salt source of a PBKDF2 salt of the otherSource
+	// form, in which case SaltSize is zero.
+	SaltSource string `json:"saltSource,omitempty"`
+	// ScryptN, ScryptR and ScryptP are the scrypt cost parameters.
+	ScryptN int `json:"scryptN,omitempty"`
+	ScryptR int `json:"scryptR,omitempty"`
+	ScryptP int `json:"scryptP,omitempty"`
+
+	// The following fields are only set if the key could be decrypted, or
+	// is a public key.
+
+	// KeyType is "RSA", "ECDSA", "DSA", "Ed25519", "Ed448", "X448" or, for other keys, the name of
+	// their algorithm, e.g. "X25519".
+	KeyType    string   `json:"keyType,omitempty"`
+	Curve      string   `json:"curve,omitempty"`
+	Bits       int      `json:"bits,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+
+	// Warnings describes problems of the input that did not prevent
+	// describing it, e.g. a mislabeled PEM block.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Inspect describes a DER-encoded, possibly encrypted, PKCS#8 private key.
+// The protection of an encrypted key is reported without a password; the
+// key type, size and attributes are only reported if the password is given.
+// Password can be nil.
+//
+// A DER-encoded SubjectPublicKeyInfo or certificate is described as well,
+// with the same key fields, so that every key artifact can be classified
+// with one function.
+func Inspect(der []byte, password []byte) (*KeyInfo, error) {
+	if info, ok := describePublic(der); ok {
+		return info, nil
+	}
+	info, err := describeContainer(der)
+	if err != nil {
+		return nil, err
+	}
+	if info.Encrypted && len(password) == 0 {
+		return info, nil
+	}
+	if _, err := info.decryptAndDescribe(der, password, &ParseOpts{AllowLegacy: true}); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// InspectPEM describes the first private key of PEM-encoded data as
+// Inspect does. The key is described by its content whatever its label: an
+// encrypted key labeled "PRIVATE KEY", or an unencrypted key labeled
+// "ENCRYPTED PRIVATE KEY", is reported as such, with a warning. Inputs
+// without PEM armor are accepted as ParsePrivateKeyPEM does.
+func InspectPEM(data []byte, password []byte) (*KeyInfo, error) {
+	block, err := decodePrivateKeyPEM(data)
+	if err != nil {
+		der, ok := detectDER(data)
+		if !ok {
+			return nil, err
+		}
+		return Inspect(der, password)
+	}
+	if err := checkPEMEncryption(block); err != nil {
+		return nil, err
+	}
+	info, err := Inspect(block.Bytes, password)
+	if err != nil {
+		return nil, err
+	}
+	if _, warning := checkPEMLabel(block); warning != "" {
+		info.Warnings = append(info.Warnings, warning)
+	}
+	return info, nil
+}
+
+// ParsePrivateKeyWithInfo parses a DER-encoded, possibly encrypted, PKCS#8
+// private key and describes it as Inspect does, decrypting it only once.
+// The password is ignored if the key is not encrypted.
+func ParsePrivateKeyWithInfo(der []byte, password []byte) (crypto.PrivateKey, *KeyInfo, error) {
+	info, err := describeContainer(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Encrypted && len(password) == 0 {
+		return nil, nil, errors.New("pkcs8: password required for encrypted key")
+	}
+	key, err := info.decryptAndDescribe(der, password, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, info, nil
+}
+
+// describeContainer describes the container and encryption of der.
+func describeContainer(der []byte) (*KeyInfo, error) {
+	info := &KeyInfo{Container: "PrivateKeyInfo"}
+	var encryptionAlgorithm pkix.AlgorithmIdentifier
+	if isPKCS7EncryptedData(der) {
+		var ci pkcs7ContentInfo
+		var ed pkcs7EncryptedData
+		if _, err := asn1.Unmarshal(der, &ci); err != nil {
+			return nil, errors.New("pkcs8: invalid PKCS#7 content info")
+		}
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+			return nil, errors.New("pkcs8: invalid PKCS#7 encrypted data")
+		}
+		info.Encrypted, info.Container = true, "PKCS7EncryptedData"
+		encryptionAlgorithm = ed.EncryptedContentInfo.ContentEncryptionAlgorithm
+	} else {
+		var epki encryptedPrivateKeyInfo
+		if rest, err := asn1.Unmarshal(der, &epki); err == nil && len(rest) == 0 {
+			info.Encrypted, info.Container = true, "EncryptedPrivateKeyInfo"
+			encryptionAlgorithm = epki.EncryptionAlgorithm
+		}
+	}
+	if info.Encrypted {
+		if err := info.describeEncryption(encryptionAlgorithm); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// decryptAndDescribe decrypts and parses der, filling in the key fields of
+// info.
+func (info *KeyInfo) decryptAndDescribe(der []byte, password []byte, opts *ParseOpts) (crypto.PrivateKey, error) {
+	if !info.Encrypted {
+		password = nil
+	}
+	pkey, kdfParams, err := decryptPrivateKeyInfo(der, password, opts)
+	if err != nil {
+		return nil, err
+	}
+	if kdfParams != nil {
+		defer zero(pkey)
+	}
+	normalized, _, changed := normalizeKeyAlgorithm(pkey)
+	if changed {
+		defer zero(normalized)
+	}
+	key, err := parsePKCS8PrivateKey(normalized)
+	if err != nil {
+		if kdfParams != nil {
+			return nil, errIncorrectPassword(der, pkey)
+		}
+		return nil, err
+	}
+	var pki privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &pki); err != nil {
+		return nil, errors.New("pkcs8: invalid private key info")
+	}
+	info.describeKey(Public(key), pki.PrivateKeyAlgorithm.Algorithm)
+	for _, attr := range pki.Attributes {
+		info.Attributes = append(info.Attributes, OIDName(attr.Type))
+	}
+	return key, nil
+}
+
+// describePublic describes der if it is a SubjectPublicKeyInfo or a
+// certificate.
+func describePublic(der []byte) (*KeyInfo, bool) {
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		var spki subjectPublicKeyInfo
+		if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+			return nil, false
+		}
+		info := &KeyInfo{Container: "Certificate"}
+		info.describeKey(cert.PublicKey, spki.Algorithm.Algorithm)
+		return info, true
+	}
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil || len(rest) != 0 {
+		return nil, false
+	}
+	pub, err := ParsePublicKey(der)
+	if err != nil {
+		// Unknown key types are still described by their algorithm.
+		pub = nil
+	}
+	info := &KeyInfo{Container: "SubjectPublicKeyInfo"}
+	info.describeKey(pub, spki.Algorithm.Algorithm)
+	return info, true
+}
+
+// describeKey fills in the key type and size of info from the public key
+// pub, or from its algorithm if pub is of an unknown type.
+func (info *KeyInfo) describeKey(pub crypto.PublicKey, algorithm asn1.ObjectIdentifier) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.Bits = "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.Curve, info.Bits = "ECDSA", k.Curve.Params().Name, k.Curve.Params().BitSize
+	case *dsa.PublicKey:
+		info.KeyType, info.Bits = "DSA", k.P.BitLen()
+	case ed25519.PublicKey:
+		info.KeyType, info.Bits = "Ed25519", 256
+	case Ed448PublicKey:
+		info.KeyType, info.Bits = "Ed448", 456
+	default:
+		info.KeyType = OIDName(algorithm)
+		// The package has no Ed448 or X448 implementation to compute the
+		// public key of a private key from.
+		switch {
+		case algorithm.Equal(oidPublicKeyEd448):
+			info.Bits = 456
+		case algorithm.Equal(oidPublicKeyX448):
+			info.Bits = 448
+		}
+	}
+}
+
+// describeEncryption fills in the encryption fields of info.
+func (info *KeyInfo) describeEncryption(alg pkix.AlgorithmIdentifier) error {
+	info.Scheme = OIDName(alg.Algorithm)
+	if isPKCS12PBE(alg.Algorithm) {
+		var params pkcs12PBEParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return errors.New("pkcs8: invalid PKCS #12 PBE parameters")
+		}
+		info.KDF, info.PRF = "PKCS12KDF", "SHA1"
+		info.Iterations, info.SaltSize = params.Iterations, len(params.Salt)
+		return nil
+	}
+	if !alg.Algorithm.Equal(oidPBES2) {
+		return nil
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return errors.New("pkcs8: invalid PBES2 parameters")
+	}
+	info.Cipher = OIDName(params.EncryptionScheme.Algorithm)
+	info.KDF = OIDName(params.KeyDerivationFunc.Algorithm)
+	switch {
+	case params.KeyDerivationFunc.Algorithm.Equal(oidPKCS5PBKDF2):
+		var p pbkdf2Params
+		if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &p); err != nil {
+			return errors.New("pkcs8: invalid KDF parameters")
+		}
+		info.PRF = "hmacWithSHA1"
+		if len(p.PRF.Algorithm) > 0 {
+			info.PRF = OIDName(p.PRF.Algorithm)
+		}
+		info.Iterations = p.IterationCount
+		if salt, ok := p.specifiedSalt(); ok {
+			info.SaltSize = len(salt)
+		} else if source, ok := p.saltSource(); ok {
+			info.SaltSource = OIDName(source.Algorithm)
+		}
+	case params.KeyDerivationFunc.Algorithm.Equal(oidScrypt):
+		var p scryptParams
+		if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &p); err != nil {
+			return errors.New("pkcs8: invalid KDF parameters")
+		}
+		info.SaltSize = len(p.Salt)
+		info.ScryptN, info.ScryptR, info.ScryptP = p.CostParameter, p.BlockSize, p.ParallelizationParameter
+	}
+	return nil
+}
+
+// InspectJSON returns the result of Inspect as a JSON document. Its fields
+// are stable and absent when not applicable.
+func InspectJSON(der []byte, password []byte) ([]byte, error) {
+	info, err := Inspect(der, password)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(info)
+}
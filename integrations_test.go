@@ -0,0 +1,440 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestMarshalAuthorizedKey(t *testing.T) {
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	keys := []crypto.Signer{rsaPrivateKey, edPrivateKey}
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		ecPrivateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey returned: %s", err)
+		}
+		keys = append(keys, ecPrivateKey)
+	}
+	for _, key := range keys {
+		line, err := pkcs8.MarshalAuthorizedKey(key, "user@host")
+		if err != nil {
+			t.Fatalf("%T: MarshalAuthorizedKey returned: %s", key, err)
+		}
+		pub, comment, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			t.Fatalf("%T: ParseAuthorizedKey returned: %s", key, err)
+		}
+		if comment != "user@host" {
+			t.Errorf("%T: unexpected comment %q", key, comment)
+		}
+		expected, err := ssh.NewPublicKey(key.Public())
+		if err != nil {
+			t.Fatalf("%T: NewPublicKey returned: %s", key, err)
+		}
+		if !bytes.Equal(pub.Marshal(), expected.Marshal()) {
+			t.Errorf("%T: public key mismatch", key)
+		}
+	}
+	for _, comment := range []string{"user@host\nssh-rsa AAAA", "user@host\r", "user\x00host"} {
+		if _, err := pkcs8.MarshalAuthorizedKey(edPrivateKey, comment); err == nil {
+			t.Errorf("MarshalAuthorizedKey(%q) did not fail", comment)
+		}
+	}
+}
+
+func TestDNSSECKey(t *testing.T) {
+	// From RFC 8080, section 6.1.
+	rfcPrivate := "Private-key-format: v1.2\nAlgorithm: 15 (ED25519)\nPrivateKey: ODIyNjAzODQ2MjgwODAxMjI2NDUxOTAyMDQxNDIyNjI=\n"
+	key, err := pkcs8.ParseDNSSECPrivateKey([]byte(rfcPrivate))
+	if err != nil {
+		t.Fatalf("ParseDNSSECPrivateKey returned: %s", err)
+	}
+	files, err := pkcs8.ExportDNSSECKey(key, "example.com", 257, 0)
+	if err != nil {
+		t.Fatalf("ExportDNSSECKey returned: %s", err)
+	}
+	if files.KeyTag != 3613 || files.BaseName != "Kexample.com.+015+03613" {
+		t.Errorf("unexpected key tag %d, base name %s", files.KeyTag, files.BaseName)
+	}
+	if want := "example.com. IN DNSKEY 257 3 15 l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4=\n"; string(files.Public) != want {
+		t.Errorf("unexpected public key file %q", files.Public)
+	}
+
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	for _, priv := range []interface{ Equal(crypto.PrivateKey) bool }{rsaPrivateKey, ecPrivateKey, key.(ed25519.PrivateKey)} {
+		der, err := pkcs8.MarshalPrivateKey(priv, []byte("password"), nil)
+		if err != nil {
+			t.Fatalf("%T: MarshalPrivateKey returned: %s", priv, err)
+		}
+		parsed, _, err := pkcs8.ParsePrivateKey(der, []byte("password"))
+		if err != nil {
+			t.Fatalf("%T: ParsePrivateKey returned: %s", priv, err)
+		}
+		files, err := pkcs8.ExportDNSSECKey(parsed, "example.org.", 256, 0)
+		if err != nil {
+			t.Fatalf("%T: ExportDNSSECKey returned: %s", priv, err)
+		}
+		back, err := pkcs8.ParseDNSSECPrivateKey(files.Private)
+		if err != nil {
+			t.Fatalf("%T: ParseDNSSECPrivateKey returned: %s", priv, err)
+		}
+		if !priv.Equal(back) {
+			t.Errorf("%T: Decoded key does not match original key", priv)
+		}
+	}
+	if _, err := pkcs8.ExportDNSSECKey(ecPrivateKey, "example.org.", 256, pkcs8.DNSSECED25519); err == nil {
+		t.Error("expected algorithm mismatch error")
+	}
+}
+
+func TestJWE(t *testing.T) {
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	for _, opts := range []*pkcs8.JWEOpts{
+		nil,
+		{Algorithm: "PBES2-HS384+A192KW", Encryption: "A128GCM", SaltSize: 8, IterationCount: 1000},
+		{Algorithm: "PBES2-HS512+A256KW", Encryption: "A256CBC-HS512", SaltSize: 16, IterationCount: 1000},
+		{Algorithm: "PBES2-HS256+A128KW", Encryption: "A128CBC-HS256", SaltSize: 16, IterationCount: 1000},
+	} {
+		for _, priv := range []interface{ Equal(crypto.PrivateKey) bool }{rsaPrivateKey, ecPrivateKey, edPrivateKey} {
+			jwe, err := pkcs8.MarshalJWE(priv, []byte("password"), opts)
+			if err != nil {
+				t.Fatalf("%T: MarshalJWE returned: %s", priv, err)
+			}
+			key, err := pkcs8.ParseJWE(jwe, []byte("password"))
+			if err != nil {
+				t.Fatalf("%T: ParseJWE returned: %s", priv, err)
+			}
+			if !priv.Equal(key) {
+				t.Fatalf("%T: Decoded key does not match original key", priv)
+			}
+			if _, err := pkcs8.ParseJWE(jwe, []byte("wrong password")); err == nil {
+				t.Errorf("%T: should have failed", priv)
+			}
+
+			// Converting to encrypted PKCS#8 and back keeps the key.
+			der, err := pkcs8.MarshalPrivateKey(key, []byte("password"), nil)
+			if err != nil {
+				t.Fatalf("%T: MarshalPrivateKey returned: %s", priv, err)
+			}
+			if key, _, err = pkcs8.ParsePrivateKey(der, []byte("password")); err != nil || !priv.Equal(key) {
+				t.Fatalf("%T: PKCS#8 round trip failed: %v", priv, err)
+			}
+		}
+	}
+}
+
+func TestJWEMaxIterationCount(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	opts := *pkcs8.DefaultJWEOpts
+	opts.IterationCount = 2000
+	jwe, err := pkcs8.MarshalJWE(priv, []byte("password"), &opts)
+	if err != nil {
+		t.Fatalf("MarshalJWE returned: %s", err)
+	}
+	if _, err := pkcs8.ParseJWEWithOpts(jwe, []byte("password"), &pkcs8.JWEParseOpts{MaxIterationCount: 1000}); err == nil ||
+		!strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("ParseJWEWithOpts returned %v, want an iteration count error", err)
+	}
+	if _, err := pkcs8.ParseJWEWithOpts(jwe, []byte("password"), &pkcs8.JWEParseOpts{MaxIterationCount: 2000}); err != nil {
+		t.Errorf("ParseJWEWithOpts returned: %s", err)
+	}
+
+	// A forged header asking for 2^31 iterations is refused at once rather
+	// than pinning a CPU.
+	parts := strings.Split(string(jwe), ".")
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	header = bytes.Replace(header, []byte(`"p2c":2000`), []byte(`"p2c":2147483648`), 1)
+	parts[0] = base64.RawURLEncoding.EncodeToString(header)
+	if _, err := pkcs8.ParseJWE([]byte(strings.Join(parts, ".")), []byte("password")); err == nil ||
+		!strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("ParseJWE returned %v, want an iteration count error", err)
+	}
+}
+
+func TestJWEDefaultOpts(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	// Zero salt sizes and iteration counts take the defaults.
+	jwe, err := pkcs8.MarshalJWE(priv, []byte("password"), &pkcs8.JWEOpts{Algorithm: "PBES2-HS256+A128KW", Encryption: "A128GCM"})
+	if err != nil {
+		t.Fatalf("MarshalJWE returned: %s", err)
+	}
+	if key, err := pkcs8.ParseJWE(jwe, []byte("password")); err != nil || !priv.Equal(key) {
+		t.Errorf("ParseJWE returned %v", err)
+	}
+	for _, opts := range []*pkcs8.JWEOpts{
+		{Algorithm: "PBES2-HS256+A128KW", Encryption: "A128GCM", SaltSize: 4, IterationCount: 1000},
+		{Algorithm: "PBES2-HS256+A128KW", Encryption: "A128GCM", SaltSize: 16, IterationCount: -1},
+	} {
+		if _, err := pkcs8.MarshalJWE(priv, []byte("password"), opts); err == nil {
+			t.Errorf("MarshalJWE accepted %+v", opts)
+		}
+	}
+}
+
+// rfc7517JWE is the PBES2-HS256+A128KW and A128CBC-HS256 encrypted RSA key of
+// RFC 7517, Appendix C.
+const rfc7517JWE = "" +
+	"eyJhbGciOiJQQkVTMi1IUzI1NitBMTI4S1ciLCJwMnMiOiIyV0NUY0paMVJ2ZF9DSnVK" +
+	"cmlwUTF3IiwicDJjIjo0MDk2LCJlbmMiOiJBMTI4Q0JDLUhTMjU2IiwiY3R5Ijoiandr" +
+	"K2pzb24ifQ.TrqXOwuNUfDV9VPTNbyGvEJ9JMjefAVn-TR1uIxR9p6hsRQh9Tk7BA.Ye" +
+	"9j1qs22DmRSAddIh-VnA.AwhB8lxrlKjFn02LGWEqg27H4Tg9fyZAbFv3p5ZicHpj64Q" +
+	"yHC44qqlZ3JEmnZTgQowIqZJ13jbyHB8LgePiqUJ1hf6M2HPLgzw8L-mEeQ0jvDUTrE0" +
+	"7NtOerBk8bwBQyZ6g0kQ3DEOIglfYxV8-FJvNBYwbqN1Bck6d_i7OtjSHV-8DIrp-3Jc" +
+	"RIe05YKy3Oi34Z_GOiAc1EK21B11c_AE11PII_wvvtRiUiG8YofQXakWd1_O98Kap-Ug" +
+	"myWPfreUJ3lJPnbD4Ve95owEfMGLOPflo2MnjaTDCwQokoJ_xplQ2vNPz8iguLcHBoKl" +
+	"lyQFJL2mOWBwqhBo9Oj-O800as5mmLsvQMTflIrIEbbTMzHMBZ8EFW9fWwwFu0DWQJGk" +
+	"MNhmBZQ-3lvqTc-M6-gWA6D8PDhONfP2Oib2HGizwG1iEaX8GRyUpfLuljCLIe1DkGOe" +
+	"whKuKkZh04DKNM5Nbugf2atmU9OP0Ldx5peCUtRG1gMVl7Qup5ZXHTjgPDr5b2N731Uo" +
+	"oCGAUqHdgGhg0JVJ_ObCTdjsH4CF1SJsdUhrXvYx3HJh2Xd7CwJRzU_3Y1GxYU6-s3GF" +
+	"PbirfqqEipJDBTHpcoCmyrwYjYHFgnlqBZRotRrS95g8F95bRXqsaDY7UgQGwBQBwy66" +
+	"5d0zpvTasvfXf_c0MWAl-neFaKOW_Px6g4EUDjG1GWSXV9cLStLw_0ovdApDIFLHYHeP" +
+	"yagyHjouQUuGiq7BsYwYrwaF06tgB8hV8omLNfMEmDPJaZUzMuHw6tBDwGkzD-tS_ub9" +
+	"hxrpJ4UsOWnt5rGUyoN2N_c1-TQlXxm5oto14MxnoAyBQBpwIEgSH3Y4ZhwKBhHPjSo0" +
+	"cdwuNdYbGPpb-YUvF-2NZzODiQ1OvWQBRHSbPWYz_xbGkgD504LRtqRwCO7CC_CyyURi" +
+	"1sEssPVsMJRX_U4LFEOc82TiDdqjKOjRUfKK5rqLi8nBE9soQ0DSaOoFQZiGrBrqxDsN" +
+	"YiAYAmxxkos-i3nX4qtByVx85sCE5U_0MqG7COxZWMOPEFrDaepUV-cOyrvoUIng8i8l" +
+	"jKBKxETY2BgPegKBYCxsAUcAkKamSCC9AiBxA0UOHyhTqtlvMksO7AEhNC2-YzPyx1Fk" +
+	"hMoS4LLe6E_pFsMlmjA6P1NSge9C5G5tETYXGAn6b1xZbHtmwrPScro9LWhVmAaA7_bx" +
+	"YObnFUxgWtK4vzzQBjZJ36UTk4OTB-JvKWgfVWCFsaw5WCHj6Oo4jpO7d2yN7WMfAj2h" +
+	"TEabz9wumQ0TMhBduZ-QON3pYObSy7TSC1vVme0NJrwF_cJRehKTFmdlXGVldPxZCplr" +
+	"7ZQqRQhF8JP-l4mEQVnCaWGn9ONHlemczGOS-A-wwtnmwjIB1V_vgJRf4FdpV-4hUk4-" +
+	"QLpu3-1lWFxrtZKcggq3tWTduRo5_QebQbUUT_VSCgsFcOmyWKoj56lbxthN19hq1XGW" +
+	"bLGfrrR6MWh23vk01zn8FVwi7uFwEnRYSafsnWLa1Z5TpBj9GvAdl2H9NHwzpB5NqHpZ" +
+	"NkQ3NMDj13Fn8fzO0JB83Etbm_tnFQfcb13X3bJ15Cz-Ww1MGhvIpGGnMBT_ADp9xSIy" +
+	"AM9dQ1yeVXk-AIgWBUlN5uyWSGyCxp0cJwx7HxM38z0UIeBu-MytL-eqndM7LxytsVzC" +
+	"bjOTSVRmhYEMIzUAnS1gs7uMQAGRdgRIElTJESGMjb_4bZq9s6Ve1LKkSi0_QDsrABaL" +
+	"e55UY0zF4ZSfOV5PMyPtocwV_dcNPlxLgNAD1BFX_Z9kAdMZQW6fAmsfFle0zAoMe4l9" +
+	"pMESH0JB4sJGdCKtQXj1cXNydDYozF7l8H00BV_Er7zd6VtIw0MxwkFCTatsv_R-GsBC" +
+	"H218RgVPsfYhwVuT8R4HarpzsDBufC4r8_c8fc9Z278sQ081jFjOja6L2x0N_ImzFNXU" +
+	"6xwO-Ska-QeuvYZ3X_L31ZOX4Llp-7QSfgDoHnOxFv1Xws-D5mDHD3zxOup2b2TppdKT" +
+	"Zb9eW2vxUVviM8OI9atBfPKMGAOv9omA-6vv5IxUH0-lWMiHLQ_g8vnswp-Jav0c4t6U" +
+	"RVUzujNOoNd_CBGGVnHiJTCHl88LQxsqLHHIu4Fz-U2SGnlxGTj0-ihit2ELGRv4vO8E" +
+	"1BosTmf0cx3qgG0Pq0eOLBDIHsrdZ_CCAiTc0HVkMbyq1M6qEhM-q5P6y1QCIrwg.0HF" +
+	"mhOzsQ98nNWJjIHkR7A"
+
+func TestJWERFC7517(t *testing.T) {
+	n := "" +
+		"t6Q8PWSi1dkJj9hTP8hNYFlvadM7DflW9mWepOJhJ66w7nyoK1gPNqFMSQRyO125" +
+		"Gp-TEkodhWr0iujjHVx7BcV0llS4w5ACGgPrcAd6ZcSR0-Iqom-QFcNP8Sjg086M" +
+		"woqQU_LYywlAGZ21WSdS_PERyGFiNnj3QQlO8Yns5jCtLCRwLHL0Pb1fEv45AuRI" +
+		"uUfVcPySBWYnDyGxvjYGDSM-AqWS9zIQ2ZilgT-GqUmipg0XOC0Cc20rgLe2ymLH" +
+		"jpHciCKVAbY5-L32-lSeZO-Os6U15_aXrk9Gw8cPUaX1_I8sLGuSiVdt3C_Fn2PZ" +
+		"3Z8i744FPFGGcG1qs2Wz-Q"
+	d := "" +
+		"GRtbIQmhOZtyszfgKdg4u_N-R_mZGU_9k7JQ_jn1DnfTuMdSNprTeaSTyWfSNkua" +
+		"AwnOEbIQVy1IQbWVV25NY3ybc_IhUJtfri7bAXYEReWaCl3hdlPKXy9UvqPYGR0k" +
+		"IXTQRqns-dVJ7jahlI7LyckrpTmrM8dWBo4_PMaenNnPiQgO0xnuToxutRZJfJvG" +
+		"4Ox4ka3GORQd9CsCZ2vsUDmsXOfUENOyMqADC6p1M3h33tsurY15k9qMSpG9OX_I" +
+		"JAXmxzAh_tWiZOwk2K4yxH9tS3Lq1yX8C1EWmeRDkK2ahecG85-oLKQt5VEpWHKm" +
+		"jOi_gJSdSgqcN96X52esAQ"
+	key, err := pkcs8.ParseJWE([]byte(rfc7517JWE), []byte("Thus from my lips, by yours, my sin is purged."))
+	if err != nil {
+		t.Fatalf("ParseJWE returned: %s", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		t.Fatalf("ParseJWE returned a %T", key)
+	}
+	wantN, _ := base64.RawURLEncoding.DecodeString(n)
+	wantD, _ := base64.RawURLEncoding.DecodeString(d)
+	if rsaKey.N.Cmp(new(big.Int).SetBytes(wantN)) != 0 || rsaKey.D.Cmp(new(big.Int).SetBytes(wantD)) != 0 {
+		t.Error("Decoded key does not match the RFC 7517 key")
+	}
+	if _, err := pkcs8.ParseJWE([]byte(rfc7517JWE), []byte("wrong")); err == nil {
+		t.Error("expected wrong password to fail")
+	}
+}
+
+func TestCOSEKey(t *testing.T) {
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	keys := []interface{ Equal(crypto.PrivateKey) bool }{edPrivateKey}
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		ecPrivateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey returned: %s", err)
+		}
+		keys = append(keys, ecPrivateKey)
+	}
+	for _, priv := range keys {
+		data, err := pkcs8.MarshalCOSEKey(priv)
+		if err != nil {
+			t.Fatalf("%T: MarshalCOSEKey returned: %s", priv, err)
+		}
+		key, err := pkcs8.ParseCOSEKey(data)
+		if err != nil {
+			t.Fatalf("%T: ParseCOSEKey returned: %s", priv, err)
+		}
+		if !priv.Equal(key) {
+			t.Fatalf("%T: Decoded key does not match original key", priv)
+		}
+	}
+
+	// An OKP key with a kid, key_ops and the RFC 8032 test 1 key pair.
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	pub := "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	data, _ := hex.DecodeString("a6010102436b696404810220062158" + "20" + pub + "2358" + "20" + hex.EncodeToString(seed))
+	key, err := pkcs8.ParseCOSEKey(data)
+	if err != nil {
+		t.Fatalf("ParseCOSEKey returned: %s", err)
+	}
+	if !ed25519.NewKeyFromSeed(seed).Equal(key) {
+		t.Error("Decoded key does not match the expected key")
+	}
+	if _, err := pkcs8.ParseCOSEKey(data[:len(data)-1]); err == nil {
+		t.Error("expected truncated COSE_Key to fail")
+	}
+	mismatched, _ := hex.DecodeString("a6010102436b696404810220062158" + "20" + strings.Repeat("00", 32) + "2358" + "20" + hex.EncodeToString(seed))
+	if _, err := pkcs8.ParseCOSEKey(mismatched); err == nil {
+		t.Error("expected a COSE_Key whose x does not match d to fail")
+	}
+}
+
+func TestParseDKEKWrappedKey(t *testing.T) {
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	share1, share2 := read("dkek/share1.bin"), read("dkek/share2.bin")
+	for blobName, plainName := range map[string]string{
+		"dkek/ec-p256.bin": "openssl/plain-ec.pem",
+		// RSA key in CRT form, key type 6.
+		"dkek/rsa-2048.bin": "openssl/plain-rsa.pem",
+	} {
+		want, _, err := pkcs8.ParsePrivateKeyPEM(read(plainName), nil)
+		if err != nil {
+			t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+		}
+		key, err := pkcs8.ParseDKEKWrappedKey(read(blobName), share1, share2)
+		if err != nil {
+			t.Fatalf("%s: ParseDKEKWrappedKey returned: %s", blobName, err)
+		}
+		if !want.(interface{ Equal(crypto.PrivateKey) bool }).Equal(key) {
+			t.Fatalf("%s: decoded key does not match original key", blobName)
+		}
+		if _, err := pkcs8.MarshalPrivateKey(key, []byte("password"), nil); err != nil {
+			t.Fatalf("%s: MarshalPrivateKey returned: %s", blobName, err)
+		}
+	}
+
+	blob := read("dkek/ec-p256.bin")
+	if _, err := pkcs8.ParseDKEKWrappedKey(blob, share1); err == nil {
+		t.Error("expected a missing share to fail")
+	}
+	tampered := append([]byte(nil), blob...)
+	tampered[len(tampered)-20] ^= 1
+	if _, err := pkcs8.ParseDKEKWrappedKey(tampered, share1, share2); err == nil {
+		t.Error("expected a tampered blob to fail")
+	}
+}
+
+func TestTLSSecretData(t *testing.T) {
+	keyPEM, certPEM, err := pkcs8.CreateSelfSigned([]byte("password"), pkcs8.SelfSignedOpts{
+		CommonName: "example.com",
+		KeyOpts:    pkcs8.LegacyDefaults(),
+	})
+	if err != nil {
+		t.Fatalf("CreateSelfSigned returned: %s", err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(keyPEM, []byte("password"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+
+	data, err := pkcs8.TLSSecretData(keyPEM, []byte("password"), certPEM)
+	if err != nil {
+		t.Fatalf("TLSSecretData returned: %s", err)
+	}
+	plainPEM, err := base64.StdEncoding.DecodeString(data[pkcs8.TLSSecretKeyKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := pem.Decode(plainPEM); block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("tls.key is not an unencrypted PKCS#8 key: %q", plainPEM)
+	}
+	if got, _ := base64.StdEncoding.DecodeString(data[pkcs8.TLSSecretCertKey]); !bytes.Equal(got, certPEM) {
+		t.Error("tls.crt does not hold the certificate")
+	}
+
+	encryptedPEM, gotCert, err := pkcs8.ParseTLSSecretData(data, []byte("new"), pkcs8.LegacyDefaults())
+	if err != nil {
+		t.Fatalf("ParseTLSSecretData returned: %s", err)
+	}
+	if !bytes.Equal(gotCert, certPEM) {
+		t.Error("ParseTLSSecretData did not return the certificate")
+	}
+	key, _, err := pkcs8.ParsePrivateKeyPEM(encryptedPEM, []byte("new"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	if !want.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("Decoded key does not match original key")
+	}
+
+	_, otherCert, err := pkcs8.CreateSelfSigned(nil, pkcs8.SelfSignedOpts{CommonName: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkcs8.TLSSecretData(keyPEM, []byte("password"), otherCert); err == nil {
+		t.Error("expected an error for a certificate of another key")
+	}
+	delete(data, pkcs8.TLSSecretCertKey)
+	if _, _, err := pkcs8.ParseTLSSecretData(data, nil, nil); err == nil {
+		t.Error("expected an error for a Secret without tls.crt")
+	}
+}
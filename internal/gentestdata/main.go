@@ -0,0 +1,241 @@
+// Command gentestdata regenerates the key fixtures used by the tests of
+// package pkcs8.
+//
+// Fixtures reproducing the openssl command line are written to
+// testdata/openssl when an openssl binary is available. The pure Go matrix of
+// every cipher and KDF supported for encryption is written to
+// testdata/matrix; new ciphers and KDFs only need to be added to the lists
+// below to be covered.
+//
+// Run it from the repository root:
+//
+//	go run ./internal/gentestdata
+package main
+
+import (
+	"crypto"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+)
+
+// password is the password of every encrypted fixture.
+const password = "password"
+
+// keys are the unencrypted fixtures every other fixture is derived from.
+var keys = []string{"rsa", "ec", "ed25519", "ed448", "x448"}
+
+var ciphers = []struct {
+	name   string
+	cipher pkcs8.Cipher
+}{
+	{"aes128cbc", pkcs8.AES128CBC},
+	{"aes192cbc", pkcs8.AES192CBC},
+	{"aes256cbc", pkcs8.AES256CBC},
+	{"des3cbc", pkcs8.TripleDESCBC},
+}
+
+var kdfs = []struct {
+	name string
+	opts pkcs8.KDFOpts
+}{
+	{"pbkdf2-sha1", pkcs8.PBKDF2Opts{SaltSize: 8, IterationCount: 2048, HMACHash: crypto.SHA1}},
+	{"pbkdf2-sha256", pkcs8.PBKDF2Opts{SaltSize: 16, IterationCount: 2048, HMACHash: crypto.SHA256}},
+	{"scrypt", pkcs8.ScryptOpts{SaltSize: 16, CostParameter: 1 << 10, BlockSize: 8, ParallelizationParameter: 1}},
+}
+
+// opensslProfiles are the openssl invocations of the fixtures, see
+// testdata/openssl/README.md.
+var opensslProfiles = []struct {
+	name string
+	args []string
+}{
+	{"openssl-3.0-v2-aes256-sha1", []string{"pkcs8", "-topk8", "-v2", "aes-256-cbc", "-v2prf", "hmacWithSHA1", "-iter", "2048"}},
+	{"openssl-3.0-v2-des3-sha1", []string{"pkcs8", "-topk8", "-v2", "des3", "-v2prf", "hmacWithSHA1", "-iter", "2048"}},
+	{"openssl-3.0-v2-aes256-sha256", []string{"pkcs8", "-topk8", "-v2", "aes-256-cbc", "-v2prf", "hmacWithSHA256", "-iter", "2048"}},
+	{"openssl-3.0-v2-aes128-sha256", []string{"pkcs8", "-topk8", "-v2", "aes-128-cbc", "-v2prf", "hmacWithSHA256", "-iter", "2048"}},
+	{"openssl-3.0-default", []string{"pkcs8", "-topk8"}},
+	{"openssl-3.0-scrypt", []string{"pkcs8", "-topk8", "-scrypt"}},
+	{"openssl-3.0-pkey-aes256", []string{"pkey", "-aes-256-cbc"}},
+}
+
+// legacyProfiles are PKCS#12 PBE schemes, only generated for EC keys.
+var legacyProfiles = []string{"RC4-128", "RC4-40", "3DES", "RC2-128", "RC2-40"}
+
+// legacyPBES2Ciphers are PBES2 ciphers only parsed with AllowLegacy, only
+// generated for EC keys.
+var legacyPBES2Ciphers = []string{
+	"rc2-cbc", "rc2-64-cbc", "rc2-40-cbc",
+	"aes-128-cfb", "aes-192-cfb", "aes-256-cfb", "aes-128-ofb", "aes-192-ofb", "aes-256-ofb",
+}
+
+// pvkModes are the PVK encryption modes of openssl rsa, only generated for
+// RSA keys.
+var pvkModes = []string{"strong", "weak", "none"}
+
+func main() {
+	dir := flag.String("dir", "testdata", "testdata directory")
+	opensslPath := flag.String("openssl", "openssl", "openssl binary, empty to skip the openssl fixtures")
+	flag.Parse()
+
+	if err := generateMatrix(*dir); err != nil {
+		log.Fatal(err)
+	}
+	if *opensslPath == "" {
+		return
+	}
+	if _, err := exec.LookPath(*opensslPath); err != nil {
+		log.Printf("openssl not found, skipping the openssl fixtures: %s", err)
+		return
+	}
+	if err := generateOpenSSL(*dir, *opensslPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func plainKeyPath(dir, key string) string {
+	return filepath.Join(dir, "openssl", "plain-"+key+".pem")
+}
+
+func generateMatrix(dir string) error {
+	out := filepath.Join(dir, "matrix")
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		data, err := os.ReadFile(plainKeyPath(dir, key))
+		if err != nil {
+			return err
+		}
+		priv, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		for _, c := range ciphers {
+			for _, k := range kdfs {
+				opts := &pkcs8.Opts{Cipher: c.cipher, KDFOpts: k.opts}
+				encoded, err := pkcs8.MarshalPrivateKeyPEM(priv, []byte(password), opts)
+				if err != nil {
+					return fmt.Errorf("%s/%s/%s: %w", c.name, k.name, key, err)
+				}
+				name := fmt.Sprintf("%s-%s-%s.pem", c.name, k.name, key)
+				if err := os.WriteFile(filepath.Join(out, name), encoded, 0o644); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func generateOpenSSL(dir, openssl string) error {
+	run := func(out string, args ...string) error {
+		cmd := exec.Command(openssl, args...)
+		cmd.Args = append(cmd.Args, "-passout", "pass:"+password, "-out", out)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %s\n%s", strings.Join(cmd.Args, " "), err, output)
+		}
+		return nil
+	}
+
+	for _, key := range keys {
+		in := plainKeyPath(dir, key)
+		for _, p := range opensslProfiles {
+			out := filepath.Join(dir, "openssl", p.name+"-"+key+".pem")
+			if err := run(out, append(p.args, "-in", in)...); err != nil {
+				return err
+			}
+		}
+	}
+	for _, alg := range legacyProfiles {
+		out := filepath.Join(dir, "openssl", "legacy-pbe-sha1-"+strings.ToLower(alg)+"-ec.pem")
+		args := []string{"pkcs8", "-topk8", "-in", plainKeyPath(dir, "ec"), "-v1", "PBE-SHA1-" + alg,
+			"-provider", "legacy", "-provider", "default"}
+		if err := run(out, args...); err != nil {
+			return err
+		}
+	}
+	for _, c := range legacyPBES2Ciphers {
+		out := filepath.Join(dir, "openssl", "legacy-pbes2-"+c+"-ec.pem")
+		args := []string{"pkcs8", "-topk8", "-in", plainKeyPath(dir, "ec"), "-v2", c, "-v2prf", "hmacWithSHA256",
+			"-iter", "2048", "-provider", "legacy", "-provider", "default"}
+		if err := run(out, args...); err != nil {
+			return err
+		}
+	}
+	for _, mode := range pvkModes {
+		out := filepath.Join(dir, "openssl", "pvk-"+mode+"-rsa.pvk")
+		args := []string{"rsa", "-in", plainKeyPath(dir, "rsa"), "-outform", "PVK", "-pvk-" + mode,
+			"-provider", "legacy", "-provider", "default"}
+		if err := run(out, args...); err != nil {
+			return err
+		}
+	}
+
+	// X25519 keys are parsed with crypto/ecdh, hence Go 1.20, and kept out
+	// of the fixtures every Go version parses.
+	x25519 := filepath.Join(dir, "x25519")
+	if err := run(filepath.Join(x25519, "openssl-3.0-default.pem"), "pkcs8", "-topk8", "-in", filepath.Join(x25519, "plain.pem")); err != nil {
+		return err
+	}
+	if err := run(filepath.Join(x25519, "public.pem"), "pkey", "-pubout", "-in", filepath.Join(x25519, "plain.pem")); err != nil {
+		return err
+	}
+
+	// DSA keys have no Equal method, and RSASSA-PSS keys are checked
+	// against their parameters, apart from the other fixtures.
+	for _, name := range []string{"dsa", "rsapss"} {
+		keyDir := filepath.Join(dir, name)
+		if err := run(filepath.Join(keyDir, "openssl-3.0-default.pem"), "pkcs8", "-topk8", "-in", filepath.Join(keyDir, "plain.pem")); err != nil {
+			return err
+		}
+		if err := run(filepath.Join(keyDir, "public.pem"), "pkey", "-pubout", "-in", filepath.Join(keyDir, "plain.pem")); err != nil {
+			return err
+		}
+	}
+
+	// SM2 keys are encrypted with SM4 as GmSSL does, though not with its
+	// hmacWithSM3 PRF, which openssl does not support.
+	sm2 := filepath.Join(dir, "sm2")
+	if err := run(filepath.Join(sm2, "openssl-3.0-sm4.pem"), "pkcs8", "-topk8", "-v2", "sm4-cbc", "-v2prf", "hmacWithSHA256",
+		"-iter", "2048", "-in", filepath.Join(sm2, "plain.pem")); err != nil {
+		return err
+	}
+	if err := run(filepath.Join(sm2, "public.pem"), "pkey", "-pubout", "-in", filepath.Join(sm2, "plain.pem")); err != nil {
+		return err
+	}
+
+	for _, curve := range []string{"p256r1", "p384r1", "p512r1"} {
+		brainpool := filepath.Join(dir, "brainpool")
+		in := filepath.Join(brainpool, "plain-"+curve+".pem")
+		if err := run(filepath.Join(brainpool, "openssl-3.0-default-"+curve+".pem"), "pkcs8", "-topk8", "-in", in); err != nil {
+			return err
+		}
+		if err := run(filepath.Join(brainpool, "public-"+curve+".pem"), "pkey", "-pubout", "-in", in); err != nil {
+			return err
+		}
+	}
+
+	// Check that the fixtures are PEM-encoded.
+	files, err := filepath.Glob(filepath.Join(dir, "openssl", "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if block, _ := pem.Decode(data); block == nil {
+			return fmt.Errorf("%s: no PEM block", file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// JWEOpts contains options for encrypting a private key as a JWE.
+type JWEOpts struct {
+	// Algorithm is the key management algorithm: "PBES2-HS256+A128KW",
+	// "PBES2-HS384+A192KW" or "PBES2-HS512+A256KW".
+	Algorithm string
+	// Encryption is the content encryption algorithm: "A128GCM", "A192GCM",
+	// "A256GCM", "A128CBC-HS256", "A192CBC-HS384" or "A256CBC-HS512".
+	Encryption string
+	// SaltSize is the size of the p2s salt, in bytes, at least 8 as RFC
+	// 7518, section 4.8.1.1, requires. DefaultJWEOpts.SaltSize is used if
+	// zero.
+	SaltSize int
+	// IterationCount is the p2c PBKDF2 iteration count.
+	// DefaultJWEOpts.IterationCount is used if zero.
+	IterationCount int
+}
+
+// DefaultJWEOpts are the default options for encrypting a JWE if none are given.
+var DefaultJWEOpts = &JWEOpts{
+	Algorithm:      "PBES2-HS256+A128KW",
+	Encryption:     "A256GCM",
+	SaltSize:       16,
+	IterationCount: 10000,
+}
+
+var jweKeyManagement = map[string]struct {
+	hash    func() hash.Hash
+	keySize int
+}{
+	"PBES2-HS256+A128KW": {sha256.New, 16},
+	"PBES2-HS384+A192KW": {sha512.New384, 24},
+	"PBES2-HS512+A256KW": {sha512.New, 32},
+}
+
+// jweContentEncryption describes a content encryption algorithm of RFC 7518,
+// section 5. For the CBC-HMAC algorithms, the content-encryption key is the
+// concatenation of the MAC key and the AES key.
+var jweContentEncryption = map[string]struct {
+	keySize int
+	hash    func() hash.Hash
+}{
+	"A128GCM":       {16, nil},
+	"A192GCM":       {24, nil},
+	"A256GCM":       {32, nil},
+	"A128CBC-HS256": {32, sha256.New},
+	"A192CBC-HS384": {48, sha512.New384},
+	"A256CBC-HS512": {64, sha512.New},
+}
+
+type jweHeader struct {
+	Algorithm      string `json:"alg"`
+	Encryption     string `json:"enc"`
+	ContentType    string `json:"cty,omitempty"`
+	Salt           string `json:"p2s"`
+	IterationCount int    `json:"p2c"`
+}
+
+// MarshalJWE encrypts a private key, encoded as a JWK (RFC 7517), into a
+// password-protected JWE in compact serialization (RFC 7516), using the
+// PBES2 key management algorithms of RFC 7518.
+// RSA, ECDSA and Ed25519 keys are supported. Opts can be nil.
+func MarshalJWE(priv interface{}, password []byte, opts *JWEOpts) ([]byte, error) {
+	if opts == nil {
+		opts = DefaultJWEOpts
+	}
+	km, ok := jweKeyManagement[opts.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported JWE algorithm %q", opts.Algorithm)
+	}
+	enc, ok := jweContentEncryption[opts.Encryption]
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported JWE encryption %q", opts.Encryption)
+	}
+	saltSize, iterations := opts.SaltSize, opts.IterationCount
+	if saltSize == 0 {
+		saltSize = DefaultJWEOpts.SaltSize
+	}
+	if iterations == 0 {
+		iterations = DefaultJWEOpts.IterationCount
+	}
+	if saltSize < 8 {
+		return nil, fmt.Errorf("pkcs8: JWE salt size %d below the minimum of 8", saltSize)
+	}
+	if iterations < 0 {
+		return nil, fmt.Errorf("pkcs8: invalid JWE iteration count %d", iterations)
+	}
+
+	jwk, err := marshalJWK(priv)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(jwk)
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(jweHeader{
+		Algorithm:      opts.Algorithm,
+		Encryption:     opts.Encryption,
+		ContentType:    "jwk+json",
+		Salt:           base64.RawURLEncoding.EncodeToString(salt),
+		IterationCount: iterations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+
+	cek := make([]byte, enc.keySize)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	defer zero(cek)
+	kek := jweKEK(password, opts.Algorithm, salt, iterations, km.hash, km.keySize)
+	encryptedKey, err := aesKeyWrap(kek, cek)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv, ciphertext, tag []byte
+	aad := []byte(encodedHeader)
+	if enc.hash == nil {
+		gcm, err := newGCM(cek)
+		if err != nil {
+			return nil, err
+		}
+		iv = make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+		sealed := gcm.Seal(nil, iv, jwk, aad)
+		ciphertext, tag = sealed[:len(jwk)], sealed[len(jwk):]
+	} else {
+		macKey, encKey := cek[:len(cek)/2], cek[len(cek)/2:]
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, err
+		}
+		iv = make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+		if ciphertext, err = cbcEncrypt(block, encKey, iv, jwk); err != nil {
+			return nil, err
+		}
+		tag = jweCBCHMACTag(enc.hash, macKey, aad, iv, ciphertext)
+	}
+
+	return []byte(strings.Join([]string{
+		encodedHeader,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, ".")), nil
+}
+
+// DefaultJWEMaxIterationCount is the largest p2c accepted by ParseJWE. The
+// iteration count comes from the untrusted header and PBKDF2 runs before the
+// password can be checked, so it is bounded as RFC 7518, section 4.8.1.2,
+// recommends.
+const DefaultJWEMaxIterationCount = 2000000
+
+// JWEParseOpts contains options for decrypting a JWE.
+type JWEParseOpts struct {
+	// MaxIterationCount is the largest p2c PBKDF2 iteration count accepted.
+	// DefaultJWEMaxIterationCount is used if zero.
+	MaxIterationCount int
+}
+
+// ParseJWE decrypts a private key protected by MarshalJWE, or by any JOSE
+// implementation using the PBES2 algorithms with AES-GCM or AES-CBC-HMAC
+// content encryption. JWEs whose p2c is above DefaultJWEMaxIterationCount
+// are refused.
+func ParseJWE(jwe []byte, password []byte) (crypto.PrivateKey, error) {
+	return ParseJWEWithOpts(jwe, password, nil)
+}
+
+// ParseJWEWithOpts is like ParseJWE with the given options. Opts can be nil.
+func ParseJWEWithOpts(jwe []byte, password []byte, opts *JWEParseOpts) (crypto.PrivateKey, error) {
+	maxIterations := DefaultJWEMaxIterationCount
+	if opts != nil && opts.MaxIterationCount != 0 {
+		maxIterations = opts.MaxIterationCount
+	}
+	parts := strings.Split(strings.TrimSpace(string(jwe)), ".")
+	if len(parts) != 5 {
+		return nil, errors.New("pkcs8: invalid JWE compact serialization")
+	}
+	var decoded [5][]byte
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, errors.New("pkcs8: invalid JWE compact serialization")
+		}
+		decoded[i] = b
+	}
+
+	var header jweHeader
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, errors.New("pkcs8: invalid JWE header")
+	}
+	km, ok := jweKeyManagement[header.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported JWE algorithm %q", header.Algorithm)
+	}
+	enc, ok := jweContentEncryption[header.Encryption]
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported JWE encryption %q", header.Encryption)
+	}
+	if header.IterationCount <= 0 {
+		return nil, errors.New("pkcs8: invalid JWE iteration count")
+	}
+	if header.IterationCount > maxIterations {
+		return nil, fmt.Errorf("pkcs8: JWE iteration count %d exceeds the maximum of %d", header.IterationCount, maxIterations)
+	}
+	salt, err := base64.RawURLEncoding.DecodeString(header.Salt)
+	if err != nil {
+		return nil, errors.New("pkcs8: invalid JWE salt")
+	}
+
+	kek := jweKEK(password, header.Algorithm, salt, header.IterationCount, km.hash, km.keySize)
+	cek, err := aesKeyUnwrap(kek, decoded[1])
+	if err != nil {
+		return nil, errors.New("pkcs8: incorrect password")
+	}
+	defer zero(cek)
+	if len(cek) != enc.keySize {
+		return nil, errors.New("pkcs8: invalid JWE content encryption key")
+	}
+
+	iv, ciphertext, tag, aad := decoded[2], decoded[3], decoded[4], []byte(parts[0])
+	var jwk []byte
+	if enc.hash == nil {
+		gcm, err := newGCM(cek)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != gcm.NonceSize() {
+			return nil, errors.New("pkcs8: invalid JWE initialization vector")
+		}
+		jwk, err = gcm.Open(nil, iv, append(ciphertext, tag...), aad)
+		if err != nil {
+			return nil, errors.New("pkcs8: JWE authentication failed")
+		}
+	} else {
+		macKey, encKey := cek[:len(cek)/2], cek[len(cek)/2:]
+		expected := jweCBCHMACTag(enc.hash, macKey, aad, iv, ciphertext)
+		if subtle.ConstantTimeCompare(expected, tag) != 1 {
+			return nil, errors.New("pkcs8: JWE authentication failed")
+		}
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != aes.BlockSize {
+			return nil, errors.New("pkcs8: invalid JWE initialization vector")
+		}
+		if jwk, err = cbcDecrypt(block, encKey, iv, ciphertext); err != nil {
+			return nil, err
+		}
+	}
+	defer zero(jwk)
+	return parseJWK(jwk)
+}
+
+// jweCBCHMACTag computes the authentication tag of the AES-CBC-HMAC-SHA2
+// algorithms, see RFC 7518, section 5.2.2.1.
+func jweCBCHMACTag(h func() hash.Hash, macKey, aad, iv, ciphertext []byte) []byte {
+	mac := hmac.New(h, macKey)
+	mac.Write(aad)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	var al [8]byte
+	binary.BigEndian.PutUint64(al[:], uint64(len(aad))*8)
+	mac.Write(al[:])
+	return mac.Sum(nil)[:len(macKey)]
+}
+
+// jweKEK derives the key-encryption key of a PBES2 JWE, see RFC 7518,
+// section 4.8.1.1.
+func jweKEK(password []byte, alg string, p2s []byte, p2c int, h func() hash.Hash, size int) []byte {
+	salt := append(append([]byte(alg), 0), p2s...)
+	return pbkdf2.Key(password, salt, p2c, size, h)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var aesKeyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// aesKeyWrap wraps key with kek as specified in RFC 3394.
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("pkcs8: invalid key size for AES key wrap")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out, aesKeyWrapIV)
+	copy(out[8:], key)
+	var buf [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], out[:8])
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Encrypt(buf[:], buf[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[8*i:8*i+8], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+// aesKeyUnwrap unwraps a key wrapped with kek as specified in RFC 3394.
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("pkcs8: invalid wrapped key size")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+	var buf [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Decrypt(buf[:], buf[:])
+			copy(out[:8], buf[:8])
+			copy(out[8*i:8*i+8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], aesKeyWrapIV) != 1 {
+		return nil, errors.New("pkcs8: AES key unwrap integrity check failed")
+	}
+	return out[8:], nil
+}
+
+// jsonWebKey is a private JWK, see RFC 7517 and RFC 8037.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+	D       string `json:"d,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	P       string `json:"p,omitempty"`
+	Q       string `json:"q,omitempty"`
+	DP      string `json:"dp,omitempty"`
+	DQ      string `json:"dq,omitempty"`
+	QI      string `json:"qi,omitempty"`
+}
+
+func marshalJWK(priv interface{}) ([]byte, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	var jwk jsonWebKey
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, errors.New("pkcs8: multi-prime RSA keys are not supported in JWK")
+		}
+		k.Precompute()
+		jwk = jsonWebKey{
+			KeyType: "RSA",
+			N:       b64(k.N.Bytes()),
+			E:       b64(big.NewInt(int64(k.E)).Bytes()),
+			D:       b64(k.D.Bytes()),
+			P:       b64(k.Primes[0].Bytes()),
+			Q:       b64(k.Primes[1].Bytes()),
+			DP:      b64(k.Precomputed.Dp.Bytes()),
+			DQ:      b64(k.Precomputed.Dq.Bytes()),
+			QI:      b64(k.Precomputed.Qinv.Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk = jsonWebKey{
+			KeyType: "EC",
+			Curve:   k.Curve.Params().Name,
+			X:       b64(k.X.FillBytes(make([]byte, size))),
+			Y:       b64(k.Y.FillBytes(make([]byte, size))),
+			D:       b64(k.D.FillBytes(make([]byte, size))),
+		}
+		if _, err := jwkCurve(jwk.Curve); err != nil {
+			return nil, err
+		}
+	case ed25519.PrivateKey:
+		jwk = jsonWebKey{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       b64(k.Public().(ed25519.PublicKey)),
+			D:       b64(k.Seed()),
+		}
+	default:
+		return nil, errors.New("pkcs8: unsupported key type for JWK")
+	}
+	return json.Marshal(jwk)
+}
+
+func parseJWK(data []byte) (interface{}, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, errors.New("pkcs8: invalid JWK")
+	}
+	field := func(name, v string) ([]byte, error) {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("pkcs8: invalid or missing JWK parameter %q", name)
+		}
+		return b, nil
+	}
+	integer := func(name, v string) (*big.Int, error) {
+		b, err := field(name, v)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch jwk.KeyType {
+	case "RSA":
+		var n, e, d, p, q *big.Int
+		var err error
+		for _, f := range []struct {
+			dst   **big.Int
+			name  string
+			value string
+		}{{&n, "n", jwk.N}, {&e, "e", jwk.E}, {&d, "d", jwk.D}, {&p, "p", jwk.P}, {&q, "q", jwk.Q}} {
+			if *f.dst, err = integer(f.name, f.value); err != nil {
+				return nil, err
+			}
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("pkcs8: invalid RSA public exponent")
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+	case "EC":
+		curve, err := jwkCurve(jwk.Curve)
+		if err != nil {
+			return nil, err
+		}
+		d, err := field("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+		key.Curve = curve
+		key.X, key.Y = curve.ScalarBaseMult(d)
+		if jwk.X != "" {
+			x, err := integer("x", jwk.X)
+			if err != nil || x.Cmp(key.X) != 0 {
+				return nil, errors.New("pkcs8: JWK public key does not match private key")
+			}
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("pkcs8: unsupported JWK curve %q", jwk.Curve)
+		}
+		seed, err := field("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, errors.New("pkcs8: invalid Ed25519 JWK")
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	return nil, fmt.Errorf("pkcs8: unsupported JWK key type %q", jwk.KeyType)
+}
+
+func jwkCurve(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	}
+	return nil, fmt.Errorf("pkcs8: unsupported JWK curve %q", name)
+}
@@ -0,0 +1,158 @@
+package pkcs8
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// KCVMethod selects how a key check value is computed.
+type KCVMethod int
+
+const (
+	// KCVEncryptZeros is the first 3 bytes of a block of zeros encrypted
+	// with the key in ECB mode, the traditional check value of DES and AES
+	// keys.
+	KCVEncryptZeros KCVMethod = iota
+	// KCVCMAC is the first 5 bytes of the CMAC of a block of zeros, the
+	// check value used by payment HSMs for AES keys.
+	KCVCMAC
+)
+
+// KCV returns the key check value of key, a key of the cipher c, computed
+// with method. HSM-centric workflows compare check values to verify that
+// two parties hold the same key without revealing it. c must be one of the
+// CBC or GCM ciphers of the package, e.g. AES256CBC or TripleDESCBC.
+func KCV(c Cipher, key []byte, method KCVMethod) ([]byte, error) {
+	newBlock, err := kcvBlock(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != c.KeySize() {
+		return nil, fmt.Errorf("pkcs8: key must be %d bytes long", c.KeySize())
+	}
+	block, err := newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	zeros := make([]byte, block.BlockSize())
+	switch method {
+	case KCVEncryptZeros:
+		block.Encrypt(zeros, zeros)
+		return zeros[:3], nil
+	case KCVCMAC:
+		return cmac(block, zeros)[:5], nil
+	}
+	return nil, errors.New("pkcs8: unknown check value method")
+}
+
+// kcvBlock returns the function creating the block cipher of c.
+func kcvBlock(c Cipher) (func(key []byte) (cipher.Block, error), error) {
+	switch c := c.(type) {
+	case cipherWithBlock:
+		return c.newBlock, nil
+	case cipherWithGCM:
+		return c.newBlock, nil
+	}
+	return nil, fmt.Errorf("pkcs8: no check value for cipher %s", OIDName(c.OID()))
+}
+
+// DerivedKeyKCV derives the content-encryption key of the PBES2-encrypted
+// key der from password, the key ParseWithDerivedKey expects, and returns
+// its check value computed with method. der is not decrypted. Comparing
+// the result with the check value of a key derived elsewhere, e.g. in an
+// HSM, tells whether both derived the same key.
+func DerivedKeyKCV(der, password []byte, method KCVMethod) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.New("pkcs8: invalid EncryptedPrivateKeyInfo")
+	}
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.New("pkcs8: only PBES2 supported")
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("pkcs8: invalid PBES2 parameters")
+	}
+	c, _, err := defaultRegistry.parseEncryptionScheme(params.EncryptionScheme)
+	if err != nil {
+		return nil, err
+	}
+	kdfParams, err := defaultRegistry.parseKeyDerivationFunc(params.KeyDerivationFunc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := kcvBlock(c); err != nil {
+		return nil, err
+	}
+	// Derive the key decryptPBES2 uses.
+	keySize, err := derivedKeySize(c, kdfParams)
+	if err != nil {
+		return nil, err
+	}
+	key, err := kdfParams.DeriveKey(password, keySize)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	return KCV(c, key, method)
+}
+
+// aesCMAC computes the AES-CMAC of msg, see RFC 4493.
+func aesCMAC(key, msg []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cmac(block, msg), nil
+}
+
+// cmac computes the CMAC of msg with a 64 or 128-bit block cipher, see NIST
+// SP 800-38B.
+func cmac(block cipher.Block, msg []byte) []byte {
+	size := block.BlockSize()
+	rb := byte(0x87)
+	if size == 8 {
+		rb = 0x1b
+	}
+	subkey := func(b []byte) []byte {
+		k := make([]byte, len(b))
+		var carry byte
+		for i := len(b) - 1; i >= 0; i-- {
+			k[i] = b[i]<<1 | carry
+			carry = b[i] >> 7
+		}
+		if carry != 0 {
+			k[len(k)-1] ^= rb
+		}
+		return k
+	}
+	l := make([]byte, size)
+	block.Encrypt(l, l)
+	k1 := subkey(l)
+	k2 := subkey(k1)
+
+	n := (len(msg) + size - 1) / size
+	last := make([]byte, size)
+	if n > 0 && len(msg)%size == 0 {
+		xorBytes(last, msg[(n-1)*size:], k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		copy(last, msg[(n-1)*size:])
+		last[len(msg)-(n-1)*size] = 0x80
+		xorBytes(last, last, k2)
+	}
+
+	x := make([]byte, size)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, x, msg[i*size:])
+		block.Encrypt(x, x)
+	}
+	xorBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x
+}
@@ -0,0 +1,175 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"hash"
+)
+
+func init() {
+	// GOST R 34.11-94 has no crypto.Hash value, so keys exported by
+	// CryptoPro CSP with the id-HMACGostR3411-94 PRF can be parsed but not
+	// written.
+	prfHashFuncs[oidHMACGOSTR3411_94.String()] = newGOSTR3411_94
+	defaultRegistry.SetLegacy(oidHMACGOSTR3411_94, true)
+}
+
+// gostR3411Size is the digest and block size of GOST R 34.11-94.
+const gostR3411Size = 32
+
+// gostR3411CryptoProSBox is the S-box of the GOST 28147-89 encryptions of
+// the step function under id-GostR3411-94-CryptoProParamSet, RFC 4357,
+// section 11.2, the parameters of the CryptoPro HMAC.
+var gostR3411CryptoProSBox = [8][16]byte{
+	{0xa, 0x4, 0x5, 0x6, 0x8, 0x1, 0x3, 0x7, 0xd, 0xc, 0xe, 0x0, 0x9, 0x2, 0xb, 0xf},
+	{0x5, 0xf, 0x4, 0x0, 0x2, 0xd, 0xb, 0x9, 0x1, 0x7, 0x6, 0x3, 0xc, 0xe, 0xa, 0x8},
+	{0x7, 0xf, 0xc, 0xe, 0x9, 0x4, 0x1, 0x0, 0x3, 0xb, 0x5, 0x2, 0x6, 0xa, 0x8, 0xd},
+	{0x4, 0xa, 0x7, 0xc, 0x0, 0xf, 0x2, 0x8, 0xe, 0x1, 0x6, 0x5, 0xd, 0xb, 0x9, 0x3},
+	{0x7, 0x6, 0x4, 0xb, 0x9, 0xc, 0x2, 0xa, 0x1, 0x8, 0x0, 0xe, 0xf, 0xd, 0x3, 0x5},
+	{0x7, 0x6, 0x2, 0x4, 0xd, 0x9, 0xf, 0x0, 0xa, 0x1, 0x5, 0xb, 0x8, 0xe, 0xc, 0x3},
+	{0xd, 0xe, 0x4, 0x1, 0x7, 0x0, 0x5, 0xa, 0x3, 0xc, 0x8, 0xf, 0x6, 0x2, 0x9, 0xb},
+	{0x1, 0x3, 0xa, 0x9, 0x5, 0xb, 0x4, 0xf, 0x8, 0x6, 0x7, 0xe, 0xd, 0x0, 0x2, 0xc},
+}
+
+// gostR3411Digest computes GOST R 34.11-94 with the CryptoPro parameters and
+// a zero starting value, RFC 5831. Blocks and the 256-bit values of the
+// algorithm are little-endian byte strings, as in CryptoPro and OpenSSL.
+type gostR3411Digest struct {
+	h   [gostR3411Size]byte
+	sum [gostR3411Size]byte
+	buf [gostR3411Size]byte
+	n   int
+	len uint64
+}
+
+func newGOSTR3411_94() hash.Hash {
+	return new(gostR3411Digest)
+}
+
+func (d *gostR3411Digest) Size() int { return gostR3411Size }
+
+// BlockSize is the block size of the HMAC of RFC 4357, section 3.1.
+func (d *gostR3411Digest) BlockSize() int { return gostR3411Size }
+
+func (d *gostR3411Digest) Reset() {
+	*d = gostR3411Digest{}
+}
+
+func (d *gostR3411Digest) Write(p []byte) (int, error) {
+	written := len(p)
+	d.len += uint64(written)
+	if d.n > 0 {
+		c := copy(d.buf[d.n:], p)
+		d.n += c
+		p = p[c:]
+		if d.n < gostR3411Size {
+			return written, nil
+		}
+		d.block(d.buf[:])
+		d.n = 0
+	}
+	for len(p) >= gostR3411Size {
+		d.block(p[:gostR3411Size])
+		p = p[gostR3411Size:]
+	}
+	d.n = copy(d.buf[:], p)
+	return written, nil
+}
+
+func (d *gostR3411Digest) Sum(in []byte) []byte {
+	d0 := *d
+	if d0.n > 0 {
+		var last [gostR3411Size]byte
+		copy(last[:], d0.buf[:d0.n])
+		d0.block(last[:])
+	}
+	// The length block holds the message length in bits.
+	var length [gostR3411Size]byte
+	bitLen := d0.len << 3
+	for i := 0; bitLen > 0; i++ {
+		length[i] = byte(bitLen)
+		bitLen >>= 8
+	}
+	gostR3411Step(&d0.h, &length)
+	gostR3411Step(&d0.h, &d0.sum)
+	return append(in, d0.h[:]...)
+}
+
+// block hashes the block m, adding it to the checksum.
+func (d *gostR3411Digest) block(m []byte) {
+	var block [gostR3411Size]byte
+	copy(block[:], m)
+	gostR3411Step(&d.h, &block)
+	var carry uint16
+	for i := range d.sum {
+		carry += uint16(d.sum[i]) + uint16(block[i])
+		d.sum[i] = byte(carry)
+		carry >>= 8
+	}
+}
+
+// gostR3411Step is the step function of RFC 5831, section 6: it encrypts
+// each quarter of h under a key derived from h and m, and mixes the result
+// with h and m through the psi shift register.
+func gostR3411Step(h, m *[gostR3411Size]byte) {
+	var s, w, key [gostR3411Size]byte
+	u, v := *h, *m
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			gostR3411A(&u)
+			if i == 2 {
+				// The constant C3, the only nonzero one.
+				for _, j := range []int{1, 3, 5, 7, 8, 10, 12, 14, 17, 18, 20, 23, 24, 28, 29, 31} {
+					u[j] = ^u[j]
+				}
+			}
+			gostR3411A(&v)
+			gostR3411A(&v)
+		}
+		for j := range w {
+			w[j] = u[j] ^ v[j]
+		}
+		// The permutation P.
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 8; k++ {
+				key[j+4*k] = w[8*j+k]
+			}
+		}
+		c, _ := newGOST28147Cipher(key[:], &gostR3411CryptoProSBox)
+		c.Encrypt(s[8*i:], h[8*i:])
+	}
+	for i := 0; i < 12; i++ {
+		gostR3411Psi(&s)
+	}
+	for i := range s {
+		s[i] ^= m[i]
+	}
+	gostR3411Psi(&s)
+	for i := range s {
+		s[i] ^= h[i]
+	}
+	for i := 0; i < 61; i++ {
+		gostR3411Psi(&s)
+	}
+	*h = s
+}
+
+// gostR3411A is the transformation A, shifting y out of y4||y3||y2||y1 and
+// shifting y1 xor y2 in.
+func gostR3411A(y *[gostR3411Size]byte) {
+	var y1 [8]byte
+	copy(y1[:], y[:8])
+	copy(y[:], y[8:])
+	for i := range y1 {
+		y[24+i] = y1[i] ^ y[i]
+	}
+}
+
+// gostR3411Psi is the linear feedback shift register psi on 16-bit words.
+func gostR3411Psi(y *[gostR3411Size]byte) {
+	lo := y[0] ^ y[2] ^ y[4] ^ y[6] ^ y[24] ^ y[30]
+	hi := y[1] ^ y[3] ^ y[5] ^ y[7] ^ y[25] ^ y[31]
+	copy(y[:], y[2:])
+	y[30], y[31] = lo, hi
+}
@@ -3,64 +3,203 @@ package pkcs8
 import (
 	"crypto"
 	"crypto/sha1"
-	"crypto/sha256"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
+	"fmt"
 	"hash"
+	"sync"
 
 	"golang.org/x/crypto/pbkdf2"
+	// Register the hashes of the SHA-3 PRFs.
+	_ "golang.org/x/crypto/sha3"
 )
 
 var (
-	oidPKCS5PBKDF2        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
-	oidHMACWithSHA1       = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
-	oidHMACWithSHA256     = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidPKCS5PBKDF2       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1      = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256    = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA3_224  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 13}
+	oidHMACWithSHA3_256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 14}
+	oidHMACWithSHA3_384  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 15}
+	oidHMACWithSHA3_512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 16}
+	oidHMACWithRIPEMD160 = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 8, 1, 4}
+	oidHMACWithSM3       = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401, 2}
+	oidHMACGOSTR3411_94  = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 10}
 )
 
 func init() {
 	RegisterKDF(oidPKCS5PBKDF2, func() KDFParameters {
 		return new(pbkdf2Params)
 	})
+	defaultRegistry.SetLegacy(oidHMACWithRIPEMD160, true)
+}
+
+// prfHashes maps the OIDs of PBKDF2 PRFs to their HMAC hash functions, and
+// prfOIDs maps them back. Both are guarded by prfMu, as is saltSources.
+var (
+	prfMu     sync.RWMutex
+	prfHashes = map[string]crypto.Hash{
+		oidHMACWithSHA1.String():     crypto.SHA1,
+		oidHMACWithSHA256.String():   crypto.SHA256,
+		oidHMACWithSHA3_224.String(): crypto.SHA3_224,
+		oidHMACWithSHA3_256.String(): crypto.SHA3_256,
+		oidHMACWithSHA3_384.String(): crypto.SHA3_384,
+		oidHMACWithSHA3_512.String(): crypto.SHA3_512,
+		// Only for parsing keys written by old OpenSSL builds.
+		oidHMACWithRIPEMD160.String(): crypto.RIPEMD160,
+	}
+	prfOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA1:     oidHMACWithSHA1,
+		crypto.SHA256:   oidHMACWithSHA256,
+		crypto.SHA3_224: oidHMACWithSHA3_224,
+		crypto.SHA3_256: oidHMACWithSHA3_256,
+		crypto.SHA3_384: oidHMACWithSHA3_384,
+		crypto.SHA3_512: oidHMACWithSHA3_512,
+	}
+)
+
+// prfHashFuncs maps the OIDs of PBKDF2 PRFs whose hash has no crypto.Hash
+// value to their hash function. Keys using them can be parsed but not
+// written.
+var prfHashFuncs = map[string]func() hash.Hash{
+	// The hmacWithSM3 PRF of GmSSL.
+	oidHMACWithSM3.String(): newSM3,
+}
+
+// RegisterPRF registers the OID of a PBKDF2 PRF computing HMAC with the given
+// hash function, both to parse keys and to encrypt them with
+// PBKDF2Opts.HMACHash set to h. Hashes such as BLAKE2b or SHA-3 are available
+// once their package is linked; proprietary ones can be plugged in with
+// crypto.RegisterHash. A hash that already has a PRF OID, e.g. SHA-256,
+// keeps being encrypted with it.
+func RegisterPRF(oid asn1.ObjectIdentifier, h crypto.Hash) {
+	prfMu.Lock()
+	defer prfMu.Unlock()
+	prfHashes[oid.String()] = h
+	if _, ok := prfOIDs[h]; !ok {
+		prfOIDs[h] = oid
+	}
 }
 
 func newHashFromPRF(ai pkix.AlgorithmIdentifier) (func() hash.Hash, error) {
-	switch {
-	case len(ai.Algorithm) == 0 || ai.Algorithm.Equal(oidHMACWithSHA1):
+	if len(ai.Algorithm) == 0 {
 		return sha1.New, nil
-	case ai.Algorithm.Equal(oidHMACWithSHA256):
-		return sha256.New, nil
-	default:
+	}
+	if f, ok := prfHashFuncs[ai.Algorithm.String()]; ok {
+		return f, nil
+	}
+	prfMu.RLock()
+	h, ok := prfHashes[ai.Algorithm.String()]
+	prfMu.RUnlock()
+	if !ok || !h.Available() {
 		return nil, errors.New("pkcs8: unsupported hash function")
 	}
+	return h.New, nil
 }
 
 func newPRFParamFromHash(h crypto.Hash) (pkix.AlgorithmIdentifier, error) {
-	switch h {
-	case crypto.SHA1:
-		return pkix.AlgorithmIdentifier{
-			Algorithm:  oidHMACWithSHA1,
-			Parameters: asn1.RawValue{Tag: asn1.TagNull}}, nil
-	case crypto.SHA256:
-		return pkix.AlgorithmIdentifier{
-			Algorithm:  oidHMACWithSHA256,
-			Parameters: asn1.RawValue{Tag: asn1.TagNull}}, nil
+	prfMu.RLock()
+	oid, ok := prfOIDs[h]
+	prfMu.RUnlock()
+	if !ok || !h.Available() {
+		return pkix.AlgorithmIdentifier{}, errors.New("pkcs8: unsupported hash function")
 	}
-	return pkix.AlgorithmIdentifier{}, errors.New("pkcs8: unsupported hash function")
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oid,
+		Parameters: asn1.RawValue{Tag: asn1.TagNull}}, nil
 }
 
+// saltSources maps the OIDs of PBKDF2 salt sources to the functions
+// computing the salt from their parameters.
+var saltSources = map[string]func(params []byte) ([]byte, error){}
+
+// RegisterSaltSource registers the function computing the PBKDF2 salt of the
+// otherSource form of RFC 8018, an AlgorithmIdentifier in place of the salt,
+// for the salt source oid. The function is given the DER-encoded parameters
+// of the AlgorithmIdentifier, nil if absent. RFC 8018 defines no salt
+// source: they are agreed upon by the applications using them.
+func RegisterSaltSource(oid asn1.ObjectIdentifier, source func(params []byte) ([]byte, error)) {
+	prfMu.Lock()
+	defer prfMu.Unlock()
+	saltSources[oid.String()] = source
+}
+
+// pbkdf2Params are the PBKDF2-params of RFC 8018. The salt is a CHOICE of
+// the specified salt, an OCTET STRING, and otherSource, an
+// AlgorithmIdentifier.
 type pbkdf2Params struct {
-	Salt           []byte
+	Salt           asn1.RawValue
 	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
 	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
 }
 
+func specifiedSalt(salt []byte) asn1.RawValue {
+	return asn1.RawValue{Tag: asn1.TagOctetString, Bytes: salt}
+}
+
+// specifiedSalt returns the salt of p if it is of the specified form.
+func (p pbkdf2Params) specifiedSalt() ([]byte, bool) {
+	if p.Salt.Class != asn1.ClassUniversal || p.Salt.Tag != asn1.TagOctetString || p.Salt.IsCompound {
+		return nil, false
+	}
+	return p.Salt.Bytes, true
+}
+
+// saltSource returns the AlgorithmIdentifier of the salt of p if it is of
+// the otherSource form.
+func (p pbkdf2Params) saltSource() (pkix.AlgorithmIdentifier, bool) {
+	var source pkix.AlgorithmIdentifier
+	if p.Salt.Class != asn1.ClassUniversal || p.Salt.Tag != asn1.TagSequence {
+		return source, false
+	}
+	if rest, err := asn1.Unmarshal(p.Salt.FullBytes, &source); err != nil || len(rest) != 0 {
+		return source, false
+	}
+	return source, true
+}
+
+// validate checks that the salt of p is one of the CHOICEs.
+func (p *pbkdf2Params) validate() error {
+	if _, ok := p.specifiedSalt(); ok {
+		return nil
+	}
+	if _, ok := p.saltSource(); ok {
+		return nil
+	}
+	return errors.New("pkcs8: invalid PBKDF2 salt")
+}
+
+// salt returns the salt of p, computing it with the registered salt source
+// for the otherSource form.
+func (p pbkdf2Params) salt() ([]byte, error) {
+	if salt, ok := p.specifiedSalt(); ok {
+		return salt, nil
+	}
+	source, ok := p.saltSource()
+	if !ok {
+		return nil, errors.New("pkcs8: invalid PBKDF2 salt")
+	}
+	prfMu.RLock()
+	f, ok := saltSources[source.Algorithm.String()]
+	prfMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported PBKDF2 salt source %s", OIDName(source.Algorithm))
+	}
+	return f(source.Parameters.FullBytes)
+}
+
 func (p pbkdf2Params) DeriveKey(password []byte, size int) (key []byte, err error) {
 	h, err := newHashFromPRF(p.PRF)
 	if err != nil {
 		return nil, err
 	}
-	return pbkdf2.Key(password, p.Salt, p.IterationCount, size, h), nil
+	salt, err := p.salt()
+	if err != nil {
+		return nil, err
+	}
+	return pbkdf2.Key(password, salt, p.IterationCount, size, h), nil
 }
 
 // PBKDF2Opts contains options for the PBKDF2 key derivation function.
@@ -78,7 +217,7 @@ func (p PBKDF2Opts) DeriveKey(password, salt []byte, size int) (
 	if err != nil {
 		return nil, nil, err
 	}
-	params = pbkdf2Params{salt, p.IterationCount, prfParam}
+	params = pbkdf2Params{Salt: specifiedSalt(salt), IterationCount: p.IterationCount, PRF: prfParam}
 	return key, params, nil
 }
 
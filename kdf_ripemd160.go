@@ -0,0 +1,10 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	// Register the hash of the RIPEMD-160 PRF, a legacy PRF only used by old
+	// OpenSSL builds.
+	_ "golang.org/x/crypto/ripemd160"
+)
@@ -2,19 +2,15 @@ package pkcs8
 
 import (
 	"encoding/asn1"
-
-	"golang.org/x/crypto/scrypt"
 )
 
 var (
 	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
 )
 
-func init() {
-	RegisterKDF(oidScrypt, func() KDFParameters {
-		return new(scryptParams)
-	})
-}
+// The scrypt implementation, scryptKey, and the registration of the KDF are
+// in scrypt.go, which the pkcs8_noscrypt build tag replaces with
+// scrypt_stub.go.
 
 type scryptParams struct {
 	Salt                     []byte
@@ -24,7 +20,7 @@ type scryptParams struct {
 }
 
 func (p scryptParams) DeriveKey(password []byte, size int) (key []byte, err error) {
-	return scrypt.Key(password, p.Salt, p.CostParameter, p.BlockSize,
+	return scryptKey(password, p.Salt, p.CostParameter, p.BlockSize,
 		p.ParallelizationParameter, size)
 }
 
@@ -39,7 +35,7 @@ type ScryptOpts struct {
 func (p ScryptOpts) DeriveKey(password, salt []byte, size int) (
 	key []byte, params KDFParameters, err error) {
 
-	key, err = scrypt.Key(password, salt, p.CostParameter, p.BlockSize,
+	key, err = scryptKey(password, salt, p.CostParameter, p.BlockSize,
 		p.ParallelizationParameter, size)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,248 @@
+package pkcs8
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Keystore holds several encrypted private keys addressed by alias, so that
+// an application can keep its keys in a single file rather than one file
+// per key. The keys are stored as EncryptedPrivateKeyInfo, each with its
+// own password, and only decrypted by Get.
+//
+// The zero Keystore is empty and ready to use. A Keystore must not be
+// modified while in use.
+type Keystore struct {
+	entries []keystoreEntry
+}
+
+// KeystoreVersion is the version of the keystore format written by
+// Keystore.Marshal. Files of older versions are read and can be rewritten
+// with UpgradeBundle.
+const KeystoreVersion = 2
+
+// keystoreFile is the encoding of a Keystore:
+//
+//	Keystore ::= SEQUENCE {
+//	  version INTEGER,
+//	  entries SEQUENCE OF SEQUENCE {
+//	    alias UTF8String,
+//	    key   EncryptedPrivateKeyInfo,
+//	    added GeneralizedTime OPTIONAL -- since version 1
+//	  },
+//	  integrity [0] EXPLICIT SEQUENCE { -- since version 2
+//	    algorithm AlgorithmIdentifier,
+//	    value     OCTET STRING } OPTIONAL }
+//
+// Every version may change the structure following the version field.
+type keystoreFile struct {
+	Version   int
+	Entries   []keystoreEntry
+	Integrity keystoreIntegrity `asn1:"optional,explicit,tag:0"`
+}
+
+type keystoreEntry struct {
+	Alias string `asn1:"utf8"`
+	Key   asn1.RawValue
+	Added time.Time `asn1:"optional,generalized"`
+}
+
+// keystoreMigrations upgrade the encoding of a keystore of version i to
+// version i+1. Structural changes to the format, and to the encryption of
+// the keys when defaults change, are added here so that old files keep
+// being read.
+var keystoreMigrations = []func(der []byte) ([]byte, error){
+	// Version 1 adds the time entries were added, unknown for older ones.
+	func(der []byte) ([]byte, error) {
+		var file struct {
+			Version int
+			Entries []struct {
+				Alias string `asn1:"utf8"`
+				Key   asn1.RawValue
+			}
+		}
+		if rest, err := asn1.Unmarshal(der, &file); err != nil || len(rest) != 0 {
+			return nil, errors.New("pkcs8: invalid keystore")
+		}
+		upgraded := keystoreFile{Version: 1}
+		for _, e := range file.Entries {
+			upgraded.Entries = append(upgraded.Entries, keystoreEntry{Alias: e.Alias, Key: e.Key})
+		}
+		return asn1.Marshal(upgraded)
+	},
+	// Version 2 adds the optional integrity layer.
+	func(der []byte) ([]byte, error) {
+		var file struct {
+			Version int
+			Entries []keystoreEntry
+		}
+		if rest, err := asn1.Unmarshal(der, &file); err != nil || len(rest) != 0 {
+			return nil, errors.New("pkcs8: invalid keystore")
+		}
+		file.Version = 2
+		return asn1.Marshal(file)
+	},
+}
+
+// BundleVersion returns the format version of a DER-encoded keystore.
+func BundleVersion(der []byte) (int, error) {
+	var header struct {
+		Version int
+	}
+	if _, err := asn1.Unmarshal(der, &header); err != nil {
+		return 0, errors.New("pkcs8: invalid keystore")
+	}
+	return header.Version, nil
+}
+
+// UpgradeBundle rewrites a DER-encoded keystore of any version in the
+// current format, KeystoreVersion, applying the migrations of every version
+// in between. The keys are not decrypted. A keystore already current is
+// returned as is; one written by a newer version of the package is refused.
+func UpgradeBundle(der []byte) ([]byte, error) {
+	version, err := BundleVersion(der)
+	if err != nil {
+		return nil, err
+	}
+	if version < 0 || version > KeystoreVersion {
+		return nil, fmt.Errorf("pkcs8: unsupported keystore version %d", version)
+	}
+	for ; version < KeystoreVersion; version++ {
+		if der, err = keystoreMigrations[version](der); err != nil {
+			return nil, fmt.Errorf("pkcs8: upgrading keystore to version %d: %w", version+1, err)
+		}
+	}
+	return der, nil
+}
+
+// ParseKeystore parses a DER-encoded keystore written by Keystore.Marshal,
+// upgrading it in memory if it has an older version. The keys are not
+// decrypted. The integrity layer of a keystore written by MarshalWithMAC or
+// MarshalSigned is not checked: use ParseKeystoreWithMAC or
+// ParseKeystoreWithSignature to require it.
+func ParseKeystore(der []byte) (*Keystore, error) {
+	der, err := UpgradeBundle(der)
+	if err != nil {
+		return nil, err
+	}
+	var file keystoreFile
+	if rest, err := asn1.Unmarshal(der, &file); err != nil || len(rest) != 0 {
+		return nil, errors.New("pkcs8: invalid keystore")
+	}
+	ks := &Keystore{}
+	for _, e := range file.Entries {
+		if ks.find(e.Alias) >= 0 {
+			return nil, fmt.Errorf("pkcs8: duplicate keystore alias %q", e.Alias)
+		}
+		if encryptedKeyContainer(e.Key.FullBytes) != "EncryptedPrivateKeyInfo" {
+			return nil, fmt.Errorf("pkcs8: keystore entry %q is not an encrypted key", e.Alias)
+		}
+		ks.entries = append(ks.entries, e)
+	}
+	return ks, nil
+}
+
+// Marshal returns the DER encoding of the keystore, in the current format.
+func (ks *Keystore) Marshal() ([]byte, error) {
+	return asn1.Marshal(keystoreFile{Version: KeystoreVersion, Entries: ks.entries})
+}
+
+// Aliases returns the aliases of the keys, in the order they were added.
+func (ks *Keystore) Aliases() []string {
+	aliases := make([]string, len(ks.entries))
+	for i, e := range ks.entries {
+		aliases[i] = e.Alias
+	}
+	return aliases
+}
+
+// Add encrypts priv with password and opts, DefaultOpts if nil, and stores
+// it under alias, which must not be in use.
+func (ks *Keystore) Add(alias string, priv interface{}, password []byte, opts *Opts) error {
+	if len(password) == 0 {
+		return errors.New("pkcs8: password required to add a key to a keystore")
+	}
+	der, err := MarshalPrivateKey(priv, password, opts)
+	if err != nil {
+		return err
+	}
+	return ks.Import(alias, der)
+}
+
+// Import stores the DER-encoded EncryptedPrivateKeyInfo der under alias,
+// which must not be in use, without decrypting it, e.g. to gather existing
+// key files into the keystore.
+func (ks *Keystore) Import(alias string, der []byte) error {
+	if ks.find(alias) >= 0 {
+		return fmt.Errorf("pkcs8: keystore alias %q already in use", alias)
+	}
+	if encryptedKeyContainer(der) != "EncryptedPrivateKeyInfo" {
+		return errors.New("pkcs8: only encrypted keys can be stored in a keystore")
+	}
+	ks.entries = append(ks.entries, keystoreEntry{
+		Alias: alias,
+		Key:   asn1.RawValue{FullBytes: append([]byte(nil), der...)},
+		Added: time.Now().UTC().Truncate(time.Second),
+	})
+	return nil
+}
+
+// Added returns the time the key stored under alias was added to the
+// keystore, if known: it is not for keys of version 0 keystores.
+func (ks *Keystore) Added(alias string) (time.Time, bool) {
+	i := ks.find(alias)
+	if i < 0 || ks.entries[i].Added.IsZero() {
+		return time.Time{}, false
+	}
+	return ks.entries[i].Added, true
+}
+
+// Get decrypts and returns the key stored under alias.
+func (ks *Keystore) Get(alias string, password []byte) (crypto.PrivateKey, error) {
+	i := ks.find(alias)
+	if i < 0 {
+		return nil, fmt.Errorf("pkcs8: no key with alias %q in keystore", alias)
+	}
+	key, _, err := ParsePrivateKey(ks.entries[i].Key.FullBytes, password)
+	return key, err
+}
+
+// Delete removes the key stored under alias.
+func (ks *Keystore) Delete(alias string) error {
+	i := ks.find(alias)
+	if i < 0 {
+		return fmt.Errorf("pkcs8: no key with alias %q in keystore", alias)
+	}
+	ks.entries = append(ks.entries[:i], ks.entries[i+1:]...)
+	return nil
+}
+
+// ChangePassword re-encrypts the key stored under alias with newPassword
+// and opts, DefaultOpts if nil, as ReEncrypt does.
+func (ks *Keystore) ChangePassword(alias string, oldPassword, newPassword []byte, opts *Opts) error {
+	i := ks.find(alias)
+	if i < 0 {
+		return fmt.Errorf("pkcs8: no key with alias %q in keystore", alias)
+	}
+	if len(newPassword) == 0 {
+		return errors.New("pkcs8: password required to store a key in a keystore")
+	}
+	der, err := ReEncrypt(ks.entries[i].Key.FullBytes, oldPassword, newPassword, opts)
+	if err != nil {
+		return err
+	}
+	ks.entries[i].Key = asn1.RawValue{FullBytes: der}
+	return nil
+}
+
+func (ks *Keystore) find(alias string) int {
+	for i, e := range ks.entries {
+		if e.Alias == alias {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,280 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	oidPBMAC1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 14}
+	oidSHA256WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	errKeystoreIntegrity = errors.New("pkcs8: keystore integrity check failed: wrong password, wrong key or tampered file")
+)
+
+// keystoreIntegrity is the integrity layer of a keystore: a MAC or a
+// signature of the DER encoding of its entries.
+type keystoreIntegrity struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Value     []byte
+}
+
+// pbmac1Params are the PBMAC1-params of RFC 8018, section A.5.
+type pbmac1Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	MessageAuthScheme pkix.AlgorithmIdentifier
+}
+
+// MarshalWithMAC is Marshal with an integrity layer: an HMAC of the entries
+// keyed from password with PBMAC1 (RFC 8018, as used by PKCS#12 since RFC
+// 9579), so that tampering is detected by VerifyKeystoreMAC before any key
+// is decrypted. The KDF options of opts, DefaultOpts if nil, must be
+// PBKDF2Opts; the HMAC uses its hash. The password may differ from those of
+// the keys.
+func (ks *Keystore) MarshalWithMAC(password []byte, opts *Opts) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("pkcs8: password required to MAC a keystore")
+	}
+	if opts == nil {
+		opts = defaultMarshalOpts()
+	}
+	kdf, ok := opts.KDFOpts.(PBKDF2Opts)
+	if !ok {
+		return nil, errors.New("pkcs8: PBMAC1 requires PBKDF2")
+	}
+	salt := make([]byte, kdf.SaltSize)
+	if _, err := io.ReadFull(opts.rand(), salt); err != nil {
+		return nil, err
+	}
+	size := kdf.HMACHash.Size()
+	key, kdfParams, err := kdf.DeriveKey(password, salt, size)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	p := kdfParams.(pbkdf2Params)
+	// RFC 9579 requires the key length of PBMAC1.
+	p.KeyLength = size
+	kdfDER, err := asn1.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbmac1Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPKCS5PBKDF2, Parameters: asn1.RawValue{FullBytes: kdfDER}},
+		MessageAuthScheme: p.PRF,
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := asn1.Marshal(ks.entries)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(kdf.HMACHash.New, key)
+	mac.Write(entries)
+	return asn1.Marshal(keystoreFile{
+		Version: KeystoreVersion,
+		Entries: ks.entries,
+		Integrity: keystoreIntegrity{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPBMAC1, Parameters: asn1.RawValue{FullBytes: params}},
+			Value:     mac.Sum(nil),
+		},
+	})
+}
+
+// MarshalSigned is Marshal with an integrity layer: a signature of the
+// entries by signer, checked by VerifyKeystoreSignature before any key is
+// decrypted. RSA keys sign with PKCS #1 v1.5 and SHA-256, ECDSA keys with
+// SHA-256.
+func (ks *Keystore) MarshalSigned(signer crypto.Signer) ([]byte, error) {
+	entries, err := asn1.Marshal(ks.entries)
+	if err != nil {
+		return nil, err
+	}
+	var alg pkix.AlgorithmIdentifier
+	var digest []byte
+	opts := crypto.SignerOpts(crypto.SHA256)
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		alg = pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		alg = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	case ed25519.PublicKey:
+		alg = pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEd25519}
+		digest, opts = entries, crypto.Hash(0)
+	default:
+		return nil, fmt.Errorf("pkcs8: unsupported signing key type %T", signer.Public())
+	}
+	if digest == nil {
+		sum := sha256.Sum256(entries)
+		digest = sum[:]
+	}
+	signature, err := signer.Sign(defaultMarshalOpts().rand(), digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(keystoreFile{
+		Version:   KeystoreVersion,
+		Entries:   ks.entries,
+		Integrity: keystoreIntegrity{Algorithm: alg, Value: signature},
+	})
+}
+
+// DefaultKeystoreMaxIterationCount is the largest PBKDF2 iteration count of
+// a keystore MAC accepted by VerifyKeystoreMAC. The iteration count comes
+// from the file being authenticated and PBKDF2 runs before tampering can be
+// detected, so it is bounded as for JWE.
+const DefaultKeystoreMaxIterationCount = 2000000
+
+// KeystoreMACOpts contains options for verifying the MAC of a keystore.
+type KeystoreMACOpts struct {
+	// MaxIterationCount is the largest PBKDF2 iteration count accepted.
+	// DefaultKeystoreMaxIterationCount is used if zero.
+	MaxIterationCount int
+}
+
+// VerifyKeystoreMAC checks the MAC of a keystore written by MarshalWithMAC
+// with password. It fails for keystores without one, and for those whose
+// iteration count is above DefaultKeystoreMaxIterationCount.
+func VerifyKeystoreMAC(der, password []byte) error {
+	return VerifyKeystoreMACWithOpts(der, password, nil)
+}
+
+// VerifyKeystoreMACWithOpts is like VerifyKeystoreMAC with the given
+// options. Opts can be nil.
+func VerifyKeystoreMACWithOpts(der, password []byte, opts *KeystoreMACOpts) error {
+	maxIterations := DefaultKeystoreMaxIterationCount
+	if opts != nil && opts.MaxIterationCount != 0 {
+		maxIterations = opts.MaxIterationCount
+	}
+	entries, integrity, err := parseKeystoreIntegrity(der)
+	if err != nil {
+		return err
+	}
+	if !integrity.Algorithm.Algorithm.Equal(oidPBMAC1) {
+		return errors.New("pkcs8: keystore is not protected by a MAC")
+	}
+	var params pbmac1Params
+	if _, err := asn1.Unmarshal(integrity.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return errors.New("pkcs8: invalid PBMAC1 parameters")
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPKCS5PBKDF2) {
+		return errors.New("pkcs8: PBMAC1 requires PBKDF2")
+	}
+	kdfParams, err := defaultRegistry.parseKeyDerivationFunc(params.KeyDerivationFunc)
+	if err != nil {
+		return err
+	}
+	h, err := newHashFromPRF(params.MessageAuthScheme)
+	if err != nil {
+		return err
+	}
+	// PBKDF2 may have been registered again with RegisterKDF.
+	p, ok := kdfParams.(*pbkdf2Params)
+	if !ok {
+		return errors.New("pkcs8: PBMAC1 requires PBKDF2")
+	}
+	if p.IterationCount > maxIterations {
+		return fmt.Errorf("pkcs8: PBMAC1 iteration count %d above the maximum of %d", p.IterationCount, maxIterations)
+	}
+	// The key is as long as the HMAC output. Any other key length, which an
+	// attacker could set to exhaust memory, is rejected.
+	size := h().Size()
+	if l := p.KeyLength; l != 0 && l != size {
+		return fmt.Errorf("pkcs8: invalid PBMAC1 key length %d", l)
+	}
+	key, err := kdfParams.DeriveKey(password, size)
+	if err != nil {
+		return err
+	}
+	defer zero(key)
+	mac := hmac.New(h, key)
+	mac.Write(entries)
+	if subtle.ConstantTimeCompare(mac.Sum(nil), integrity.Value) != 1 {
+		return errKeystoreIntegrity
+	}
+	return nil
+}
+
+// VerifyKeystoreSignature checks the signature of a keystore written by
+// MarshalSigned with the public key pub. It fails for keystores without
+// one.
+func VerifyKeystoreSignature(der []byte, pub crypto.PublicKey) error {
+	entries, integrity, err := parseKeystoreIntegrity(der)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(entries)
+	alg := integrity.Algorithm.Algorithm
+	var ok bool
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		ok = alg.Equal(oidSHA256WithRSA) && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], integrity.Value) == nil
+	case *ecdsa.PublicKey:
+		ok = alg.Equal(oidECDSAWithSHA256) && ecdsa.VerifyASN1(pub, digest[:], integrity.Value)
+	case ed25519.PublicKey:
+		ok = alg.Equal(oidPublicKeyEd25519) && ed25519.Verify(pub, entries, integrity.Value)
+	default:
+		return fmt.Errorf("pkcs8: unsupported public key type %T", pub)
+	}
+	if !ok {
+		return errKeystoreIntegrity
+	}
+	return nil
+}
+
+// ParseKeystoreWithMAC is ParseKeystore for keystores written by
+// MarshalWithMAC: it fails unless the keystore has a MAC that verifies with
+// password, so that the returned entries are those that were MACed. A MAC
+// with more iterations than DefaultKeystoreMaxIterationCount can be checked
+// with VerifyKeystoreMACWithOpts before calling ParseKeystore.
+func ParseKeystoreWithMAC(der, password []byte) (*Keystore, error) {
+	if err := VerifyKeystoreMAC(der, password); err != nil {
+		return nil, err
+	}
+	return ParseKeystore(der)
+}
+
+// ParseKeystoreWithSignature is ParseKeystore for keystores written by
+// MarshalSigned: it fails unless the keystore has a signature that verifies
+// with pub, so that the returned entries are those that were signed.
+func ParseKeystoreWithSignature(der []byte, pub crypto.PublicKey) (*Keystore, error) {
+	if err := VerifyKeystoreSignature(der, pub); err != nil {
+		return nil, err
+	}
+	return ParseKeystore(der)
+}
+
+// parseKeystoreIntegrity returns the DER encoding of the entries of a
+// keystore and its integrity layer.
+func parseKeystoreIntegrity(der []byte) ([]byte, *keystoreIntegrity, error) {
+	version, err := BundleVersion(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version != KeystoreVersion {
+		return nil, nil, fmt.Errorf("pkcs8: keystore version %d has no integrity protection", version)
+	}
+	var file struct {
+		Version   int
+		Entries   asn1.RawValue
+		Integrity keystoreIntegrity `asn1:"optional,explicit,tag:0"`
+	}
+	if rest, err := asn1.Unmarshal(der, &file); err != nil || len(rest) != 0 {
+		return nil, nil, errors.New("pkcs8: invalid keystore")
+	}
+	if len(file.Integrity.Algorithm.Algorithm) == 0 {
+		return nil, nil, errors.New("pkcs8: keystore has no integrity protection")
+	}
+	return file.Entries.FullBytes, &file.Integrity, nil
+}
@@ -0,0 +1,249 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// keyType parses the keys of an algorithm crypto/x509 does not support. The
+// keys of types defined by the package are encoded by their own
+// marshalPKCS8 and marshalPKIX methods, others by marshalPrivate and
+// marshalPublic.
+type keyType struct {
+	oid asn1.ObjectIdentifier
+	// curve, if set, is the named curve the algorithm parameters must hold,
+	// for EC keys on curves crypto/x509 does not support.
+	curve asn1.ObjectIdentifier
+	// parsePrivate parses a PrivateKeyInfo whose algorithm is oid.
+	parsePrivate func(pki *privateKeyInfo) (crypto.PrivateKey, error)
+	// parsePublic parses a SubjectPublicKeyInfo whose algorithm is oid. If
+	// nil, crypto/x509 parses it.
+	parsePublic func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error)
+	// marshalPrivate and marshalPublic encode keys of types defined outside
+	// the package, returning nil for other types. Either can be nil.
+	marshalPrivate func(priv crypto.PrivateKey) (*privateKeyInfo, error)
+	marshalPublic  func(pub crypto.PublicKey) (*subjectPublicKeyInfo, error)
+}
+
+// keyTypes are the key types the package adds to those of crypto/x509.
+var keyTypes []keyType
+
+func lookupKeyType(alg pkix.AlgorithmIdentifier) *keyType {
+	for i := range keyTypes {
+		kt := &keyTypes[i]
+		if !kt.oid.Equal(alg.Algorithm) {
+			continue
+		}
+		if kt.curve != nil {
+			var curve asn1.ObjectIdentifier
+			if rest, err := asn1.Unmarshal(alg.Parameters.FullBytes, &curve); err != nil || len(rest) != 0 || !curve.Equal(kt.curve) {
+				continue
+			}
+		}
+		return kt
+	}
+	return nil
+}
+
+// pkcs8Marshaler is implemented by the private keys of the key types.
+type pkcs8Marshaler interface {
+	marshalPKCS8() (*privateKeyInfo, error)
+}
+
+// pkixMarshaler is implemented by the public keys of the key types.
+type pkixMarshaler interface {
+	marshalPKIX() (*subjectPublicKeyInfo, error)
+}
+
+// parsePKCS8PrivateKey parses an unencrypted PrivateKeyInfo of any key type
+// of the package or of crypto/x509.
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	var pki privateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &pki); err == nil && len(rest) == 0 {
+		if kt := lookupKeyType(pki.PrivateKeyAlgorithm); kt != nil {
+			return kt.parsePrivate(&pki)
+		}
+	}
+	return x509.ParsePKCS8PrivateKey(der)
+}
+
+// marshalPKCS8PrivateKey encodes a private key of any key type of the
+// package or of crypto/x509 into an unencrypted PrivateKeyInfo.
+func marshalPKCS8PrivateKey(priv interface{}) ([]byte, error) {
+	var pki *privateKeyInfo
+	var err error
+	if m, ok := priv.(pkcs8Marshaler); ok {
+		pki, err = m.marshalPKCS8()
+	}
+	for i := 0; pki == nil && err == nil && i < len(keyTypes); i++ {
+		if keyTypes[i].marshalPrivate != nil {
+			pki, err = keyTypes[i].marshalPrivate(priv)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pki != nil {
+		return asn1.Marshal(*pki)
+	}
+	return x509.MarshalPKCS8PrivateKey(priv)
+}
+
+// parsePKIXPublicKey parses a SubjectPublicKeyInfo of any key type of the
+// package or of crypto/x509.
+func parsePKIXPublicKey(der []byte) (crypto.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err == nil && len(rest) == 0 {
+		if kt := lookupKeyType(spki.Algorithm); kt != nil && kt.parsePublic != nil {
+			return kt.parsePublic(&spki)
+		}
+	}
+	return x509.ParsePKIXPublicKey(der)
+}
+
+// marshalPKIXPublicKey encodes a public key of any key type of the package
+// or of crypto/x509 into a SubjectPublicKeyInfo.
+func marshalPKIXPublicKey(pub interface{}) ([]byte, error) {
+	var spki *subjectPublicKeyInfo
+	var err error
+	if m, ok := pub.(pkixMarshaler); ok {
+		spki, err = m.marshalPKIX()
+	}
+	for i := 0; spki == nil && err == nil && i < len(keyTypes); i++ {
+		if keyTypes[i].marshalPublic != nil {
+			spki, err = keyTypes[i].marshalPublic(pub)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if spki != nil {
+		return asn1.Marshal(*spki)
+	}
+	return x509.MarshalPKIXPublicKey(pub)
+}
+
+// parseCurvePrivateKey parses the privateKey field of an RFC 8410 key, a
+// CurvePrivateKey of size bytes, whose algorithm parameters must be absent.
+func parseCurvePrivateKey(pki *privateKeyInfo, size int) ([]byte, error) {
+	name := OIDName(pki.PrivateKeyAlgorithm.Algorithm)
+	if len(pki.PrivateKeyAlgorithm.Parameters.FullBytes) != 0 {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key parameters", name)
+	}
+	var key []byte
+	if rest, err := asn1.Unmarshal(pki.PrivateKey, &key); err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", name)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key size %d", name, len(key))
+	}
+	return key, nil
+}
+
+// marshalCurvePrivateKey returns the PrivateKeyInfo of an RFC 8410 key.
+func marshalCurvePrivateKey(oid asn1.ObjectIdentifier, key []byte) (*privateKeyInfo, error) {
+	curvePrivateKey, err := asn1.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	return &privateKeyInfo{
+		PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey:          curvePrivateKey,
+	}, nil
+}
+
+// parseCurvePublicKey returns the public key of size bytes of an RFC 8410
+// SubjectPublicKeyInfo.
+func parseCurvePublicKey(spki *subjectPublicKeyInfo, size int) ([]byte, error) {
+	if len(spki.Algorithm.Parameters.FullBytes) != 0 {
+		return nil, errors.New("pkcs8: " + OIDName(spki.Algorithm.Algorithm) + " parameters must be absent")
+	}
+	if spki.PublicKey.BitLength != 8*size {
+		return nil, fmt.Errorf("pkcs8: invalid %s public key", OIDName(spki.Algorithm.Algorithm))
+	}
+	return append([]byte(nil), spki.PublicKey.Bytes...), nil
+}
+
+// marshalCurvePublicKey returns the SubjectPublicKeyInfo of an RFC 8410
+// key.
+func marshalCurvePublicKey(oid asn1.ObjectIdentifier, key []byte) *subjectPublicKeyInfo {
+	return &subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{Bytes: key, BitLength: 8 * len(key)},
+	}
+}
+
+// seedPrivateKey is the both form of the private keys of FIPS 203 and FIPS
+// 204 algorithms.
+type seedPrivateKey struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+// parseSeedPrivateKey parses the privateKey field of an ML-DSA or ML-KEM key,
+// the CHOICE of a seed [0] of seedSize bytes, an expanded key of
+// expandedSize bytes, or both. The key not stored is nil. pki.PrivateKey is
+// zeroed, as the keys returned are copies of it.
+func parseSeedPrivateKey(pki *privateKeyInfo, seedSize, expandedSize int) (seed, expanded []byte, err error) {
+	defer zero(pki.PrivateKey)
+	name := OIDName(pki.PrivateKeyAlgorithm.Algorithm)
+	if len(pki.PrivateKeyAlgorithm.Parameters.FullBytes) != 0 {
+		return nil, nil, fmt.Errorf("pkcs8: invalid %s private key parameters", name)
+	}
+	errInvalid := fmt.Errorf("pkcs8: invalid %s private key", name)
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(pki.PrivateKey, &raw); err != nil || len(rest) != 0 {
+		return nil, nil, errInvalid
+	}
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		seed = append([]byte(nil), raw.Bytes...)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString && !raw.IsCompound:
+		expanded = append([]byte(nil), raw.Bytes...)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		// encoding/asn1 copies the OCTET STRINGs out of pki.PrivateKey.
+		var both seedPrivateKey
+		if rest, err := asn1.Unmarshal(pki.PrivateKey, &both); err != nil || len(rest) != 0 {
+			zero(both.Seed)
+			zero(both.ExpandedKey)
+			return nil, nil, errInvalid
+		}
+		seed, expanded = both.Seed, both.ExpandedKey
+	default:
+		return nil, nil, errInvalid
+	}
+	if (seed != nil && len(seed) != seedSize) || (expanded != nil && len(expanded) != expandedSize) {
+		zero(seed)
+		zero(expanded)
+		return nil, nil, errInvalid
+	}
+	return seed, expanded, nil
+}
+
+// marshalSeedPrivateKey returns the PrivateKeyInfo of an ML-DSA or ML-KEM
+// key, in the seed, expanded key or both form depending on which is set.
+func marshalSeedPrivateKey(oid asn1.ObjectIdentifier, seed, expanded []byte) (*privateKeyInfo, error) {
+	var key []byte
+	var err error
+	switch {
+	case seed != nil && expanded != nil:
+		key, err = asn1.Marshal(seedPrivateKey{seed, expanded})
+	case seed != nil:
+		key, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: seed})
+	case expanded != nil:
+		key, err = asn1.Marshal(expanded)
+	default:
+		return nil, fmt.Errorf("pkcs8: empty %s private key", OIDName(oid))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &privateKeyInfo{
+		PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey:          key,
+	}, nil
+}
@@ -0,0 +1,100 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Keys of the data of a kubernetes.io/tls Secret.
+const (
+	TLSSecretCertKey = "tls.crt"
+	TLSSecretKeyKey  = "tls.key"
+)
+
+// TLSSecretData returns the data of a kubernetes.io/tls Secret, with
+// base64-encoded values as in a manifest, for the PEM-encoded private key
+// keyPEM decrypted with password and the PEM-encoded certificate chain
+// certPEM. Password can be nil.
+//
+// Kubernetes requires the key to be unencrypted, so it is stored as an
+// unencrypted PKCS#8 "PRIVATE KEY" block. An error is returned if the first
+// certificate of the chain does not match the key.
+func TLSSecretData(keyPEM, password, certPEM []byte) (map[string]string, error) {
+	priv, _, err := ParsePrivateKeyPEM(keyPEM, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCertificateKey(certPEM, priv); err != nil {
+		return nil, err
+	}
+	plainPEM, err := MarshalPrivateKeyPEM(priv, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		TLSSecretCertKey: base64.StdEncoding.EncodeToString(certPEM),
+		TLSSecretKeyKey:  base64.StdEncoding.EncodeToString(plainPEM),
+	}, nil
+}
+
+// ParseTLSSecretData parses the data of a kubernetes.io/tls Secret, with
+// base64-encoded values as in a manifest, and returns the private key as
+// PEM-encoded PKCS#8 encrypted with password and opts, along with the
+// PEM-encoded certificate chain. Password can be nil, in which case the key
+// is returned unencrypted.
+func ParseTLSSecretData(data map[string]string, password []byte, opts *Opts) (keyPEM, certPEM []byte, err error) {
+	values := make(map[string][]byte, 2)
+	for _, name := range []string{TLSSecretKeyKey, TLSSecretCertKey} {
+		encoded, ok := data[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("pkcs8: Secret data has no %s", name)
+		}
+		if values[name], err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, nil, fmt.Errorf("pkcs8: invalid base64 in Secret %s: %w", name, err)
+		}
+	}
+	priv, _, err := ParsePrivateKeyPEM(values[TLSSecretKeyKey], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = values[TLSSecretCertKey]
+	if err := checkCertificateKey(certPEM, priv); err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err = MarshalPrivateKeyPEM(priv, password, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPEM, certPEM, nil
+}
+
+// checkCertificateKey checks that the first certificate of the PEM-encoded
+// chain certPEM is for priv.
+func checkCertificateKey(certPEM []byte, priv crypto.PrivateKey) error {
+	var block *pem.Block
+	for rest := certPEM; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return errors.New("pkcs8: no certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			break
+		}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	pub, ok := Public(priv).(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return errors.New("pkcs8: certificate does not match the private key")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package pkcs8
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DecryptLayer decrypts an EncryptedPrivateKeyInfo or PKCS#7 EncryptedData
+// with password and returns its content without parsing it, to remove the
+// outer layer of a key reported by a LayeredEncryptionError. As the content
+// is not checked, a wrong password is only detected by the padding of block
+// ciphers, and then not always. The inner key may use another password.
+func DecryptLayer(der []byte, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("pkcs8: password required to decrypt a layer")
+	}
+	content, _, err := decryptPrivateKeyInfo(der, password, nil)
+	return content, err
+}
+
+// checkPEMEncryption returns an error if block is encrypted at the PEM
+// layer, which the parse functions do not remove.
+func checkPEMEncryption(block *pem.Block) error {
+	dekInfo, ok := block.Headers["DEK-Info"]
+	if !ok {
+		return nil
+	}
+	outer := "PEM encryption (DEK-Info " + strings.SplitN(dekInfo, ",", 2)[0] + ")"
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return &LayeredEncryptionError{Outer: outer, Inner: "EncryptedPrivateKeyInfo", Peel: "DecryptPEMBlock"}
+	}
+	return fmt.Errorf("pkcs8: %s block is protected by %s; decrypt it with DecryptPEMBlock first", block.Type, outer)
+}
+
+// errIncorrectPassword returns the error for the content decrypted from der
+// that is not a PrivateKeyInfo: a LayeredEncryptionError if it is another
+// encrypted key, as happens when an encrypted key is encrypted again.
+func errIncorrectPassword(der, decrypted []byte) error {
+	if inner := encryptedKeyContainer(decrypted); inner != "" {
+		return &LayeredEncryptionError{Outer: encryptedKeyContainer(der), Inner: inner, Peel: "DecryptLayer"}
+	}
+	return errors.New("pkcs8: incorrect password")
+}
+
+// encryptedKeyContainer returns the name of the encrypted key container
+// der consists of, or "" if it is none.
+func encryptedKeyContainer(der []byte) string {
+	if isPKCS7EncryptedData(der) {
+		return "PKCS7EncryptedData"
+	}
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) != 0 {
+		return ""
+	}
+	if alg := info.EncryptionAlgorithm.Algorithm; alg.Equal(oidPBES2) || isPKCS12PBE(alg) {
+		return "EncryptedPrivateKeyInfo"
+	}
+	return ""
+}
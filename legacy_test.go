@@ -0,0 +1,555 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestLegacyCiphers(t *testing.T) {
+	if info, _ := pkcs8.LookupCipher(pkcs8.DESCBC.OID()); !info.Legacy || info.BlockSize != 8 {
+		t.Errorf("unexpected DES-CBC description: %+v", info)
+	}
+}
+
+func TestRC2PBKDF2KeyLength(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	// RC2 accepts keys of 1 to 128 bytes.
+	opts := pkcs8.LegacyDefaults()
+	opts.Cipher = pkcs8.RC2CBC
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("password"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parseOpts := &pkcs8.ParseOpts{AllowLegacy: true}
+	for _, keyLength := range []int{1 << 40, 129} {
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(setPBKDF2KeyLength(t, der, keyLength), []byte("password"), parseOpts); err == nil {
+			t.Errorf("RC2 keyLength %d: expected an error", keyLength)
+		}
+	}
+}
+
+func TestParseOptsAllowLegacy(t *testing.T) {
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	der, err := pkcs8.MarshalPrivateKey(ecPrivateKey, []byte("password"), &pkcs8.Opts{
+		Cipher: pkcs8.DESCBC,
+		KDFOpts: pkcs8.PBKDF2Opts{
+			SaltSize: 8, IterationCount: 16, HMACHash: crypto.SHA1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(der, []byte("password")); err == nil {
+		t.Error("expected legacy cipher to be refused by default")
+	}
+	key, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("password"), &pkcs8.ParseOpts{AllowLegacy: true})
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithOpts returned: %s", err)
+	}
+	if ecPrivateKey.D.Cmp(key.(*ecdsa.PrivateKey).D) != 0 {
+		t.Fatal("Decoded key does not match original key")
+	}
+}
+
+func TestRC2CBC(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	parseOpts := &pkcs8.ParseOpts{AllowLegacy: true}
+	files, err := filepath.Glob(filepath.Join("testdata", "openssl", "legacy-pbes2-rc2-*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no RC2 fixtures found")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), parseOpts)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyWithOpts returned: %s", filepath.Base(file), err)
+		}
+		if !want.(*ecdsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", filepath.Base(file))
+		}
+	}
+
+	for _, size := range []struct{ keySize, effectiveBits int }{{16, 128}, {8, 64}, {5, 40}, {32, 256}} {
+		cipher, err := pkcs8.NewRC2CBC(size.keySize, size.effectiveBits)
+		if err != nil {
+			t.Fatalf("NewRC2CBC returned: %s", err)
+		}
+		opts := pkcs8.LegacyDefaults()
+		opts.Cipher = cipher
+		der, err := pkcs8.MarshalPrivateKey(want, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("MarshalPrivateKey returned: %s", err)
+		}
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("password"), parseOpts)
+		if err != nil {
+			t.Fatalf("%d/%d: ParsePrivateKeyWithOpts returned: %s", size.keySize, size.effectiveBits, err)
+		}
+		if !want.(*ecdsa.PrivateKey).Equal(key) {
+			t.Errorf("%d/%d: Decoded key does not match original key", size.keySize, size.effectiveBits)
+		}
+	}
+	if _, err := pkcs8.NewRC2CBC(16, 100); err == nil {
+		t.Error("expected an error for an unsupported effective key length")
+	}
+}
+
+func TestGOST28147(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "gost", "gost28147-cfb-*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("found %d GOST 28147-89 CFB fixtures", len(files))
+	}
+	opts := &pkcs8.ParseOpts{AllowLegacy: true}
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		if _, _, err := pkcs8.ParsePrivateKey(block.Bytes, []byte("password")); err == nil {
+			t.Errorf("%s: expected the cipher to be refused by default", name)
+		}
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyWithOpts returned: %s", name, err)
+		}
+		if !want.(*rsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", name)
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("wrong"), opts); err == nil {
+			t.Errorf("%s: expected an error for a wrong password", name)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join("testdata", "gost", "gost28147-cbc-a-rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts); err == nil {
+		t.Error("expected the CBC key to fail in the default CFB mode")
+	}
+	gost, _ := pkcs8.LookupOID("gost89")
+	registry := pkcs8.DefaultRegistry()
+	registry.RegisterCipher(gost, func() pkcs8.Cipher {
+		return pkcs8.GOST28147CBC
+	})
+	cbcOpts := &pkcs8.ParseOpts{AllowLegacy: true, Registry: registry}
+	key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), cbcOpts)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithOpts returned: %s", err)
+	}
+	if !want.(*rsa.PrivateKey).Equal(key) {
+		t.Error("Decoded CBC key does not match original key")
+	}
+
+	if _, err := pkcs8.GOST28147CFB.Encrypt(make([]byte, 32), make([]byte, 8), []byte("data")); err == nil {
+		t.Error("expected GOST 28147-89 encryption to be refused")
+	}
+	info, _ := pkcs8.LookupCipher(gost)
+	if !info.Legacy || info.KeySize != 32 || info.IVSize != 8 {
+		t.Errorf("unexpected GOST 28147-89 description: %+v", info)
+	}
+}
+
+func TestPVK(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	for _, mode := range []string{"strong", "weak", "none"} {
+		pvk, err := os.ReadFile(filepath.Join("testdata", "openssl", "pvk-"+mode+"-rsa.pvk"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := pkcs8.ParsePVK(pvk, []byte("password"))
+		if err != nil {
+			t.Fatalf("%s: ParsePVK returned: %s", mode, err)
+		}
+		if !want.(*rsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", mode)
+		}
+		if mode == "none" {
+			continue
+		}
+		if _, err := pkcs8.ParsePVK(pvk, []byte("wrong")); err == nil {
+			t.Errorf("%s: expected an error for a wrong password", mode)
+		}
+		if _, err := pkcs8.ParsePVK(pvk, nil); err == nil {
+			t.Errorf("%s: expected an error without a password", mode)
+		}
+	}
+
+	for _, opts := range []*pkcs8.PVKOpts{nil, {KeySpec: pkcs8.PVKSignature, Weak: true}} {
+		pvk, err := pkcs8.MarshalPVK(want, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("MarshalPVK returned: %s", err)
+		}
+		der, err := pkcs8.ConvertPVKToPKCS8(pvk, []byte("password"), []byte("pkcs8"), pkcs8.LegacyDefaults())
+		if err != nil {
+			t.Fatalf("ConvertPVKToPKCS8 returned: %s", err)
+		}
+		key, _, err := pkcs8.ParsePrivateKey(der, []byte("pkcs8"))
+		if err != nil {
+			t.Fatalf("ParsePrivateKey returned: %s", err)
+		}
+		if !want.(*rsa.PrivateKey).Equal(key) {
+			t.Error("Converted key does not match original key")
+		}
+		pvk, err = pkcs8.ConvertPKCS8ToPVK(der, []byte("pkcs8"), nil, opts)
+		if err != nil {
+			t.Fatalf("ConvertPKCS8ToPVK returned: %s", err)
+		}
+		key, err = pkcs8.ParsePVK(pvk, nil)
+		if err != nil {
+			t.Fatalf("ParsePVK returned: %s", err)
+		}
+		if !want.(*rsa.PrivateKey).Equal(key) {
+			t.Error("Decoded unencrypted key does not match original key")
+		}
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkcs8.MarshalPVK(ecKey, nil, nil); err == nil {
+		t.Error("expected an error for an EC key")
+	}
+}
+
+func TestBindParametersPKCS12PBE(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "legacy-pbe-sha1-3des-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, _ := pem.Decode(data)
+	strict := &pkcs8.ParseOpts{RequireBoundParameters: true, AllowLegacy: true}
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(legacy.Bytes, []byte("password"), strict); err == nil || !strings.Contains(err.Error(), "cannot bind") {
+		t.Errorf("ParsePrivateKeyWithOpts of a PKCS#12 PBE key returned %v", err)
+	}
+}
+
+func TestParsePKCS7EncryptedDataPKCS12PBE(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	for _, name := range []string{"openssl-pbe-sha1-3des-ec.der", "openssl-pbe-sha1-rc2-40-ec.der"} {
+		der, err := os.ReadFile(filepath.Join("testdata", "pkcs7", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := pkcs8.ParsePrivateKey(der, []byte("password")); err == nil {
+			t.Errorf("%s: expected PKCS#12 PBE to be refused by default", name)
+		}
+		opts := &pkcs8.ParseOpts{AllowLegacy: true}
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyWithOpts returned: %s", name, err)
+		}
+		if !want.(*ecdsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", name)
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("wrong"), opts); err == nil {
+			t.Errorf("%s: expected wrong password to fail", name)
+		}
+	}
+}
+
+func TestParsePKCS12PBELegacy(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "openssl", "legacy-pbe-*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		if _, _, err := pkcs8.ParsePrivateKey(block.Bytes, []byte("password")); err == nil {
+			t.Errorf("%s: expected legacy PBE to be refused by default", name)
+		}
+		opts := &pkcs8.ParseOpts{AllowLegacy: true}
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyWithOpts returned: %s", name, err)
+		}
+		if !want.(*ecdsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", name)
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("wrong"), opts); err == nil {
+			t.Errorf("%s: expected wrong password to fail", name)
+		}
+		// The PKCS#12 KDF is not PBKDF2.
+		opts.AllowedKDFs = []asn1.ObjectIdentifier{pkcs8.PBKDF2Opts{}.OID()}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts); err == nil ||
+			!strings.Contains(err.Error(), "KDF not allowed") {
+			t.Errorf("%s: ParsePrivateKeyWithOpts with PBKDF2 only returned %v", name, err)
+		}
+	}
+}
+
+func TestPKCS12PBEAllowLists(t *testing.T) {
+	for _, test := range []struct {
+		file string
+		oid  asn1.ObjectIdentifier
+	}{
+		{"openssl/legacy-pbe-sha1-rc2-40-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}},
+		{"openssl/legacy-pbe-sha1-rc2-128-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}},
+		{"openssl/legacy-pbe-sha1-rc4-40-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 2}},
+		{"openssl/legacy-pbe-sha1-rc4-128-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 1}},
+		{"pkcs7/openssl-pbe-sha1-rc2-40-ec.der", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}},
+	} {
+		der, err := os.ReadFile(filepath.Join("testdata", filepath.FromSlash(test.file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block, _ := pem.Decode(der); block != nil {
+			der = block.Bytes
+		}
+		pbkdf2Only := []asn1.ObjectIdentifier{pkcs8.PBKDF2Opts{}.OID()}
+		aesOnly := []asn1.ObjectIdentifier{pkcs8.AES256CBC.OID()}
+		for _, opts := range []*pkcs8.ParseOpts{
+			{AllowLegacy: true, AllowedKDFs: pbkdf2Only},
+			{AllowLegacy: true, AllowedCiphers: aesOnly},
+			{AllowLegacy: true, AllowedKDFs: []asn1.ObjectIdentifier{test.oid}, AllowedCiphers: aesOnly},
+		} {
+			if _, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("password"), opts); err == nil ||
+				!strings.Contains(err.Error(), "not allowed") {
+				t.Errorf("%s: ParsePrivateKeyWithOpts(%+v) returned %v", test.file, opts, err)
+			}
+		}
+		opts := &pkcs8.ParseOpts{
+			AllowLegacy:    true,
+			AllowedKDFs:    []asn1.ObjectIdentifier{test.oid},
+			AllowedCiphers: []asn1.ObjectIdentifier{test.oid},
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(der, []byte("password"), opts); err != nil {
+			t.Errorf("%s: ParsePrivateKeyWithOpts with the scheme allowed returned: %s", test.file, err)
+		}
+	}
+}
+
+func TestAESFeedbackModes(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "openssl", "legacy-pbes2-aes-*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("found %d AES-CFB and AES-OFB fixtures", len(files))
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		if _, _, err := pkcs8.ParsePrivateKey(block.Bytes, []byte("password")); err == nil {
+			t.Errorf("%s: expected the cipher to be refused by default", name)
+		}
+		opts := &pkcs8.ParseOpts{AllowLegacy: true}
+		key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts)
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKeyWithOpts returned: %s", name, err)
+		}
+		if !want.(*ecdsa.PrivateKey).Equal(key) {
+			t.Errorf("%s: Decoded key does not match original key", name)
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("wrong"), opts); err == nil {
+			t.Errorf("%s: expected an error for a wrong password", name)
+		}
+	}
+
+	cfb, ok := pkcs8.LookupOID("aes-256-cfb")
+	if !ok {
+		t.Fatal("aes-256-cfb not found")
+	}
+	info, _ := pkcs8.LookupCipher(cfb)
+	if !info.Legacy || info.KeySize != 32 {
+		t.Errorf("unexpected AES-256-CFB description: %+v", info)
+	}
+}
+
+func TestHMACGOSTR3411PRF(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain-rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _, err := pkcs8.ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned: %s", err)
+	}
+	data, err = os.ReadFile(filepath.Join("testdata", "gost", "hmacgost94-cfb-a-rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if _, _, err := pkcs8.ParsePrivateKey(block.Bytes, []byte("password")); err == nil {
+		t.Error("expected id-HMACGostR3411-94 to be refused by default")
+	}
+	opts := &pkcs8.ParseOpts{AllowLegacy: true}
+	key, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithOpts returned: %s", err)
+	}
+	if !want.(*rsa.PrivateKey).Equal(key) {
+		t.Error("Decoded key does not match original key")
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("wrong"), opts); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+	info, err := pkcs8.Inspect(block.Bytes, nil)
+	if err != nil {
+		t.Fatalf("Inspect returned: %s", err)
+	}
+	if info.PRF != "id-HMACGostR3411-94" || info.Cipher != "gost89" {
+		t.Errorf("unexpected encryption %+v", info)
+	}
+}
+
+func TestPKCS12PBERegistry(t *testing.T) {
+	aesOnly := pkcs8.NewRegistry()
+	aesOnly.RegisterCipher(pkcs8.AES256CBC.OID(), func() pkcs8.Cipher { return pkcs8.AES256CBC })
+	for _, test := range []struct {
+		name string
+		oid  asn1.ObjectIdentifier
+	}{
+		{"legacy-pbe-sha1-rc4-128-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 1}},
+		{"legacy-pbe-sha1-3des-ec.pem", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}},
+	} {
+		name := test.name
+		data, err := os.ReadFile(filepath.Join("testdata", "openssl", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		noPBE := pkcs8.DefaultRegistry()
+		noPBE.UnregisterPBE(test.oid)
+		for _, registry := range []*pkcs8.Registry{pkcs8.NewRegistry(), aesOnly, noPBE} {
+			for _, allowLegacy := range []bool{false, true} {
+				opts := &pkcs8.ParseOpts{Registry: registry, AllowLegacy: allowLegacy}
+				if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts); err == nil {
+					t.Errorf("%s: a registry without the scheme decrypted the key (AllowLegacy: %t)", name, allowLegacy)
+				}
+			}
+		}
+		cloned := &pkcs8.ParseOpts{Registry: pkcs8.DefaultRegistry()}
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), cloned); err == nil {
+			t.Errorf("%s: a cloned registry decrypted the key without AllowLegacy", name)
+		}
+		cloned.AllowLegacy = true
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), cloned); err != nil {
+			t.Errorf("%s: ParsePrivateKeyWithOpts with a cloned registry returned: %s", name, err)
+		}
+	}
+}
+
+func TestRewrapLegacy(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "legacy-pbe-sha1-rc4-128-ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if _, err := pkcs8.ReEncrypt(block.Bytes, []byte("password"), []byte("new"), nil); err == nil {
+		t.Error("ReEncrypt decrypted a legacy key without AllowLegacy")
+	}
+	parseOpts := &pkcs8.ParseOpts{AllowLegacy: true}
+	der, err := pkcs8.ReEncryptWithOpts(block.Bytes, []byte("password"), []byte("new"), parseOpts, pkcs8.LegacyDefaults())
+	if err != nil {
+		t.Fatalf("ReEncryptWithOpts returned: %s", err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(der, []byte("new")); err != nil {
+		t.Errorf("ParsePrivateKey of the re-encrypted key returned: %s", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fsys := pkcs8.WritableDirFS(dir)
+	opts := pkcs8.RewrapOpts{NewPassword: []byte("new"), Opts: pkcs8.LegacyDefaults()}
+	if report, err := pkcs8.Rewrap(fsys, nil, []byte("password"), opts); err != nil || !report.Failed() {
+		t.Errorf("Rewrap without AllowLegacy returned %+v, %v", report, err)
+	}
+	opts.ParseOpts = parseOpts
+	if report, err := pkcs8.Rewrap(fsys, nil, []byte("password"), opts); err != nil || report.Failed() {
+		t.Fatalf("Rewrap with AllowLegacy returned %+v, %v", report, err)
+	}
+	rewrapped, err := os.ReadFile(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyPEM(rewrapped, []byte("new")); err != nil {
+		t.Errorf("ParsePrivateKeyPEM of the rewrapped key returned: %s", err)
+	}
+}
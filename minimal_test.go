@@ -0,0 +1,54 @@
+//go:build pkcs8_nolegacy || pkcs8_noscrypt || pkcs8_nointegrations
+// +build pkcs8_nolegacy pkcs8_noscrypt pkcs8_nointegrations
+
+package pkcs8_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+// These check that minimal builds still handle PBKDF2 and AES end to end;
+// tests needing the excluded algorithms are in files with the matching tags.
+
+func TestMinimalRoundTrip(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("password"), pkcs8.LegacyDefaults())
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	parsed, _, err := pkcs8.ParsePrivateKey(der, []byte("password"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey returned: %s", err)
+	}
+	if !reflect.DeepEqual(parsed, key) {
+		t.Fatal("parsed key does not match")
+	}
+}
+
+func TestMinimalOpenSSL(t *testing.T) {
+	for _, name := range []string{"openssl-3.0-default-ec.pem", "openssl-3.0-v2-des3-sha1-rsa.pem"} {
+		data, err := os.ReadFile("testdata/openssl/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := pkcs8.ParsePrivateKeyPEM(data, []byte("password")); err != nil {
+			t.Errorf("%s: ParsePrivateKeyPEM returned: %s", name, err)
+		}
+	}
+}
+
+func TestMinimalVerifyInterop(t *testing.T) {
+	if err := pkcs8.VerifyInterop(); err != nil {
+		t.Fatalf("VerifyInterop returned: %s", err)
+	}
+}
@@ -0,0 +1,161 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var (
+	oidMLDSA44 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17}
+	oidMLDSA65 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18}
+	oidMLDSA87 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19}
+)
+
+// MLDSASeedSize is the size of the seed ML-DSA keys are generated from.
+const MLDSASeedSize = 32
+
+// MLDSAParameters is an ML-DSA parameter set of FIPS 204.
+type MLDSAParameters int
+
+// The ML-DSA parameter sets, of increasing security category.
+const (
+	MLDSA44 MLDSAParameters = iota + 1
+	MLDSA65
+	MLDSA87
+)
+
+var mldsaParameters = []struct {
+	params       MLDSAParameters
+	oid          asn1.ObjectIdentifier
+	expandedSize int
+	publicSize   int
+}{
+	{MLDSA44, oidMLDSA44, 2560, 1312},
+	{MLDSA65, oidMLDSA65, 4032, 1952},
+	{MLDSA87, oidMLDSA87, 4896, 2592},
+}
+
+func (p MLDSAParameters) String() string {
+	for _, ps := range mldsaParameters {
+		if ps.params == p {
+			return OIDName(ps.oid)
+		}
+	}
+	return fmt.Sprintf("MLDSAParameters(%d)", int(p))
+}
+
+// ExpandedKeySize returns the size of the expanded private keys of p.
+func (p MLDSAParameters) ExpandedKeySize() int {
+	for _, ps := range mldsaParameters {
+		if ps.params == p {
+			return ps.expandedSize
+		}
+	}
+	return 0
+}
+
+// PublicKeySize returns the size of the public keys of p.
+func (p MLDSAParameters) PublicKeySize() int {
+	for _, ps := range mldsaParameters {
+		if ps.params == p {
+			return ps.publicSize
+		}
+	}
+	return 0
+}
+
+func (p MLDSAParameters) oid() asn1.ObjectIdentifier {
+	for _, ps := range mldsaParameters {
+		if ps.params == p {
+			return ps.oid
+		}
+	}
+	return nil
+}
+
+// MLDSAPrivateKey is an ML-DSA private key, as written by OpenSSL 3.5 or
+// BoringSSL. It is returned by the parse functions for id-ml-dsa-44,
+// id-ml-dsa-65 and id-ml-dsa-87 keys and accepted by the marshal functions,
+// which write the forms of the key that are set.
+//
+// The package does not implement ML-DSA: the key only carries its raw
+// material, to be used with an ML-DSA implementation. It has no Public
+// method, as the public key cannot be derived without one.
+type MLDSAPrivateKey struct {
+	Parameters MLDSAParameters
+	// Seed is the seed ξ the key is generated from, nil if only the
+	// expanded key is stored.
+	Seed []byte
+	// ExpandedKey is the expanded private key of FIPS 204, nil if only the
+	// seed is stored.
+	ExpandedKey []byte
+}
+
+// MLDSAPublicKey is an ML-DSA public key. It is returned by ParsePublicKey
+// and accepted by MarshalPublicKey.
+type MLDSAPublicKey struct {
+	Parameters MLDSAParameters
+	Key        []byte
+}
+
+func init() {
+	for _, ps := range mldsaParameters {
+		ps := ps
+		keyTypes = append(keyTypes, keyType{
+			oid: ps.oid,
+			parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+				seed, expanded, err := parseSeedPrivateKey(pki, MLDSASeedSize, ps.expandedSize)
+				if err != nil {
+					return nil, err
+				}
+				return &MLDSAPrivateKey{Parameters: ps.params, Seed: seed, ExpandedKey: expanded}, nil
+			},
+			parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+				key, err := parseCurvePublicKey(spki, ps.publicSize)
+				if err != nil {
+					return nil, err
+				}
+				return &MLDSAPublicKey{Parameters: ps.params, Key: key}, nil
+			},
+		})
+	}
+}
+
+func (priv *MLDSAPrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	oid := priv.Parameters.oid()
+	if oid == nil {
+		return nil, errors.New("pkcs8: unsupported ML-DSA parameters")
+	}
+	if (priv.Seed != nil && len(priv.Seed) != MLDSASeedSize) ||
+		(priv.ExpandedKey != nil && len(priv.ExpandedKey) != priv.Parameters.ExpandedKeySize()) {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", priv.Parameters)
+	}
+	return marshalSeedPrivateKey(oid, priv.Seed, priv.ExpandedKey)
+}
+
+// Equal reports whether priv and x are the same key, stored in the same
+// forms.
+func (priv *MLDSAPrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*MLDSAPrivateKey)
+	return ok && priv.Parameters == other.Parameters &&
+		subtle.ConstantTimeCompare(priv.Seed, other.Seed) == 1 &&
+		subtle.ConstantTimeCompare(priv.ExpandedKey, other.ExpandedKey) == 1
+}
+
+func (pub *MLDSAPublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	oid := pub.Parameters.oid()
+	if oid == nil || len(pub.Key) != pub.Parameters.PublicKeySize() {
+		return nil, errors.New("pkcs8: invalid ML-DSA public key")
+	}
+	return marshalCurvePublicKey(oid, pub.Key), nil
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub *MLDSAPublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*MLDSAPublicKey)
+	return ok && pub.Parameters == other.Parameters && bytes.Equal(pub.Key, other.Key)
+}
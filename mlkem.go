@@ -0,0 +1,162 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var (
+	oidMLKEM512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}
+	oidMLKEM768  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+	oidMLKEM1024 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}
+)
+
+// MLKEMSeedSize is the size of the seed ML-KEM keys are generated from, d
+// followed by z.
+const MLKEMSeedSize = 64
+
+// MLKEMParameters is an ML-KEM parameter set of FIPS 203.
+type MLKEMParameters int
+
+// The ML-KEM parameter sets, of increasing security category.
+const (
+	MLKEM512 MLKEMParameters = iota + 1
+	MLKEM768
+	MLKEM1024
+)
+
+var mlkemParameters = []struct {
+	params       MLKEMParameters
+	oid          asn1.ObjectIdentifier
+	expandedSize int
+	publicSize   int
+}{
+	{MLKEM512, oidMLKEM512, 1632, 800},
+	{MLKEM768, oidMLKEM768, 2400, 1184},
+	{MLKEM1024, oidMLKEM1024, 3168, 1568},
+}
+
+func (p MLKEMParameters) String() string {
+	for _, ps := range mlkemParameters {
+		if ps.params == p {
+			return OIDName(ps.oid)
+		}
+	}
+	return fmt.Sprintf("MLKEMParameters(%d)", int(p))
+}
+
+// ExpandedKeySize returns the size of the expanded decapsulation keys of p.
+func (p MLKEMParameters) ExpandedKeySize() int {
+	for _, ps := range mlkemParameters {
+		if ps.params == p {
+			return ps.expandedSize
+		}
+	}
+	return 0
+}
+
+// PublicKeySize returns the size of the encapsulation keys of p.
+func (p MLKEMParameters) PublicKeySize() int {
+	for _, ps := range mlkemParameters {
+		if ps.params == p {
+			return ps.publicSize
+		}
+	}
+	return 0
+}
+
+func (p MLKEMParameters) oid() asn1.ObjectIdentifier {
+	for _, ps := range mlkemParameters {
+		if ps.params == p {
+			return ps.oid
+		}
+	}
+	return nil
+}
+
+// MLKEMPrivateKey is an ML-KEM decapsulation key, as used by hybrid TLS key
+// exchanges. It is returned by the parse functions for id-alg-ml-kem-512,
+// id-alg-ml-kem-768 and id-alg-ml-kem-1024 keys and accepted by the marshal
+// functions, which write the forms of the key that are set.
+//
+// The package does not implement ML-KEM: the key only carries its raw
+// material, to be used with an ML-KEM implementation such as crypto/mlkem,
+// whose NewDecapsulationKey768 takes Seed. It has no Public method.
+type MLKEMPrivateKey struct {
+	Parameters MLKEMParameters
+	// Seed is the seed d || z the key is generated from, nil if only the
+	// expanded key is stored.
+	Seed []byte
+	// ExpandedKey is the expanded decapsulation key of FIPS 203, nil if
+	// only the seed is stored.
+	ExpandedKey []byte
+}
+
+// MLKEMPublicKey is an ML-KEM encapsulation key. It is returned by
+// ParsePublicKey and accepted by MarshalPublicKey.
+type MLKEMPublicKey struct {
+	Parameters MLKEMParameters
+	Key        []byte
+}
+
+func init() {
+	for _, ps := range mlkemParameters {
+		ps := ps
+		keyTypes = append(keyTypes, keyType{
+			oid: ps.oid,
+			parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+				seed, expanded, err := parseSeedPrivateKey(pki, MLKEMSeedSize, ps.expandedSize)
+				if err != nil {
+					return nil, err
+				}
+				return &MLKEMPrivateKey{Parameters: ps.params, Seed: seed, ExpandedKey: expanded}, nil
+			},
+			parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+				key, err := parseCurvePublicKey(spki, ps.publicSize)
+				if err != nil {
+					return nil, err
+				}
+				return &MLKEMPublicKey{Parameters: ps.params, Key: key}, nil
+			},
+		})
+	}
+}
+
+func (priv *MLKEMPrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	oid := priv.Parameters.oid()
+	if oid == nil {
+		return nil, errors.New("pkcs8: unsupported ML-KEM parameters")
+	}
+	if (priv.Seed != nil && len(priv.Seed) != MLKEMSeedSize) ||
+		(priv.ExpandedKey != nil && len(priv.ExpandedKey) != priv.Parameters.ExpandedKeySize()) {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", priv.Parameters)
+	}
+	return marshalSeedPrivateKey(oid, priv.Seed, priv.ExpandedKey)
+}
+
+// Equal reports whether priv and x are the same key, stored in the same
+// forms.
+func (priv *MLKEMPrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*MLKEMPrivateKey)
+	return ok && priv.Parameters == other.Parameters &&
+		subtle.ConstantTimeCompare(priv.Seed, other.Seed) == 1 &&
+		subtle.ConstantTimeCompare(priv.ExpandedKey, other.ExpandedKey) == 1
+}
+
+func (pub *MLKEMPublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	oid := pub.Parameters.oid()
+	if oid == nil || len(pub.Key) != pub.Parameters.PublicKeySize() {
+		return nil, errors.New("pkcs8: invalid ML-KEM public key")
+	}
+	return marshalCurvePublicKey(oid, pub.Key), nil
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub *MLKEMPublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*MLKEMPublicKey)
+	return ok && pub.Parameters == other.Parameters && bytes.Equal(pub.Key, other.Key)
+}
@@ -0,0 +1,32 @@
+//go:build pkcs8_nolegacy
+// +build pkcs8_nolegacy
+
+package pkcs8_test
+
+import (
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestNoLegacy(t *testing.T) {
+	opts := &pkcs8.ParseOpts{AllowLegacy: true}
+	for _, name := range []string{
+		"legacy-pbe-sha1-rc4-128-ec.pem",
+		"legacy-pbe-sha1-rc2-40-ec.pem",
+		"legacy-pbe-sha1-3des-ec.pem",
+		"legacy-pbes2-rc2-cbc-ec.pem",
+		"legacy-pbes2-aes-128-cfb-ec.pem",
+	} {
+		data, err := os.ReadFile("testdata/openssl/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), opts); err == nil {
+			t.Errorf("%s: legacy key parsed", name)
+		}
+	}
+}
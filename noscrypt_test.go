@@ -0,0 +1,35 @@
+//go:build pkcs8_noscrypt
+// +build pkcs8_noscrypt
+
+package pkcs8_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+// scryptSupported reports whether the tests run against a build with scrypt.
+const scryptSupported = false
+
+func TestNoScrypt(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssl/openssl-3.0-scrypt-ec.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyPEM(data, []byte("password")); err == nil {
+		t.Error("scrypt key parsed")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkcs8.MarshalPrivateKey(key, []byte("password"), pkcs8.OpenSSLScryptOpts()); err == nil {
+		t.Error("key encrypted with scrypt")
+	}
+}
@@ -0,0 +1,196 @@
+package pkcs8
+
+import (
+	"encoding/asn1"
+	"strconv"
+	"strings"
+)
+
+// Key and curve OIDs that are only needed to name algorithms.
+var (
+	oidPublicKeyRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+	oidPublicKeyX25519  = asn1.ObjectIdentifier{1, 3, 101, 110}
+	oidMLKEM768X25519   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 58}
+
+	oidNamedCurveP224 = asn1.ObjectIdentifier{1, 3, 132, 0, 33}
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidNamedCurveP521 = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+)
+
+// Legacy algorithm OIDs. They are declared here rather than next to their
+// implementations so that they are still named when the pkcs8_nolegacy build
+// tag excludes those.
+var (
+	oidDESCBC = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 7}
+	oidRC2CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 2}
+
+	oidAES128OFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 3}
+	oidAES128CFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 4}
+	oidAES192OFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 23}
+	oidAES192CFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 24}
+	oidAES256OFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 43}
+	oidAES256CFB = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 44}
+
+	oidGOST28147             = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 21}
+	oidGOST28147TestParamSet = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 0}
+	oidGOST28147ParamSetA    = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 1}
+	oidGOST28147ParamSetB    = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 2}
+	oidGOST28147ParamSetC    = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 3}
+	oidGOST28147ParamSetD    = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 4}
+	oidGOST28147ParamSetZ    = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 5, 1, 1}
+
+	// PKCS#12 password-based encryption schemes, see RFC 7292, appendix C.
+	oidPBEWithSHAAnd128BitRC4 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 1}
+	oidPBEWithSHAAnd40BitRC4  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 2}
+	oidPBEWithSHAAnd3DES      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd128BitRC2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+)
+
+// algorithmNames maps the OIDs known to the package to their names.
+var algorithmNames = map[string]string{
+	oidPublicKeyRSA.String():     "RSA",
+	oidPublicKeyRSAPSS.String():  "RSASSA-PSS",
+	oidPublicKeyECDSA.String():   "ECDSA",
+	oidECDH.String():             "ECDH",
+	oidECMQV.String():            "ECMQV",
+	oidPublicKeyDSA.String():     "DSA",
+	oidPublicKeyEd25519.String(): "Ed25519",
+	oidPublicKeyX25519.String():  "X25519",
+	oidPublicKeyEd448.String():   "Ed448",
+	oidPublicKeyX448.String():    "X448",
+
+	oidMLDSA44.String(): "ML-DSA-44",
+	oidMLDSA65.String(): "ML-DSA-65",
+	oidMLDSA87.String(): "ML-DSA-87",
+
+	oidMLKEM512.String():  "ML-KEM-512",
+	oidMLKEM768.String():  "ML-KEM-768",
+	oidMLKEM1024.String(): "ML-KEM-1024",
+
+	oidMLKEM768X25519.String(): "X25519MLKEM768",
+
+	oidSLHDSASHA2_128s.String():  "SLH-DSA-SHA2-128s",
+	oidSLHDSASHA2_128f.String():  "SLH-DSA-SHA2-128f",
+	oidSLHDSASHA2_192s.String():  "SLH-DSA-SHA2-192s",
+	oidSLHDSASHA2_192f.String():  "SLH-DSA-SHA2-192f",
+	oidSLHDSASHA2_256s.String():  "SLH-DSA-SHA2-256s",
+	oidSLHDSASHA2_256f.String():  "SLH-DSA-SHA2-256f",
+	oidSLHDSASHAKE_128s.String(): "SLH-DSA-SHAKE-128s",
+	oidSLHDSASHAKE_128f.String(): "SLH-DSA-SHAKE-128f",
+	oidSLHDSASHAKE_192s.String(): "SLH-DSA-SHAKE-192s",
+	oidSLHDSASHAKE_192f.String(): "SLH-DSA-SHAKE-192f",
+	oidSLHDSASHAKE_256s.String(): "SLH-DSA-SHAKE-256s",
+	oidSLHDSASHAKE_256f.String(): "SLH-DSA-SHAKE-256f",
+
+	oidGOST3410_2012_256.String(): "gost2012_256",
+	oidGOST3410_2012_512.String(): "gost2012_512",
+	oidStreebog256.String():       "md_gost12_256",
+	oidStreebog512.String():       "md_gost12_512",
+
+	oidNamedCurveP224.String(): "P-224",
+	oidNamedCurveP256.String(): "P-256",
+	oidNamedCurveP384.String(): "P-384",
+	oidNamedCurveP521.String(): "P-521",
+	oidNamedCurveSM2.String():  "SM2",
+
+	oidNamedCurveBrainpoolP256r1.String(): "brainpoolP256r1",
+	oidNamedCurveBrainpoolP384r1.String(): "brainpoolP384r1",
+	oidNamedCurveBrainpoolP512r1.String(): "brainpoolP512r1",
+
+	oidPBES2.String():                  "PBES2",
+	oidPKCS5PBKDF2.String():            "PBKDF2",
+	oidScrypt.String():                 "scrypt",
+	oidHMACWithSHA1.String():           "hmacWithSHA1",
+	oidHMACWithSHA256.String():         "hmacWithSHA256",
+	oidHMACWithSHA3_224.String():       "hmacWithSHA3-224",
+	oidHMACWithSHA3_256.String():       "hmacWithSHA3-256",
+	oidHMACWithSHA3_384.String():       "hmacWithSHA3-384",
+	oidHMACWithSHA3_512.String():       "hmacWithSHA3-512",
+	oidHMACWithRIPEMD160.String():      "hmacWithRIPEMD160",
+	oidHMACWithSM3.String():            "hmacWithSM3",
+	oidHMACGOSTR3411_94.String():       "id-HMACGostR3411-94",
+	oidAES128CBC.String():              "aes-128-cbc",
+	oidAES192CBC.String():              "aes-192-cbc",
+	oidAES256CBC.String():              "aes-256-cbc",
+	oidAES128GCM.String():              "aes-128-gcm",
+	oidAES192GCM.String():              "aes-192-gcm",
+	oidAES256GCM.String():              "aes-256-gcm",
+	oidAES128CFB.String():              "aes-128-cfb",
+	oidAES192CFB.String():              "aes-192-cfb",
+	oidAES256CFB.String():              "aes-256-cfb",
+	oidAES128OFB.String():              "aes-128-ofb",
+	oidAES192OFB.String():              "aes-192-ofb",
+	oidAES256OFB.String():              "aes-256-ofb",
+	oidDESCBC.String():                 "des-cbc",
+	oidDESEDE3CBC.String():             "des-ede3-cbc",
+	oidRC2CBC.String():                 "rc2-cbc",
+	oidGOST28147.String():              "gost89",
+	oidSM4CBC.String():                 "sm4-cbc",
+	oidChaCha20Poly1305.String():       "chacha20-poly1305",
+	oidPBEWithSHAAnd128BitRC4.String(): "pbeWithSHAAnd128BitRC4",
+	oidPBEWithSHAAnd40BitRC4.String():  "pbeWithSHAAnd40BitRC4",
+	oidPBEWithSHAAnd3DES.String():      "pbeWithSHAAnd3-KeyTripleDES-CBC",
+	oidPBEWithSHAAnd128BitRC2.String(): "pbeWithSHAAnd128BitRC2-CBC",
+	oidPBEWithSHAAnd40BitRC2.String():  "pbeWithSHAAnd40BitRC2-CBC",
+
+	oidGOST28147TestParamSet.String(): "id-Gost28147-89-TestParamSet",
+	oidGOST28147ParamSetA.String():    "id-Gost28147-89-CryptoPro-A-ParamSet",
+	oidGOST28147ParamSetB.String():    "id-Gost28147-89-CryptoPro-B-ParamSet",
+	oidGOST28147ParamSetC.String():    "id-Gost28147-89-CryptoPro-C-ParamSet",
+	oidGOST28147ParamSetD.String():    "id-Gost28147-89-CryptoPro-D-ParamSet",
+	oidGOST28147ParamSetZ.String():    "id-tc26-gost-28147-param-Z",
+
+	oidPKCS7Data.String():          "data",
+	oidPKCS7EncryptedData.String(): "encryptedData",
+	OIDFriendlyName.String():       "friendlyName",
+	OIDLocalKeyID.String():         "localKeyID",
+	oidKeyLifecycle.String():       "keyLifecycle",
+	oidChecksum.String():           "keyChecksum",
+
+	OIDMicrosoftCSPName.String():            "msCSPName",
+	OIDMicrosoftLocalMachineKeyset.String(): "msLocalMachineKeyset",
+}
+
+// algorithmOIDs is the reverse of algorithmNames, keyed by lower-case name.
+var algorithmOIDs = map[string]string{}
+
+func init() {
+	for oid, name := range algorithmNames {
+		algorithmOIDs[strings.ToLower(name)] = oid
+	}
+}
+
+// OIDName returns the name of a key, curve, cipher, KDF or attribute OID
+// known to the package, or the dotted form of oid if it is unknown.
+func OIDName(oid asn1.ObjectIdentifier) string {
+	if name, ok := algorithmNames[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// LookupOID returns the OID of an algorithm name as returned by OIDName.
+// Names are matched case-insensitively.
+func LookupOID(name string) (asn1.ObjectIdentifier, bool) {
+	dotted, ok := algorithmOIDs[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return parseDottedOID(dotted)
+}
+
+// parseDottedOID parses the dotted form of an OID.
+func parseDottedOID(dotted string) (asn1.ObjectIdentifier, bool) {
+	var oid asn1.ObjectIdentifier
+	for _, arc := range strings.Split(dotted, ".") {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		oid = append(oid, n)
+	}
+	return oid, len(oid) > 0
+}
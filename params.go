@@ -0,0 +1,75 @@
+package pkcs8
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+)
+
+// normalizeKeyAlgorithm rewrites the algorithm of a PrivateKeyInfo into the
+// form crypto/x509 accepts. The id-ecDH and id-ecMQV OIDs are replaced by
+// id-ecPublicKey, which is the only one it accepts, and the restriction they
+// expressed is returned. The parameters are normalized by
+// normalizeParameters. If der needs no change, it is returned as is and
+// changed is false; otherwise normalized is a new buffer.
+func normalizeKeyAlgorithm(der []byte) (normalized []byte, restriction ECRestriction, changed bool) {
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return der, ECUnrestricted, false
+	}
+	switch {
+	case info.PrivateKeyAlgorithm.Algorithm.Equal(oidECDH):
+		restriction = ECDHOnly
+	case info.PrivateKeyAlgorithm.Algorithm.Equal(oidECMQV):
+		restriction = ECMQVOnly
+	}
+	if restriction != ECUnrestricted {
+		info.PrivateKeyAlgorithm.Algorithm = oidPublicKeyECDSA
+	}
+	if !normalizeParameters(&info.PrivateKeyAlgorithm) && restriction == ECUnrestricted {
+		return der, ECUnrestricted, false
+	}
+	normalized, err := asn1.Marshal(info)
+	if err != nil {
+		return der, ECUnrestricted, false
+	}
+	return normalized, restriction, true
+}
+
+// absentParameterAlgorithms are the key algorithms of RFC 8410, ML-DSA,
+// ML-KEM, SLH-DSA and X25519MLKEM768, whose parameters must be absent.
+var absentParameterAlgorithms = []asn1.ObjectIdentifier{
+	oidPublicKeyEd25519, oidPublicKeyX25519, oidPublicKeyEd448, oidPublicKeyX448,
+	oidMLDSA44, oidMLDSA65, oidMLDSA87, oidMLKEM512, oidMLKEM768, oidMLKEM1024,
+	oidSLHDSASHA2_128s, oidSLHDSASHA2_128f, oidSLHDSASHA2_192s, oidSLHDSASHA2_192f,
+	oidSLHDSASHA2_256s, oidSLHDSASHA2_256f, oidSLHDSASHAKE_128s, oidSLHDSASHAKE_128f,
+	oidSLHDSASHAKE_192s, oidSLHDSASHAKE_192f, oidSLHDSASHAKE_256s, oidSLHDSASHAKE_256f,
+	oidMLKEM768X25519,
+}
+
+// normalizeParameters rewrites the parameters of the key algorithm alg into
+// the form the parsers require, NULL for RSA and absent for the algorithms
+// of RFC 8410 such as Ed25519, and reports whether it changed them. Old JCE
+// providers omit the NULL of RSA keys, and some encoders write one for every
+// algorithm.
+func normalizeParameters(alg *pkix.AlgorithmIdentifier) bool {
+	absent := len(alg.Parameters.FullBytes) == 0
+	switch {
+	case alg.Algorithm.Equal(oidPublicKeyRSA) && absent:
+		alg.Parameters = asn1.NullRawValue
+	case isNull(alg.Parameters):
+		for _, oid := range absentParameterAlgorithms {
+			if alg.Algorithm.Equal(oid) {
+				alg.Parameters = asn1.RawValue{}
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+	return true
+}
+
+func isNull(v asn1.RawValue) bool {
+	return v.Class == asn1.ClassUniversal && v.Tag == asn1.TagNull && len(v.Bytes) == 0 && len(v.FullBytes) != 0
+}
@@ -0,0 +1,60 @@
+package pkcs8
+
+import (
+	"crypto"
+	"unicode/utf8"
+)
+
+// The functions below accept the password as a string or []rune. It is
+// converted to a temporary UTF-8 byte slice that is zeroed as soon as the
+// call returns, so no copy is left behind besides the caller's own; Go
+// strings cannot be zeroed, so callers that can should keep passwords in a
+// []rune or []byte and wipe it themselves. An empty password means none,
+// as a nil []byte does.
+
+// ParsePrivateKeyString is ParsePrivateKey with a string password.
+func ParsePrivateKeyString(der []byte, password string) (crypto.PrivateKey, KDFParameters, error) {
+	pass := []byte(password)
+	defer zero(pass)
+	return ParsePrivateKey(der, pass)
+}
+
+// ParsePrivateKeyRunes is ParsePrivateKey with a []rune password.
+func ParsePrivateKeyRunes(der []byte, password []rune) (crypto.PrivateKey, KDFParameters, error) {
+	pass := runesToBytes(password)
+	defer zero(pass)
+	return ParsePrivateKey(der, pass)
+}
+
+// MarshalPrivateKeyString is MarshalPrivateKey with a string password.
+func MarshalPrivateKeyString(priv interface{}, password string, opts *Opts) ([]byte, error) {
+	pass := []byte(password)
+	defer zero(pass)
+	return MarshalPrivateKey(priv, pass, opts)
+}
+
+// MarshalPrivateKeyRunes is MarshalPrivateKey with a []rune password.
+func MarshalPrivateKeyRunes(priv interface{}, password []rune, opts *Opts) ([]byte, error) {
+	pass := runesToBytes(password)
+	defer zero(pass)
+	return MarshalPrivateKey(priv, pass, opts)
+}
+
+// runesToBytes encodes runes as UTF-8 into a slice allocated once at its
+// final size, so that no discarded copy is left by growing it.
+func runesToBytes(runes []rune) []byte {
+	n := 0
+	for _, r := range runes {
+		if l := utf8.RuneLen(r); l > 0 {
+			n += l
+		} else {
+			n += utf8.RuneLen(utf8.RuneError)
+		}
+	}
+	b := make([]byte, n)
+	i := 0
+	for _, r := range runes {
+		i += utf8.EncodeRune(b[i:], r)
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package pkcs8
+
+import (
+	"crypto/sha1"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf16"
+)
+
+// pkcs12PBE describes a PKCS#12 password-based encryption scheme. These
+// schemes are only supported for parsing, and only if ParseOpts.AllowLegacy
+// is set.
+type pkcs12PBE struct {
+	keySize int
+	ivSize  int
+	decrypt func(key, iv, ciphertext []byte) ([]byte, error)
+}
+
+// isPKCS12PBE reports whether oid is a PKCS#12 PBE scheme of the default
+// registry. Its schemes are registered unless built with the pkcs8_nolegacy
+// tag, all schemes using legacy ciphers or, for 3DES, the legacy SHA-1
+// based KDF.
+func isPKCS12PBE(oid asn1.ObjectIdentifier) bool {
+	_, ok := defaultRegistry.lookupPBE(oid)
+	return ok
+}
+
+// pkcs12PBEParams are the pkcs-12PbeParams of RFC 7292, appendix C.
+type pkcs12PBEParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// DeriveKey derives a key of size bytes with the PKCS#12 KDF.
+func (p pkcs12PBEParams) DeriveKey(password []byte, size int) ([]byte, error) {
+	return pkcs12KDF(password, p.Salt, p.Iterations, 1, size)
+}
+
+// decryptPKCS12PBE decrypts data protected with scheme, the PKCS#12
+// password-based encryption scheme of info.
+func decryptPKCS12PBE(info *encryptedPrivateKeyInfo, scheme pkcs12PBE, password []byte, opts *ParseOpts) ([]byte, KDFParameters, error) {
+	oid := info.EncryptionAlgorithm.Algorithm
+	if err := opts.checkLegacy(oid); err != nil {
+		return nil, nil, err
+	}
+	// The scheme OID names both the cipher and the KDF, so it must be in
+	// both allow-lists.
+	if opts != nil && opts.AllowedCiphers != nil && !containsOID(opts.AllowedCiphers, oid) {
+		return nil, nil, fmt.Errorf("pkcs8: cipher not allowed (OID: %s)", oid)
+	}
+	if opts != nil && opts.AllowedKDFs != nil && !containsOID(opts.AllowedKDFs, oid) {
+		return nil, nil, fmt.Errorf("pkcs8: KDF not allowed (OID: %s)", oid)
+	}
+
+	if opts != nil && opts.RequireBoundParameters {
+		return nil, nil, fmt.Errorf("pkcs8: cipher cannot bind the encryption parameters (OID: %s)", oid)
+	}
+
+	var params pkcs12PBEParams
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, errors.New("pkcs8: invalid PKCS #12 PBE parameters")
+	}
+	opts.debug("chose KDF", "kdf", "PKCS12KDF", "iterations", params.Iterations, "saltSize", len(params.Salt))
+	key, err := params.DeriveKey(password, scheme.keySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zero(key)
+	opts.debug("derived key", "length", len(key))
+	var iv []byte
+	if scheme.ivSize > 0 {
+		if iv, err = pkcs12KDF(password, params.Salt, params.Iterations, 2, scheme.ivSize); err != nil {
+			return nil, nil, err
+		}
+	}
+	decrypted, err := scheme.decrypt(key, iv, info.EncryptedData)
+	if err != nil {
+		opts.debug("decryption failed", "error", err.Error())
+		return nil, nil, err
+	}
+	opts.debug("decryption succeeded", "length", len(decrypted))
+	return decrypted, &params, nil
+}
+
+// pkcs12KDF derives size bytes of key material with the SHA-1 based KDF of
+// RFC 7292, appendix B.2. The id selects the purpose: 1 for the key, 2 for
+// the IV and 3 for a MAC key.
+func pkcs12KDF(password, salt []byte, iterations int, id byte, size int) ([]byte, error) {
+	if iterations < 1 {
+		return nil, errors.New("pkcs8: invalid PKCS #12 PBE iteration count")
+	}
+	const u, v = sha1.Size, 64
+
+	// The password is encoded as a null-terminated BMPString.
+	runes := utf16.Encode([]rune(string(password)))
+	pass := make([]byte, 0, 2*len(runes)+2)
+	for _, r := range runes {
+		pass = append(pass, byte(r>>8), byte(r))
+	}
+	pass = append(pass, 0, 0)
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	in := append(fill(salt), fill(pass)...)
+
+	one := big.NewInt(1)
+	out := make([]byte, 0, size+u)
+	for len(out) < size {
+		h := sha1.New()
+		h.Write(d)
+		h.Write(in)
+		a := h.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			s := sha1.Sum(a)
+			a = s[:]
+		}
+		out = append(out, a...)
+		if len(out) >= size {
+			break
+		}
+
+		// I_j = (I_j + B + 1) mod 2^(8v) for every v-byte block of I.
+		b := new(big.Int).SetBytes(fill(a)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(in); j += v {
+			ij := new(big.Int).SetBytes(in[j : j+v])
+			ij.Add(ij, b)
+			sum := ij.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			block := in[j : j+v]
+			for k := range block {
+				block[k] = 0
+			}
+			copy(block[v-len(sum):], sum)
+		}
+	}
+	zero(pass)
+	return out[:size], nil
+}
@@ -0,0 +1,44 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"crypto/des"
+	"crypto/rc4"
+	"encoding/asn1"
+)
+
+func init() {
+	defaultRegistry.registerPBE(oidPBEWithSHAAnd128BitRC4, pkcs12PBE{keySize: 16, decrypt: rc4Decrypt})
+	defaultRegistry.registerPBE(oidPBEWithSHAAnd40BitRC4, pkcs12PBE{keySize: 5, decrypt: rc4Decrypt})
+	defaultRegistry.registerPBE(oidPBEWithSHAAnd3DES, pkcs12PBE{keySize: 24, ivSize: des.BlockSize, decrypt: TripleDESCBC.Decrypt})
+	defaultRegistry.registerPBE(oidPBEWithSHAAnd128BitRC2, pkcs12PBE{keySize: 16, ivSize: rc2BlockSize, decrypt: rc2CBCDecrypt})
+	defaultRegistry.registerPBE(oidPBEWithSHAAnd40BitRC2, pkcs12PBE{keySize: 5, ivSize: rc2BlockSize, decrypt: rc2CBCDecrypt})
+	for _, oid := range []asn1.ObjectIdentifier{
+		oidPBEWithSHAAnd128BitRC4, oidPBEWithSHAAnd40BitRC4, oidPBEWithSHAAnd3DES,
+		oidPBEWithSHAAnd128BitRC2, oidPBEWithSHAAnd40BitRC2,
+	} {
+		defaultRegistry.SetLegacy(oid, true)
+	}
+}
+
+func rc4Decrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	c.XORKeyStream(plaintext, ciphertext)
+	return plaintext, nil
+}
+
+// rc2CBCDecrypt decrypts with RC2 in CBC mode, the effective key length being
+// the length of the key.
+func rc2CBCDecrypt(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := newRC2Cipher(key, 8*len(key))
+	if err != nil {
+		return nil, err
+	}
+	return cbcDecrypt(block, key, iv, ciphertext)
+}
@@ -0,0 +1,189 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParsePrivateKeyPEM parses the first private key found in PEM-encoded data.
+// Password can be nil.
+//
+// Besides PKCS#8 "PRIVATE KEY" and "ENCRYPTED PRIVATE KEY" blocks, the
+// unencrypted traditional "RSA PRIVATE KEY" and "EC PRIVATE KEY" blocks are
+// accepted. Like openssl, the decoder tolerates explanatory text before the
+// BEGIN line, CRLF line endings, indentation and a missing final newline.
+//
+// Inputs without PEM armor, either raw DER or bare base64-encoded DER as
+// often found in environment variables and JSON documents, are detected and
+// parsed as well.
+func ParsePrivateKeyPEM(data []byte, password []byte) (crypto.PrivateKey, KDFParameters, error) {
+	return parsePrivateKeyPEM(data, password, nil)
+}
+
+// parsePrivateKeyPEM is ParsePrivateKeyPEM with opts applied to PKCS#8 keys.
+func parsePrivateKeyPEM(data []byte, password []byte, opts *ParseOpts) (crypto.PrivateKey, KDFParameters, error) {
+	block, err := decodePrivateKeyPEM(data)
+	if err != nil {
+		der, ok := detectDER(data)
+		if !ok {
+			return nil, nil, err
+		}
+		return parsePrivateKeyDER(der, password, opts)
+	}
+	if err := checkPEMEncryption(block); err != nil {
+		return nil, nil, err
+	}
+	if typ, warning := checkPEMLabel(block); warning != "" {
+		opts.debug("mislabeled PEM block", "label", block.Type, "content", typ)
+		if typ == "PRIVATE KEY" {
+			password = nil
+		} else if len(password) == 0 {
+			return nil, nil, errors.New("pkcs8: " + warning + "; password required")
+		}
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		return key, nil, err
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		return key, nil, err
+	}
+	return ParsePrivateKeyWithOpts(block.Bytes, password, opts)
+}
+
+// parsePrivateKeyDER parses a DER-encoded key of unknown format. Encrypted
+// keys must be PKCS#8, unencrypted keys may also be PKCS#1 or SEC 1.
+func parsePrivateKeyDER(der []byte, password []byte, opts *ParseOpts) (interface{}, KDFParameters, error) {
+	if len(password) != 0 {
+		return ParsePrivateKeyWithOpts(der, password, opts)
+	}
+	key, _, err := ParsePrivateKeyWithOpts(der, nil, opts)
+	if err == nil {
+		return key, nil, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil, nil
+	}
+	return nil, nil, err
+}
+
+// detectDER returns the DER encoding contained in data if data is either raw
+// DER or bare base64, in standard or URL encoding, with or without padding.
+func detectDER(data []byte) ([]byte, bool) {
+	if isDERSequence(data) {
+		return data, true
+	}
+	compact := bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, data)
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		der := make([]byte, enc.DecodedLen(len(compact)))
+		n, err := enc.Decode(der, compact)
+		if err == nil && isDERSequence(der[:n]) {
+			return der[:n], true
+		}
+	}
+	return nil, false
+}
+
+// isDERSequence reports whether data consists of exactly one DER SEQUENCE.
+func isDERSequence(data []byte) bool {
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(data, &raw)
+	return err == nil && len(rest) == 0 && raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence
+}
+
+// MarshalPrivateKeyPEM encodes a private key into PEM-encoded PKCS#8 with the
+// given options. Password can be nil, in which case a "PRIVATE KEY" block is
+// returned instead of an "ENCRYPTED PRIVATE KEY" block.
+func MarshalPrivateKeyPEM(priv interface{}, password []byte, opts *Opts) ([]byte, error) {
+	der, err := MarshalPrivateKey(priv, password, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := pem.EncodeToMemory(&pem.Block{Type: pemType(password), Bytes: der})
+	if opts != nil && opts.Annotate {
+		return append(annotation(priv, der), out...), nil
+	}
+	return out, nil
+}
+
+func pemType(password []byte) string {
+	if len(password) == 0 {
+		return "PRIVATE KEY"
+	}
+	return "ENCRYPTED PRIVATE KEY"
+}
+
+// checkPEMLabel returns the label matching the content of a PKCS#8 block
+// and, if block is labeled otherwise, a warning describing the mislabel.
+// Keys encrypted but labeled "PRIVATE KEY", and the other way round, are a
+// common mistake.
+func checkPEMLabel(block *pem.Block) (typ, warning string) {
+	var content string
+	switch {
+	case block.Type != "PRIVATE KEY" && block.Type != "ENCRYPTED PRIVATE KEY":
+		return block.Type, ""
+	case encryptedKeyContainer(block.Bytes) != "":
+		typ, content = "ENCRYPTED PRIVATE KEY", "an encrypted key"
+	case isPrivateKeyInfo(block.Bytes):
+		typ, content = "PRIVATE KEY", "an unencrypted key"
+	default:
+		return block.Type, ""
+	}
+	if typ == block.Type {
+		return typ, ""
+	}
+	return typ, fmt.Sprintf("PEM block labeled %s holds %s, it should be labeled %s", block.Type, content, typ)
+}
+
+func isPrivateKeyInfo(der []byte) bool {
+	var pki privateKeyInfo
+	_, err := asn1.Unmarshal(der, &pki)
+	return err == nil
+}
+
+// decodePrivateKeyPEM returns the first private key block of data.
+func decodePrivateKeyPEM(data []byte) (*pem.Block, error) {
+	rest := normalizePEM(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("pkcs8: no PEM-encoded private key found")
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return block, nil
+		}
+	}
+}
+
+// normalizePEM rewrites data so that encoding/pem accepts the sloppy inputs
+// openssl does: line endings are converted to LF and every line is stripped
+// of surrounding whitespace.
+func normalizePEM(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimSpace(line)
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
@@ -0,0 +1,72 @@
+package pkcs8
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// EncryptPEMBlock returns a PEM block of the given type holding data
+// encrypted with password, a replacement for the deprecated
+// x509.EncryptPEMBlock, whose unauthenticated DEK-Info encryption derives
+// the key with a single MD5 round. The block holds a DER-encoded
+// EncryptedPrivateKeyInfo using PBES2 with opts, DefaultOpts if nil, and
+// has no headers. Its type is blockType prefixed with "ENCRYPTED ", so that
+// a "PRIVATE KEY" PrivateKeyInfo becomes a standard "ENCRYPTED PRIVATE KEY"
+// block; data can be any payload though. Opts.Checksum is ignored.
+func EncryptPEMBlock(blockType string, data, password []byte, opts *Opts) (*pem.Block, error) {
+	if len(password) == 0 {
+		return nil, errors.New("pkcs8: password required to encrypt a PEM block")
+	}
+	if opts == nil {
+		opts = defaultMarshalOpts()
+	}
+	info, err := encryptPBES2(data, false, password, opts)
+	if err != nil {
+		return nil, err
+	}
+	der, err := asn1.Marshal(*info)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "ENCRYPTED " + blockType, Bytes: der}, nil
+}
+
+// DecryptPEMBlock returns the data of a block encrypted by EncryptPEMBlock,
+// or any "ENCRYPTED PRIVATE KEY" block, decrypted with password, a
+// replacement for the deprecated x509.DecryptPEMBlock. The type of the data
+// is the block type without its "ENCRYPTED " prefix.
+//
+// To migrate existing blocks, blocks encrypted by x509.EncryptPEMBlock, with
+// a DEK-Info header, are decrypted as well; encrypt their data again with
+// EncryptPEMBlock. As the data is not checked, a wrong password is only
+// detected by the padding of block ciphers, and then not always.
+func DecryptPEMBlock(b *pem.Block, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("pkcs8: password required to decrypt a PEM block")
+	}
+	if _, ok := b.Headers["DEK-Info"]; ok {
+		return x509.DecryptPEMBlock(b, password)
+	}
+	if !IsEncryptedPEMBlock(b) {
+		return nil, errors.New("pkcs8: PEM block is not encrypted")
+	}
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(b.Bytes, &info); err != nil || len(rest) != 0 {
+		return nil, errors.New("pkcs8: invalid encrypted PEM block")
+	}
+	data, _, err := decryptPrivateKeyInfo(b.Bytes, password, nil)
+	return data, err
+}
+
+// IsEncryptedPEMBlock reports whether b is encrypted, either by
+// EncryptPEMBlock or by x509.EncryptPEMBlock, replacing the deprecated
+// x509.IsEncryptedPEMBlock.
+func IsEncryptedPEMBlock(b *pem.Block) bool {
+	if _, ok := b.Headers["DEK-Info"]; ok {
+		return true
+	}
+	return strings.HasPrefix(b.Type, "ENCRYPTED ")
+}
@@ -0,0 +1,35 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// SPKIPin returns the base64-encoded SHA-256 digest of the
+// SubjectPublicKeyInfo of key, as used by HPKP pin-sha256 directives and
+// Android network security configuration pins. Key can be a public key or a
+// private key returned by the parse functions.
+func SPKIPin(key interface{}) (string, error) {
+	pub := crypto.PublicKey(key)
+	if priv := Public(key); priv != nil {
+		pub = priv
+	}
+	spki, err := MarshalPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(spki)
+	return base64.StdEncoding.EncodeToString(digest[:]), nil
+}
+
+// SPKIPinFromFile returns the SPKIPin of the private key in data, in any
+// form accepted by ParsePrivateKeyPEM, decrypted with password, which can be
+// nil.
+func SPKIPinFromFile(data []byte, password []byte) (string, error) {
+	priv, _, err := ParsePrivateKeyPEM(data, password)
+	if err != nil {
+		return "", err
+	}
+	return SPKIPin(priv)
+}
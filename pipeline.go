@@ -0,0 +1,123 @@
+package pkcs8
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// Pipeline decrypts, validates, checks, rewrites and re-encrypts PKCS#8
+// private keys. It is configured once and applied to many keys with
+// Process, as in migration and CI tooling. A Pipeline must not be modified
+// while in use; it is then safe for concurrent use.
+//
+// Each key goes through the stages in order, the first failure stopping it:
+//
+//   - "decrypt": the key is decrypted with Password and ParseOpts and
+//     parsed.
+//   - "validate": the checks of ParseOpts, such as VerifyPublicKey,
+//     FullValidation and MinRSABits, are run.
+//   - "policy": every function of Policy is called.
+//   - "attributes": every function of Attributes rewrites the attributes.
+//   - "encrypt": the key is encrypted with NewPassword and Opts.
+type Pipeline struct {
+	// Password decrypts the input keys. Nil if they are not encrypted.
+	Password []byte
+	// ParseOpts are the options to decrypt and validate the keys. Nil
+	// for the defaults.
+	ParseOpts *ParseOpts
+	// Policy holds the checks of the policy stage. They are given the key
+	// and its description, as returned by Inspect, and return an error to
+	// reject the key.
+	Policy []func(key crypto.PrivateKey, info *KeyInfo) error
+	// Attributes holds the rewrites of the attributes stage, applied in
+	// order, e.g. a call to StripMicrosoftAttributes.
+	Attributes []func(attrs []Attribute) ([]Attribute, error)
+	// NewPassword encrypts the output keys. Nil to write them unencrypted.
+	NewPassword []byte
+	// Opts are the options to encrypt the output keys. DefaultOpts are used
+	// if nil.
+	Opts *Opts
+}
+
+// PipelineError is returned by Pipeline.Process for a key that failed a
+// stage.
+type PipelineError struct {
+	// Stage is the name of the stage that failed, e.g. "policy".
+	Stage string
+	Err   error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pkcs8: %s stage: %s", e.Stage, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Process runs the DER-encoded PKCS#8 private key der through the stages of
+// p and returns the DER-encoded output key. Errors are *PipelineError.
+func (p *Pipeline) Process(der []byte) ([]byte, error) {
+	info, err := describeContainer(der)
+	if err != nil {
+		return nil, &PipelineError{Stage: "decrypt", Err: err}
+	}
+	var out []byte
+	_, _, err = decryptAndParse(der, p.Password, p.ParseOpts, func(pkey []byte, key interface{}) error {
+		if err := p.ParseOpts.validate(pkey, key); err != nil {
+			return &PipelineError{Stage: "validate", Err: err}
+		}
+
+		var pki privateKeyInfo
+		if _, err := asn1.Unmarshal(pkey, &pki); err != nil {
+			return &PipelineError{Stage: "decrypt", Err: errors.New("pkcs8: invalid private key info")}
+		}
+		info.describeKey(Public(key), pki.PrivateKeyAlgorithm.Algorithm)
+		for _, attr := range pki.Attributes {
+			info.Attributes = append(info.Attributes, OIDName(attr.Type))
+		}
+		for _, check := range p.Policy {
+			if err := check(key, info); err != nil {
+				return &PipelineError{Stage: "policy", Err: err}
+			}
+		}
+
+		if len(p.Attributes) > 0 {
+			attrs := pki.Attributes
+			for _, rewrite := range p.Attributes {
+				var err error
+				if attrs, err = rewrite(attrs); err != nil {
+					return &PipelineError{Stage: "attributes", Err: err}
+				}
+			}
+			rewritten, err := setPrivateKeyInfoAttributes(pkey, attrs)
+			if err != nil {
+				return &PipelineError{Stage: "attributes", Err: err}
+			}
+			if len(p.NewPassword) != 0 {
+				defer zero(rewritten)
+			}
+			pkey = rewritten
+		}
+
+		encrypted, err := encryptPrivateKeyInfo(pkey, p.NewPassword, p.Opts)
+		if err != nil {
+			return &PipelineError{Stage: "encrypt", Err: err}
+		}
+		if len(p.NewPassword) == 0 {
+			// pkey is zeroed once f returns.
+			encrypted = append([]byte(nil), encrypted...)
+		}
+		out = encrypted
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*PipelineError); !ok {
+			err = &PipelineError{Stage: "decrypt", Err: err}
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,151 @@
+package pkcs8
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+var (
+	oidPKCS7Data          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7EncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+)
+
+// PKCS#7 / CMS structures, see RFC 2315 and RFC 5652.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type pkcs7EncryptedData struct {
+	Version              int
+	EncryptedContentInfo pkcs7EncryptedContentInfo
+	UnprotectedAttrs     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// isPKCS7EncryptedData reports whether der looks like a PKCS#7 ContentInfo
+// of type encryptedData.
+func isPKCS7EncryptedData(der []byte) bool {
+	var ci pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return false
+	}
+	return ci.ContentType.Equal(oidPKCS7EncryptedData) &&
+		ci.Content.Class == asn1.ClassContextSpecific && ci.Content.Tag == 0
+}
+
+// decryptPKCS7EncryptedData decrypts a private key that was wrapped in a
+// password-protected PKCS#7 EncryptedData structure instead of an
+// EncryptedPrivateKeyInfo, as some Windows tooling does. The content is
+// encrypted with PBES2 or, as by Windows, Java and openssl pkcs12, with a
+// PKCS#12 PBE scheme, and is either a PrivateKeyInfo or the SafeContents of
+// a PKCS#12 safe holding a single KeyBag.
+func decryptPKCS7EncryptedData(der []byte, password []byte, opts *ParseOpts) ([]byte, KDFParameters, error) {
+	var ci pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, nil, errors.New("pkcs8: invalid PKCS#7 content info")
+	}
+	var ed pkcs7EncryptedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		return nil, nil, errors.New("pkcs8: invalid PKCS#7 encrypted data")
+	}
+	eci := ed.EncryptedContentInfo
+	if !eci.ContentType.Equal(oidPKCS7Data) {
+		return nil, nil, errors.New("pkcs8: unsupported PKCS#7 encrypted content type")
+	}
+	encryptedContent, err := pkcs7OctetString(eci.EncryptedContent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: eci.ContentEncryptionAlgorithm,
+		EncryptedData:       encryptedContent,
+	}
+	var decrypted []byte
+	var kdfParams KDFParameters
+	if scheme, ok := opts.registry().lookupPBE(info.EncryptionAlgorithm.Algorithm); ok {
+		if opts != nil && opts.derivedKey != nil {
+			return nil, nil, errors.New("pkcs8: PKCS #12 PBE schemes cannot use a derived key")
+		}
+		decrypted, kdfParams, err = decryptPKCS12PBE(&info, scheme, password, opts)
+	} else {
+		decrypted, kdfParams, err = decryptPBES2(&info, false, password, opts)
+	}
+	if err != nil {
+		return nil, nil, locateParseError(err, "ContentInfo.content.encryptedContentInfo.contentEncryptionAlgorithm", der)
+	}
+	key, err := pkcs12KeyBag(decrypted)
+	if err != nil {
+		zero(decrypted)
+		return nil, nil, err
+	}
+	return key, kdfParams, nil
+}
+
+var oidPKCS12KeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+
+// pkcs12SafeBag is the SafeBag of RFC 7292, section 4.2.
+type pkcs12SafeBag struct {
+	ID asn1.ObjectIdentifier
+	// Value is the [0] element, whose Bytes are the encoded value.
+	Value      asn1.RawValue   `asn1:"tag:0,explicit"`
+	Attributes []asn1.RawValue `asn1:"optional,set"`
+}
+
+// pkcs12KeyBag returns the PrivateKeyInfo of the single KeyBag of the
+// SafeContents content, or content itself if it is not a SafeContents, in
+// which case it should be a PrivateKeyInfo.
+func pkcs12KeyBag(content []byte) ([]byte, error) {
+	var bags []pkcs12SafeBag
+	if rest, err := asn1.Unmarshal(content, &bags); err != nil || len(rest) != 0 || len(bags) == 0 {
+		return content, nil
+	}
+	var key []byte
+	for _, bag := range bags {
+		if !bag.ID.Equal(oidPKCS12KeyBag) {
+			continue
+		}
+		if key != nil {
+			return nil, errors.New("pkcs8: PKCS#7 content holds more than one private key")
+		}
+		key = bag.Value.Bytes
+	}
+	if key == nil {
+		return nil, errors.New("pkcs8: PKCS#7 content holds no unencrypted private key")
+	}
+	// The key is moved to the front of content, so that it is zeroed with
+	// it by the caller.
+	n := copy(content, key)
+	zero(content[n:])
+	return content[:n], nil
+}
+
+// pkcs7OctetString returns the contents of an implicitly tagged OCTET STRING,
+// which BER encoders may split into a constructed series of segments.
+func pkcs7OctetString(v asn1.RawValue) ([]byte, error) {
+	if len(v.FullBytes) == 0 {
+		return nil, errors.New("pkcs8: PKCS#7 encrypted content is missing")
+	}
+	if !v.IsCompound {
+		return v.Bytes, nil
+	}
+	var content []byte
+	rest := v.Bytes
+	for len(rest) > 0 {
+		var segment []byte
+		var err error
+		rest, err = asn1.Unmarshal(rest, &segment)
+		if err != nil {
+			return nil, errors.New("pkcs8: invalid PKCS#7 encrypted content")
+		}
+		content = append(content, segment...)
+	}
+	return content, nil
+}
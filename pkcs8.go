@@ -1,29 +1,96 @@
 // Package pkcs8 implements functions to parse and convert private keys in PKCS#8 format, as defined in RFC5208 and RFC5958
+//
+// Besides the key types of crypto/x509, the parse and marshal functions
+// handle Ed448 and X448 keys, as Ed448PrivateKey and X448PrivateKey and
+// their public keys, DSA keys as *dsa.PrivateKey, id-RSASSA-PSS keys as
+// *rsa.PrivateKey, SM2 and Brainpool keys as *ecdsa.PrivateKey on the
+// SM2P256 and BrainpoolP256r1, BrainpoolP384r1 or BrainpoolP512r1 curves and
+// GOST R 34.10-2012 keys as *GOSTPrivateKey and ML-DSA, ML-KEM and SLH-DSA
+// keys as *MLDSAPrivateKey, *MLKEMPrivateKey and *SLHDSAPrivateKey. X25519
+// keys are handled as *ecdh.PrivateKey and X25519MLKEM768 composite keys as
+// *X25519MLKEM768PrivateKey when built with Go 1.20 or later.
+//
+// All functions are safe for concurrent use, as are registries, including
+// the default one changed by RegisterKDF, RegisterCipher and RegisterPRF.
+// Options must not be modified while a call using them is in progress.
+//
+// Parsing an encrypted key makes two transient copies of secret material:
+// the key derived from the password and the decrypted PrivateKeyInfo,
+// decrypted into a single buffer. BER input and restricted EC keys add a
+// re-encoded copy of the PrivateKeyInfo. All of them are zeroed before the
+// parse functions return; the returned key holds its own copy of the key
+// values, which Go offers no way to wipe.
+//
+// Build tags exclude optional parts of the package, for small binaries that
+// only need PBKDF2 and AES:
+//
+//   - pkcs8_nolegacy excludes the legacy algorithms, those parsed only with
+//     ParseOpts.AllowLegacy: DES, RC2, GOST 28147-89, AES in CFB and OFB
+//     modes, the PKCS#12 schemes, the RIPEMD-160 and GOST R 34.11-94 PRFs
+//     and PVK files.
+//   - pkcs8_noscrypt excludes scrypt. ScryptOpts is kept, but fails.
+//   - pkcs8_nointegrations excludes the other formats: JWE, COSE, DNSSEC,
+//     SmartCard-HSM DKEK blobs, SSH and Kubernetes Secrets.
+//
+// Keys using an excluded algorithm fail to parse as unsupported.
 package pkcs8
 
 import (
 	"crypto"
-	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"sync"
 )
 
-// DefaultOpts are the default options for encrypting a key if none are given.
-// The defaults can be changed by the library user.
+// DefaultOpts are the default options for encrypting a key if none are given:
+// AES-256-CBC with PBKDF2-HMAC-SHA256, 600,000 iterations and a 16-byte salt,
+// following the OWASP recommendations. The defaults can be changed by the
+// library user, e.g. with SetDefaultMarshalOpts(*LegacyDefaults()).
 var DefaultOpts = &Opts{
 	Cipher: AES256CBC,
 	KDFOpts: PBKDF2Opts{
-		SaltSize:       8,
-		IterationCount: 10000,
+		SaltSize:       16,
+		IterationCount: 600000,
 		HMACHash:       crypto.SHA256,
 	},
 }
 
+// defaultOptsMu guards DefaultOpts against SetDefaultMarshalOpts.
+var defaultOptsMu sync.RWMutex
+
+// SetDefaultMarshalOpts replaces DefaultOpts, which are used by
+// ConvertPrivateKeyToPKCS8 and every other function encrypting a key when no
+// options are given. Unlike assigning DefaultOpts, which must only be done
+// before keys are encrypted concurrently, it is safe to call at any time.
+func SetDefaultMarshalOpts(opts Opts) error {
+	if opts.Cipher == nil {
+		return errors.New("pkcs8: default options must specify a cipher")
+	}
+	if opts.KDFOpts == nil {
+		return errors.New("pkcs8: default options must specify a KDF")
+	}
+	if opts.KDFOpts.GetSaltSize() <= 0 {
+		return errors.New("pkcs8: default options must specify a salt size")
+	}
+	defaultOptsMu.Lock()
+	DefaultOpts = &opts
+	defaultOptsMu.Unlock()
+	return nil
+}
+
+// defaultMarshalOpts returns DefaultOpts.
+func defaultMarshalOpts() *Opts {
+	defaultOptsMu.RLock()
+	defer defaultOptsMu.RUnlock()
+	return DefaultOpts
+}
+
 // KDFOpts contains options for a key derivation function.
 // An implementation of this interface must be specified when encrypting a PKCS#8 key.
 type KDFOpts interface {
@@ -45,12 +112,12 @@ type KDFParameters interface {
 	DeriveKey(password []byte, size int) (key []byte, err error)
 }
 
-var kdfs = make(map[string]func() KDFParameters)
-
 // RegisterKDF registers a function that returns a new instance of the given KDF
 // parameters. This allows the library to support client-provided KDFs.
+// The KDF is added to the default registry, used by every call that does
+// not specify its own Registry.
 func RegisterKDF(oid asn1.ObjectIdentifier, params func() KDFParameters) {
-	kdfs[oid.String()] = params
+	defaultRegistry.RegisterKDF(oid, params)
 }
 
 // Cipher represents a cipher for encrypting the key material.
@@ -67,18 +134,235 @@ type Cipher interface {
 	OID() asn1.ObjectIdentifier
 }
 
-var ciphers = make(map[string]func() Cipher)
+// CipherParameters is implemented by ciphers whose encryption scheme
+// parameters are not just the IV as an OCTET STRING, such as RC2-CBC.
+type CipherParameters interface {
+	// MarshalParameters returns the DER-encoded parameters for iv.
+	MarshalParameters(iv []byte) ([]byte, error)
+	// UnmarshalParameters decodes DER-encoded parameters into the cipher
+	// they describe and the IV.
+	UnmarshalParameters(der []byte) (Cipher, []byte, error)
+}
+
+// marshalCipherParameters returns the DER-encoded parameters of c for iv.
+func marshalCipherParameters(c Cipher, iv []byte) ([]byte, error) {
+	if p, ok := c.(CipherParameters); ok {
+		return p.MarshalParameters(iv)
+	}
+	return asn1.Marshal(iv)
+}
 
 // RegisterCipher registers a function that returns a new instance of the given
 // cipher. This allows the library to support client-provided ciphers.
+// The cipher is added to the default registry, used by every call that does
+// not specify its own Registry.
 func RegisterCipher(oid asn1.ObjectIdentifier, cipher func() Cipher) {
-	ciphers[oid.String()] = cipher
+	defaultRegistry.RegisterCipher(oid, cipher)
 }
 
 // Opts contains options for encrypting a PKCS#8 key.
+// The cipher and KDF are used as given; registries only apply to parsing.
 type Opts struct {
 	Cipher  Cipher
 	KDFOpts KDFOpts
+	// Compat selects the encoding conventions of another implementation.
+	Compat Compat
+	// Rand is the source of the salt and IV. crypto/rand.Reader is used if
+	// nil.
+	Rand io.Reader
+	// HealthCheck runs the repetition count and adaptive proportion health
+	// tests of NIST SP 800-90B on the bytes read from Rand, assuming it is a
+	// full-entropy source such as an HSM DRBG, and fails with an
+	// EntropyError if they detect a stuck source: a repeated IV silently
+	// destroys the confidentiality of the keys. It has no effect if Rand is
+	// nil.
+	HealthCheck bool
+	// OmitRSANullParameters omits the parameters of the AlgorithmIdentifier
+	// of RSA keys instead of encoding them as an explicit ASN.1 NULL, as
+	// required by some strict verifiers. Both forms are accepted on parse.
+	OmitRSANullParameters bool
+	// RSAPSS marshals RSA keys under id-RSASSA-PSS rather than
+	// rsaEncryption, restricting them to RSASSA-PSS signatures with these
+	// parameters, as `openssl genpkey -algorithm rsa-pss` does. A zero
+	// PSSParameters writes no parameters, leaving the key otherwise
+	// unrestricted; in others every field must be set.
+	RSAPSS *PSSParameters
+	// GCMNonceSize selects the nonce size of AES-GCM ciphers, 12 or 16
+	// bytes. The cipher's own nonce size, 12 bytes, is used if zero.
+	GCMNonceSize int
+	// Annotate prepends comment lines to the output of MarshalPrivateKeyPEM
+	// describing the key, its SPKIPin, its encryption and when it was
+	// written. The parsers ignore text before the BEGIN line.
+	Annotate bool
+	// Checksum adds an attribute to encrypted keys holding an HMAC of the
+	// PrivateKeyInfo, keyed from the derived key. It is verified on parse,
+	// so that encrypted data corrupted in storage is reported as such
+	// rather than as an incorrect password, and not silently parsed into a
+	// wrong key. Other implementations ignore the attribute.
+	Checksum bool
+	// BindParameters authenticates the encoded encryption
+	// AlgorithmIdentifier, with the KDF and cipher parameters, as additional
+	// data of the AEAD ciphers, AES-GCM and ChaCha20Poly1305, so that they
+	// cannot be changed, e.g. to lower the iteration count, without breaking
+	// the tag. It is off by default as other implementations do not expect
+	// it and fail to decrypt such keys; this package decrypts both forms
+	// unless ParseOpts.RequireBoundParameters is set. It has no effect on
+	// other ciphers.
+	BindParameters bool
+}
+
+func (opts *Opts) rand() io.Reader {
+	if opts.Rand == nil {
+		return rand.Reader
+	}
+	if opts.HealthCheck {
+		return &healthCheckedReader{r: opts.Rand}
+	}
+	return opts.Rand
+}
+
+// Compat selects the encoding conventions used when encrypting a key.
+type Compat int
+
+const (
+	// CompatNone encodes every parameter explicitly.
+	CompatNone Compat = iota
+	// CompatOpenSSL encodes the PBES2 parameters byte-identically to
+	// `openssl pkcs8 -topk8 -v2 <cipher> -v2prf <prf>`: the PBKDF2 PRF is
+	// omitted when it is the default hmacWithSHA1.
+	CompatOpenSSL
+)
+
+// ParseOpts contains options for parsing an encrypted PKCS#8 key.
+type ParseOpts struct {
+	// Registry holds the KDFs, ciphers and PKCS#12 PBE schemes that may be
+	// used to decrypt the key. The default registry is used if nil.
+	Registry *Registry
+	// AllowedCiphers restricts the ciphers accepted by this call, given by
+	// OID, e.g. AES256CBC.OID(). All registered ciphers are accepted if nil.
+	AllowedCiphers []asn1.ObjectIdentifier
+	// AllowedKDFs restricts the KDFs accepted by this call, given by OID,
+	// e.g. PBKDF2Opts{}.OID(). All registered KDFs are accepted if nil.
+	// The PKCS#12 PBE schemes, whose OID names both the KDF and the cipher,
+	// must be in both AllowedCiphers and AllowedKDFs.
+	AllowedKDFs []asn1.ObjectIdentifier
+	// AllowLegacy allows decrypting keys that use algorithms marked as legacy
+	// in the registry, such as single DES. They are refused by default.
+	AllowLegacy bool
+	// RequireBoundParameters only accepts encrypted keys whose encryption
+	// parameters are bound to the ciphertext, see Opts.BindParameters. Keys
+	// encrypted with an AEAD cipher without binding, and keys encrypted with
+	// other ciphers, which cannot bind them, are refused.
+	RequireBoundParameters bool
+	// AllowBER accepts BER-encoded keys, with indefinite lengths or
+	// constructed OCTET STRINGs, as emitted by Java and some HSMs. They are
+	// converted to DER before being parsed.
+	AllowBER bool
+	// VerifyPublicKey checks that the public keys stored alongside the private
+	// key, in a v2 PrivateKeyInfo or in an EC private key, match it, catching
+	// corrupted or spliced key files.
+	VerifyPublicKey bool
+	// Logger, if set, receives a debug record for each parsing decision:
+	// detected container, KDF, PRF, cipher, derived key length and
+	// decryption outcome. Passwords and key material are never logged.
+	// A *slog.Logger can be used.
+	Logger Logger
+	// MinRSABits rejects RSA keys whose modulus is shorter than the given
+	// number of bits. No minimum is enforced if zero.
+	MinRSABits int
+	// CheckRSAExponent rejects RSA keys whose public exponent is even or not
+	// larger than 2^16, as required by FIPS 186-5. Such keys are degenerate
+	// or prone to signature forgeries in sloppy verifiers.
+	CheckRSAExponent bool
+	// SkipPrecompute returns RSA keys without their CRT values, which
+	// crypto/x509 computes and validates at a cost that dominates bulk
+	// parsing. crypto/rsa derives them on each use of such a key; call
+	// Precompute on keys that are kept. Only the structure of the key and
+	// its modulus are checked.
+	SkipPrecompute bool
+	// FullValidation runs the consistency checks that would otherwise only
+	// surface when the key is first used: rsa.PrivateKey.Validate, that the
+	// public point of an EC key is on the curve and matches its scalar, that
+	// an Ed25519 seed matches its public half, and VerifyPublicKey. It takes
+	// precedence over SkipPrecompute, whose keys are validated in full.
+	FullValidation bool
+
+	// derivedKey is the key given to ParseWithDerivedKey, used instead of
+	// deriving one from a password.
+	derivedKey []byte
+}
+
+func (opts *ParseOpts) registry() *Registry {
+	if opts == nil || opts.Registry == nil {
+		return defaultRegistry
+	}
+	return opts.Registry
+}
+
+// checkAllowed returns an error if the cipher or KDF of a PBES2 scheme is not
+// allowed by opts.
+func (opts *ParseOpts) checkAllowed(params *pbes2Params) error {
+	for _, oid := range []asn1.ObjectIdentifier{params.EncryptionScheme.Algorithm, params.KeyDerivationFunc.Algorithm} {
+		if err := opts.checkLegacy(oid); err != nil {
+			return err
+		}
+	}
+	if opts == nil {
+		return nil
+	}
+	if opts.AllowedCiphers != nil && !containsOID(opts.AllowedCiphers, params.EncryptionScheme.Algorithm) {
+		return fmt.Errorf("pkcs8: cipher not allowed (OID: %s)", params.EncryptionScheme.Algorithm)
+	}
+	if opts.AllowedKDFs != nil && !containsOID(opts.AllowedKDFs, params.KeyDerivationFunc.Algorithm) {
+		return fmt.Errorf("pkcs8: KDF not allowed (OID: %s)", params.KeyDerivationFunc.Algorithm)
+	}
+	return nil
+}
+
+// checkLegacy returns an error if oid is a legacy algorithm and opts do not
+// allow legacy algorithms.
+func (opts *ParseOpts) checkLegacy(oid asn1.ObjectIdentifier) error {
+	if opts.registry().IsLegacy(oid) && (opts == nil || !opts.AllowLegacy) {
+		return fmt.Errorf("pkcs8: legacy algorithm not allowed (OID: %s)", oid)
+	}
+	return nil
+}
+
+// Logger receives debug records, see ParseOpts.Logger. It is implemented by
+// *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+}
+
+func (opts *ParseOpts) debug(msg string, args ...interface{}) {
+	if opts != nil && opts.Logger != nil {
+		opts.Logger.Debug("pkcs8: "+msg, args...)
+	}
+}
+
+// checkRSA returns an error if key does not satisfy the RSA checks of opts.
+func (opts *ParseOpts) checkRSA(key *rsa.PrivateKey) error {
+	if bits := key.N.BitLen(); bits < opts.MinRSABits {
+		return fmt.Errorf("pkcs8: RSA modulus too short (%d bits)", bits)
+	}
+	if opts.CheckRSAExponent {
+		if key.E%2 == 0 {
+			return errors.New("pkcs8: RSA public exponent must be odd")
+		}
+		if key.E <= 1<<16 {
+			return fmt.Errorf("pkcs8: RSA public exponent too small (%d)", key.E)
+		}
+	}
+	return nil
+}
+
+func containsOID(oids []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) bool {
+	for _, o := range oids {
+		if o.Equal(oid) {
+			return true
+		}
+	}
+	return false
 }
 
 // Unecrypted PKCS8
@@ -96,119 +380,443 @@ type pbes2Params struct {
 	EncryptionScheme  pkix.AlgorithmIdentifier
 }
 
+// privateKeyInfo is the OneAsymmetricKey structure of RFC 5958, which
+// extends the PrivateKeyInfo of RFC 5208 with an optional public key.
 type privateKeyInfo struct {
 	Version             int
 	PrivateKeyAlgorithm pkix.AlgorithmIdentifier
 	PrivateKey          []byte
+	Attributes          []Attribute    `asn1:"optional,tag:0,set"`
+	PublicKey           asn1.BitString `asn1:"optional,tag:1"`
+}
+
+// ParsePrivateKey parses a DER-encoded PKCS#8 private key.
+// Password can be nil.
+// This is equivalent to ParsePKCS8PrivateKey.
+//
+// It keeps the interface{} result of the first releases so that existing
+// code using it as a function value still compiles; ParsePrivateKeyWithOpts
+// and the other parse functions return crypto.PrivateKey.
+func ParsePrivateKey(der []byte, password []byte) (interface{}, KDFParameters, error) {
+	return ParsePrivateKeyWithOpts(der, password, nil)
 }
 
-func parseKeyDerivationFunc(keyDerivationFunc pkix.AlgorithmIdentifier) (KDFParameters, error) {
-	oid := keyDerivationFunc.Algorithm.String()
-	newParams, ok := kdfs[oid]
-	if !ok {
-		return nil, fmt.Errorf("pkcs8: unsupported KDF (OID: %s)", oid)
+// ParsePrivateKeyWithOpts parses a DER-encoded PKCS#8 private key with the
+// given options. Password and opts can be nil.
+func ParsePrivateKeyWithOpts(der []byte, password []byte, opts *ParseOpts) (crypto.PrivateKey, KDFParameters, error) {
+	key, kdfParams, _, err := parsePrivateKey(der, password, opts)
+	return key, kdfParams, err
+}
+
+// parsePrivateKey parses a DER-encoded PKCS#8 private key and returns the
+// usage restriction of EC keys.
+func parsePrivateKey(der []byte, password []byte, opts *ParseOpts) (interface{}, KDFParameters, ECRestriction, error) {
+	var key interface{}
+	kdfParams, restriction, err := decryptAndParse(der, password, opts, func(pkey []byte, k interface{}) error {
+		key = k
+		return opts.validate(pkey, k)
+	})
+	if err != nil {
+		return nil, nil, 0, err
 	}
-	params := newParams()
-	_, err := asn1.Unmarshal(keyDerivationFunc.Parameters.FullBytes, params)
+	return key, kdfParams, restriction, nil
+}
+
+// decryptAndParse decrypts der with password if given and parses it, then
+// calls f with the DER-encoded PrivateKeyInfo and the parsed key. The
+// PrivateKeyInfo must not be retained by f, as it is zeroed when f returns
+// unless it is der itself.
+func decryptAndParse(der []byte, password []byte, opts *ParseOpts, f func(pkey []byte, key interface{}) error) (KDFParameters, ECRestriction, error) {
+	if opts != nil && opts.AllowBER {
+		var err error
+		if der, err = berToDER(der); err != nil {
+			return nil, 0, err
+		}
+	}
+	decryptedKey, kdfParams, err := decryptPrivateKeyInfo(der, password, opts)
 	if err != nil {
-		return nil, errors.New("pkcs8: invalid KDF parameters")
+		return nil, 0, err
+	}
+	if kdfParams != nil {
+		// The decrypted buffer is ours; x509 copies the key values out of it.
+		defer zero(decryptedKey)
+	}
+
+	if opts != nil && opts.AllowBER && kdfParams != nil {
+		if normalized, err := berToDER(decryptedKey); err == nil {
+			decryptedKey = normalized
+			defer zero(normalized)
+		}
 	}
-	return params, nil
+	unrestricted, restriction, changed := normalizeKeyAlgorithm(decryptedKey)
+	if changed {
+		defer zero(unrestricted)
+	}
+	key, err := parsePKCS8(unrestricted, opts)
+	if err != nil {
+		if kdfParams != nil {
+			opts.debug("decrypted data is not a PrivateKeyInfo, assuming incorrect password", "error", err.Error())
+			return nil, 0, errIncorrectPassword(der, decryptedKey)
+		}
+		return nil, 0, err
+	}
+	if err := f(decryptedKey, key); err != nil {
+		return nil, 0, err
+	}
+	return kdfParams, restriction, nil
 }
 
-func parseEncryptionScheme(encryptionScheme pkix.AlgorithmIdentifier) (Cipher, []byte, error) {
-	oid := encryptionScheme.Algorithm.String()
-	newCipher, ok := ciphers[oid]
-	if !ok {
-		return nil, nil, fmt.Errorf("pkcs8: unsupported cipher (OID: %s)", oid)
+// validate runs the checks of opts on key, parsed from the DER-encoded
+// PrivateKeyInfo pkey.
+func (opts *ParseOpts) validate(pkey []byte, key interface{}) error {
+	if opts == nil {
+		return nil
 	}
-	cipher := newCipher()
-	var iv []byte
-	if _, err := asn1.Unmarshal(encryptionScheme.Parameters.FullBytes, &iv); err != nil {
-		return nil, nil, errors.New("pkcs8: invalid cipher parameters")
+	if opts.VerifyPublicKey || opts.FullValidation {
+		if err := verifyEmbeddedPublicKey(pkey, key); err != nil {
+			return err
+		}
 	}
-	return cipher, iv, nil
+	if opts.FullValidation {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return opts.checkRSA(rsaKey)
+	}
+	return nil
 }
 
-// ParsePrivateKey parses a DER-encoded PKCS#8 private key.
-// Password can be nil.
-// This is equivalent to ParsePKCS8PrivateKey.
-func ParsePrivateKey(der []byte, password []byte) (interface{}, KDFParameters, error) {
+// parsePKCS8 parses a PrivateKeyInfo, leaving out the CRT values of RSA keys
+// if opts.SkipPrecompute is set.
+func parsePKCS8(der []byte, opts *ParseOpts) (interface{}, error) {
+	if opts == nil || !opts.SkipPrecompute {
+		return parsePKCS8PrivateKey(der)
+	}
+	var pki privateKeyInfo
+	if _, err := asn1.Unmarshal(der, &pki); err != nil || !pki.PrivateKeyAlgorithm.Algorithm.Equal(oidPublicKeyRSA) {
+		return parsePKCS8PrivateKey(der)
+	}
+	// Multi-prime and malformed keys take the slow path, which reports
+	// errors the same way.
+	var k pkcs1PrivateKey
+	if rest, err := asn1.Unmarshal(pki.PrivateKey, &k); err != nil || len(rest) != 0 || k.Version != 0 {
+		return parsePKCS8PrivateKey(der)
+	}
+	for _, v := range []*big.Int{k.N, k.D, k.P, k.Q} {
+		if v.Sign() <= 0 {
+			return nil, errors.New("pkcs8: invalid RSA private key")
+		}
+	}
+	if k.E < 2 || k.E > 1<<31-1 || new(big.Int).Mul(k.P, k.Q).Cmp(k.N) != 0 {
+		return nil, errors.New("pkcs8: invalid RSA private key")
+	}
+	return &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: k.N, E: k.E},
+		D:         k.D,
+		Primes:    []*big.Int{k.P, k.Q},
+	}, nil
+}
+
+// pkcs1PrivateKey is the two-prime RSAPrivateKey of RFC 8017, appendix A.1.2.
+type pkcs1PrivateKey struct {
+	Version int
+	N       *big.Int
+	E       int
+	D       *big.Int
+	P       *big.Int
+	Q       *big.Int
+	Dp      *big.Int
+	Dq      *big.Int
+	Qinv    *big.Int
+}
+
+// ParseWithDerivedKey parses a PBES2-encrypted PKCS#8 private key with the
+// content-encryption key already derived from the password, e.g. by PBKDF2 or
+// scrypt running in dedicated hardware, skipping the KDF. The key must have
+// the size of the cipher. A wrong key is reported as an incorrect password.
+func ParseWithDerivedKey(der []byte, symmetricKey []byte) (crypto.PrivateKey, error) {
+	if len(symmetricKey) == 0 {
+		return nil, errors.New("pkcs8: derived key must not be empty")
+	}
+	key, _, _, err := parsePrivateKey(der, nil, &ParseOpts{derivedKey: symmetricKey})
+	return key, err
+}
+
+// decryptPrivateKeyInfo returns the DER-encoded PrivateKeyInfo contained in
+// der, decrypting it first if a password is given.
+func decryptPrivateKeyInfo(der []byte, password []byte, opts *ParseOpts) ([]byte, KDFParameters, error) {
 	// No password provided, assume the private key is unencrypted
-	if len(password) == 0 {
-		privateKey, err := x509.ParsePKCS8PrivateKey(der)
-		return privateKey, nil, err
+	if len(password) == 0 && (opts == nil || opts.derivedKey == nil) {
+		opts.debug("no password, assuming PrivateKeyInfo")
+		return der, nil, nil
+	}
+
+	// Some tools wrap the key in PKCS#7 EncryptedData rather than
+	// EncryptedPrivateKeyInfo
+	if isPKCS7EncryptedData(der) {
+		opts.debug("detected container", "container", "PKCS7EncryptedData")
+		return decryptPKCS7EncryptedData(der, password, opts)
 	}
 
 	// Use the password provided to decrypt the private key
 	var privKey encryptedPrivateKeyInfo
 	if _, err := asn1.Unmarshal(der, &privKey); err != nil {
-		return nil, nil, errors.New("pkcs8: only PKCS #5 v2.0 supported")
-	}
+		return nil, nil, locateParseError(newParseError("only PKCS #5 v2.0 supported", "EncryptedPrivateKeyInfo", der, err), "", der)
+	}
+	opts.debug("detected container", "container", "EncryptedPrivateKeyInfo",
+		"scheme", OIDName(privKey.EncryptionAlgorithm.Algorithm))
+	if scheme, ok := opts.registry().lookupPBE(privKey.EncryptionAlgorithm.Algorithm); ok {
+		if opts != nil && opts.derivedKey != nil {
+			return nil, nil, errors.New("pkcs8: PKCS #12 PBE schemes cannot use a derived key")
+		}
+		return decryptPKCS12PBE(&privKey, scheme, password, opts)
+	}
+	// privKey.EncryptedData is a copy made by asn1.Unmarshal.
+	decrypted, kdfParams, err := decryptPBES2(&privKey, true, password, opts)
+	return decrypted, kdfParams, locateParseError(err, "EncryptedPrivateKeyInfo.encryptionAlgorithm", der)
+}
 
-	if !privKey.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
+// decryptPBES2 decrypts data protected with a PBES2 encryption scheme. If
+// inPlace is set, info.EncryptedData may be overwritten with the plaintext.
+func decryptPBES2(info *encryptedPrivateKeyInfo, inPlace bool, password []byte, opts *ParseOpts) ([]byte, KDFParameters, error) {
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPBES2) {
 		return nil, nil, errors.New("pkcs8: only PBES2 supported")
 	}
 
+	// Paths of parse errors are relative to the encryption AlgorithmIdentifier.
 	var params pbes2Params
-	if _, err := asn1.Unmarshal(privKey.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
-		return nil, nil, errors.New("pkcs8: invalid PBES2 parameters")
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, newParseError("invalid PBES2 parameters", "parameters", info.EncryptionAlgorithm.Parameters.FullBytes, err)
 	}
-
-	cipher, iv, err := parseEncryptionScheme(params.EncryptionScheme)
-	if err != nil {
+	if err := opts.checkAllowed(&params); err != nil {
 		return nil, nil, err
 	}
 
-	kdfParams, err := parseKeyDerivationFunc(params.KeyDerivationFunc)
+	cipher, iv, err := opts.registry().parseEncryptionScheme(params.EncryptionScheme)
 	if err != nil {
-		return nil, nil, err
+		if c, cbcIV, ok := opts.registry().parseGCMAsCBC(params.EncryptionScheme); ok {
+			opts.debug("decrypting AES-GCM labelled key in CBC mode")
+			cipher, iv, err = c, cbcIV, nil
+		}
 	}
-
-	keySize := cipher.KeySize()
-	symkey, err := kdfParams.DeriveKey(password, keySize)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, locateParseError(err, "parameters", nil)
 	}
 
-	encryptedKey := privKey.EncryptedData
-	decryptedKey, err := cipher.Decrypt(symkey, iv, encryptedKey)
+	requireBound := opts != nil && opts.RequireBoundParameters
+	if _, ok := cipher.(aeadCipher); !ok && requireBound {
+		return nil, nil, fmt.Errorf("pkcs8: cipher cannot bind the encryption parameters (OID: %s)", params.EncryptionScheme.Algorithm)
+	}
+
+	kdfParams, err := opts.registry().parseKeyDerivationFunc(params.KeyDerivationFunc)
+	if err != nil {
+		return nil, nil, locateParseError(err, "parameters", nil)
+	}
+	if p, ok := kdfParams.(*pbkdf2Params); ok {
+		if err := opts.checkLegacy(p.PRF.Algorithm); err != nil {
+			return nil, nil, err
+		}
+		prf := "hmacWithSHA1"
+		if len(p.PRF.Algorithm) > 0 {
+			prf = OIDName(p.PRF.Algorithm)
+		}
+		salt, _ := p.specifiedSalt()
+		opts.debug("chose KDF", "kdf", "PBKDF2", "prf", prf, "iterations", p.IterationCount, "saltSize", len(salt))
+	} else {
+		opts.debug("chose KDF", "kdf", OIDName(params.KeyDerivationFunc.Algorithm))
+	}
+
+	keySize, err := derivedKeySize(cipher, kdfParams)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	key, err := x509.ParsePKCS8PrivateKey(decryptedKey)
+	opts.debug("chose cipher", "cipher", OIDName(params.EncryptionScheme.Algorithm), "keySize", keySize, "ivSize", len(iv))
+	var symkey []byte
+	if opts != nil && opts.derivedKey != nil {
+		if len(opts.derivedKey) != keySize {
+			return nil, nil, fmt.Errorf("pkcs8: derived key must be %d bytes long", keySize)
+		}
+		symkey = opts.derivedKey
+		opts.debug("using derived key", "length", len(symkey))
+	} else {
+		if symkey, err = kdfParams.DeriveKey(password, keySize); err != nil {
+			return nil, nil, err
+		}
+		defer zero(symkey)
+		opts.debug("derived key", "length", len(symkey))
+	}
+
+	var decrypted []byte
+	if a, ok := cipher.(aeadCipher); ok {
+		// Try the parameters as additional data first, see
+		// Opts.BindParameters: if they were changed, neither form
+		// authenticates.
+		var aad []byte
+		if aad, err = asn1.Marshal(info.EncryptionAlgorithm); err != nil {
+			return nil, nil, err
+		}
+		if decrypted, err = a.decryptWithAAD(symkey, iv, info.EncryptedData, aad); err == nil {
+			opts.debug("parameters bound as additional data")
+		} else if !requireBound {
+			decrypted, err = cipher.Decrypt(symkey, iv, info.EncryptedData)
+		}
+	} else if d, ok := cipher.(inPlaceDecrypter); ok && inPlace {
+		decrypted, err = d.decryptInPlace(symkey, iv, info.EncryptedData)
+	} else {
+		decrypted, err = cipher.Decrypt(symkey, iv, info.EncryptedData)
+	}
 	if err != nil {
-		return nil, nil, errors.New("pkcs8: incorrect password")
+		opts.debug("decryption failed", "error", err.Error())
+		return nil, nil, err
+	}
+	opts.debug("decryption succeeded, padding valid", "length", len(decrypted))
+	if err := verifyChecksum(decrypted, symkey); err != nil {
+		zero(decrypted)
+		return nil, nil, err
 	}
-	return key, kdfParams, nil
+	return decrypted, kdfParams, nil
+}
+
+// variableKeyCipher is implemented by ciphers that accept keys of several
+// sizes, such as RC2. keySizes returns the smallest and largest key size in
+// bytes.
+type variableKeyCipher interface {
+	keySizes() (min, max int)
+}
+
+// derivedKeySize returns the size of the key to derive for cipher c. The
+// PBKDF2 keyLength is attacker-controlled: it may only select the key size
+// of a variable-key cipher, within the sizes the cipher accepts, and must
+// otherwise equal the key size of c.
+func derivedKeySize(c Cipher, kdfParams KDFParameters) (int, error) {
+	p, ok := kdfParams.(*pbkdf2Params)
+	if !ok || p.KeyLength == 0 {
+		return c.KeySize(), nil
+	}
+	if v, ok := c.(variableKeyCipher); ok {
+		if min, max := v.keySizes(); p.KeyLength >= min && p.KeyLength <= max {
+			return p.KeyLength, nil
+		}
+	} else if p.KeyLength == c.KeySize() {
+		return p.KeyLength, nil
+	}
+	return 0, fmt.Errorf("pkcs8: invalid PBKDF2 key length %d for cipher %s", p.KeyLength, OIDName(c.OID()))
 }
 
 // MarshalPrivateKey encodes a private key into DER-encoded PKCS#8 with the given options.
 // Password can be nil.
 func MarshalPrivateKey(priv interface{}, password []byte, opts *Opts) ([]byte, error) {
+	// Convert private key into PKCS8 format
+	pkey, err := marshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := priv.(*rsa.PrivateKey); ok && opts != nil {
+		switch {
+		case opts.RSAPSS != nil:
+			pkey, err = setPSSAlgorithm(pkey, opts.RSAPSS)
+		case opts.OmitRSANullParameters:
+			pkey, err = omitAlgorithmParameters(pkey)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encryptPrivateKeyInfo(pkey, password, opts)
+}
+
+// omitAlgorithmParameters removes the parameters of the AlgorithmIdentifier
+// of a DER-encoded PrivateKeyInfo.
+func omitAlgorithmParameters(pkey []byte) ([]byte, error) {
+	var pki privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &pki); err != nil {
+		return nil, err
+	}
+	pki.PrivateKeyAlgorithm.Parameters = asn1.RawValue{}
+	return asn1.Marshal(pki)
+}
+
+// ReEncrypt decrypts a DER-encoded PKCS#8 private key with oldPassword and
+// encrypts it again with newPassword and the given options. Attributes and
+// the public key stored alongside the private key are preserved.
+// Either password can be nil.
+func ReEncrypt(der []byte, oldPassword, newPassword []byte, opts *Opts) ([]byte, error) {
+	return ReEncryptWithOpts(der, oldPassword, newPassword, nil, opts)
+}
+
+// ReEncryptWithOpts is ReEncrypt with options for decrypting der, e.g.
+// ParseOpts.AllowLegacy to migrate keys off legacy algorithms. parseOpts
+// can be nil.
+func ReEncryptWithOpts(der []byte, oldPassword, newPassword []byte, parseOpts *ParseOpts, opts *Opts) ([]byte, error) {
+	pkey, kdfParams, err := decryptPrivateKeyInfo(der, oldPassword, parseOpts)
+	if err != nil {
+		return nil, err
+	}
+	if kdfParams != nil && len(newPassword) != 0 {
+		// Without a new password, pkey itself is returned.
+		defer zero(pkey)
+	}
+	normalized, _, changed := normalizeKeyAlgorithm(pkey)
+	if changed {
+		defer zero(normalized)
+	}
+	if _, err := parsePKCS8PrivateKey(normalized); err != nil {
+		if kdfParams != nil {
+			return nil, errIncorrectPassword(der, pkey)
+		}
+		return nil, err
+	}
+	return encryptPrivateKeyInfo(pkey, newPassword, opts)
+}
+
+// encryptPrivateKeyInfo encrypts a DER-encoded PrivateKeyInfo into an
+// EncryptedPrivateKeyInfo. If password is empty, pkey is returned as is.
+// A checksum attribute left by a previous encryption is removed.
+func encryptPrivateKeyInfo(pkey []byte, password []byte, opts *Opts) ([]byte, error) {
 	if len(password) == 0 {
-		return x509.MarshalPKCS8PrivateKey(priv)
+		return pkey, nil
 	}
 
 	if opts == nil {
-		opts = DefaultOpts
+		opts = defaultMarshalOpts()
+	}
+	if !opts.Checksum && hasChecksum(pkey) {
+		stripped, err := removeChecksum(pkey)
+		if err != nil {
+			return nil, err
+		}
+		defer zero(stripped)
+		pkey = stripped
 	}
 
-	// Convert private key into PKCS8 format
-	pkey, err := x509.MarshalPKCS8PrivateKey(priv)
+	encryptedPkey, err := encryptPBES2(pkey, opts.Checksum, password, opts)
 	if err != nil {
 		return nil, err
 	}
+	return asn1.Marshal(*encryptedPkey)
+}
 
+// encryptPBES2 encrypts data with a PBES2 encryption scheme built from opts.
+// If checksum is set, data must be a PrivateKeyInfo, to which a checksum
+// attribute is added.
+func encryptPBES2(data []byte, checksum bool, password []byte, opts *Opts) (*encryptedPrivateKeyInfo, error) {
 	encAlg := opts.Cipher
+	if gcm, ok := encAlg.(cipherWithGCM); ok && opts.GCMNonceSize != 0 {
+		var err error
+		if encAlg, err = gcm.withNonceSize(opts.GCMNonceSize); err != nil {
+			return nil, err
+		}
+	}
+	random := opts.rand()
 	salt := make([]byte, opts.KDFOpts.GetSaltSize())
-	_, err = rand.Read(salt)
+	_, err := io.ReadFull(random, salt)
 	if err != nil {
 		return nil, err
 	}
 	iv := make([]byte, encAlg.IVSize())
-	_, err = rand.Read(iv)
+	_, err = io.ReadFull(random, iv)
 	if err != nil {
 		return nil, err
 	}
@@ -216,10 +824,23 @@ func MarshalPrivateKey(priv interface{}, password []byte, opts *Opts) ([]byte, e
 	if err != nil {
 		return nil, err
 	}
+	defer zero(key)
+	if p, ok := kdfParams.(pbkdf2Params); ok {
+		if opts.Compat == CompatOpenSSL && p.PRF.Algorithm.Equal(oidHMACWithSHA1) {
+			p.PRF = pkix.AlgorithmIdentifier{}
+		}
+		// The key size of RC2 is not implied by its OID.
+		if encAlg.OID().Equal(oidRC2CBC) {
+			p.KeyLength = encAlg.KeySize()
+		}
+		kdfParams = p
+	}
 
-	encryptedKey, err := encAlg.Encrypt(key, iv, pkey)
-	if err != nil {
-		return nil, err
+	if checksum {
+		if data, err = addChecksum(data, key); err != nil {
+			return nil, err
+		}
+		defer zero(data)
 	}
 
 	marshalledParams, err := asn1.Marshal(kdfParams)
@@ -230,13 +851,13 @@ func MarshalPrivateKey(priv interface{}, password []byte, opts *Opts) ([]byte, e
 		Algorithm:  opts.KDFOpts.OID(),
 		Parameters: asn1.RawValue{FullBytes: marshalledParams},
 	}
-	marshalledIV, err := asn1.Marshal(iv)
+	marshalledCipherParams, err := marshalCipherParameters(encAlg, iv)
 	if err != nil {
 		return nil, err
 	}
 	encryptionScheme := pkix.AlgorithmIdentifier{
 		Algorithm:  encAlg.OID(),
-		Parameters: asn1.RawValue{FullBytes: marshalledIV},
+		Parameters: asn1.RawValue{FullBytes: marshalledCipherParams},
 	}
 
 	encryptionAlgorithmParams := pbes2Params{
@@ -252,58 +873,29 @@ func MarshalPrivateKey(priv interface{}, password []byte, opts *Opts) ([]byte, e
 		Parameters: asn1.RawValue{FullBytes: marshalledEncryptionAlgorithmParams},
 	}
 
-	encryptedPkey := encryptedPrivateKeyInfo{
-		EncryptionAlgorithm: encryptionAlgorithm,
-		EncryptedData:       encryptedKey,
-	}
-
-	return asn1.Marshal(encryptedPkey)
-}
-
-// ParsePKCS8PrivateKey parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
-func ParsePKCS8PrivateKey(der []byte, v ...[]byte) (interface{}, error) {
-	var password []byte
-	if len(v) > 0 {
-		password = v[0]
+	var encryptedKey []byte
+	if a, ok := encAlg.(aeadCipher); ok && opts.BindParameters {
+		var aad []byte
+		if aad, err = asn1.Marshal(encryptionAlgorithm); err != nil {
+			return nil, err
+		}
+		encryptedKey, err = a.encryptWithAAD(key, iv, data, aad)
+	} else {
+		encryptedKey, err = encAlg.Encrypt(key, iv, data)
 	}
-	privateKey, _, err := ParsePrivateKey(der, password)
-	return privateKey, err
-}
-
-// ParsePKCS8PrivateKeyRSA parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
-func ParsePKCS8PrivateKeyRSA(der []byte, v ...[]byte) (*rsa.PrivateKey, error) {
-	key, err := ParsePKCS8PrivateKey(der, v...)
 	if err != nil {
 		return nil, err
 	}
-	typedKey, ok := key.(*rsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("key block is not of type RSA")
-	}
-	return typedKey, nil
-}
 
-// ParsePKCS8PrivateKeyECDSA parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
-func ParsePKCS8PrivateKeyECDSA(der []byte, v ...[]byte) (*ecdsa.PrivateKey, error) {
-	key, err := ParsePKCS8PrivateKey(der, v...)
-	if err != nil {
-		return nil, err
-	}
-	typedKey, ok := key.(*ecdsa.PrivateKey)
-	if !ok {
-		return nil, errors.New("key block is not of type ECDSA")
-	}
-	return typedKey, nil
+	return &encryptedPrivateKeyInfo{
+		EncryptionAlgorithm: encryptionAlgorithm,
+		EncryptedData:       encryptedKey,
+	}, nil
 }
 
-// ConvertPrivateKeyToPKCS8 converts the private key into PKCS#8 format.
-// To encrypt the private key, the password of []byte type should be provided as the second parameter.
-//
-// The only supported key types are RSA and ECDSA (*rsa.PrivateKey or *ecdsa.PrivateKey for priv)
-func ConvertPrivateKeyToPKCS8(priv interface{}, v ...[]byte) ([]byte, error) {
-	var password []byte
-	if len(v) > 0 {
-		password = v[0]
+// zero overwrites b, which held key material, with zeros.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
 	}
-	return MarshalPrivateKey(priv, password, nil)
 }
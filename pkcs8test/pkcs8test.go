@@ -0,0 +1,162 @@
+// Package pkcs8test provides helpers to test code built on package pkcs8,
+// such as custom KDFs and ciphers, with the round-trip properties package
+// pkcs8 tests itself with.
+package pkcs8test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/quick"
+
+	"github.com/youmark/pkcs8"
+)
+
+// RoundTrip encrypts key with opts, decrypts it again and checks that the
+// result matches key and that a wrong password is refused. Ciphers and KDFs
+// in opts must be registered for parsing.
+func RoundTrip(key crypto.Signer, opts *pkcs8.Opts) error {
+	password := []byte("pkcs8test password")
+	der, err := pkcs8.MarshalPrivateKey(key, password, opts)
+	if err != nil {
+		return fmt.Errorf("MarshalPrivateKey returned: %w", err)
+	}
+	decoded, _, err := pkcs8.ParsePrivateKey(der, password)
+	if err != nil {
+		return fmt.Errorf("ParsePrivateKey returned: %w", err)
+	}
+	equal, ok := key.(interface{ Equal(crypto.PrivateKey) bool })
+	if !ok {
+		return fmt.Errorf("key of type %T cannot be compared", key)
+	}
+	if !equal.Equal(decoded) {
+		return errors.New("decoded key does not match original key")
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(der, []byte("wrong password")); err == nil {
+		return errors.New("ParsePrivateKey accepted a wrong password")
+	}
+	return nil
+}
+
+// AssertRoundTrip reports a test failure if RoundTrip fails.
+func AssertRoundTrip(t testing.TB, key crypto.Signer, opts *pkcs8.Opts) {
+	t.Helper()
+	if err := RoundTrip(key, opts); err != nil {
+		t.Errorf("round trip of %T failed: %s", key, err)
+	}
+}
+
+// CheckCipher checks the RoundTrip property for random keys encrypted with
+// cipher and a fast PBKDF2 configuration.
+func CheckCipher(t testing.TB, cipher pkcs8.Cipher) {
+	t.Helper()
+	property := func(key Key) bool {
+		opts := &pkcs8.Opts{
+			Cipher:  cipher,
+			KDFOpts: pkcs8.PBKDF2Opts{SaltSize: 16, IterationCount: 16, HMACHash: crypto.SHA256},
+		}
+		return noError(t, RoundTrip(key.Signer, opts))
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
+
+// CheckKDF checks the RoundTrip property for random keys encrypted with
+// AES-256-CBC and kdf.
+func CheckKDF(t testing.TB, kdf pkcs8.KDFOpts) {
+	t.Helper()
+	property := func(key Key) bool {
+		opts := &pkcs8.Opts{Cipher: pkcs8.AES256CBC, KDFOpts: kdf}
+		return noError(t, RoundTrip(key.Signer, opts))
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
+
+// noError reports err as a test failure and returns whether it was nil.
+func noError(t testing.TB, err error) bool {
+	t.Helper()
+	if err != nil {
+		t.Error(err)
+		return false
+	}
+	return true
+}
+
+// Key is a testing/quick generator of private keys: RSA, ECDSA on every NIST
+// curve supported by crypto/elliptic, and Ed25519.
+type Key struct {
+	Signer crypto.Signer
+}
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PrivateKey
+)
+
+// Generate implements quick.Generator. A single RSA key is generated per
+// process, RSA key generation being slow.
+func (Key) Generate(r *rand.Rand, size int) reflect.Value {
+	var key crypto.Signer
+	switch r.Intn(6) {
+	case 0:
+		rsaKeyOnce.Do(func() {
+			var err error
+			if rsaKey, err = rsa.GenerateKey(cryptorand.Reader, 2048); err != nil {
+				panic(err)
+			}
+		})
+		key = rsaKey
+	case 1, 2, 3, 4:
+		curve := []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()}[r.Intn(4)]
+		ecKey, err := ecdsa.GenerateKey(curve, cryptorand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		key = ecKey
+	default:
+		seed := make([]byte, ed25519.SeedSize)
+		r.Read(seed)
+		key = ed25519.NewKeyFromSeed(seed)
+	}
+	return reflect.ValueOf(Key{key})
+}
+
+// Opts is a testing/quick generator of encryption options using the
+// built-in ciphers and KDFs, with parameters small enough for tests.
+type Opts struct {
+	Opts *pkcs8.Opts
+}
+
+// Generate implements quick.Generator.
+func (Opts) Generate(r *rand.Rand, size int) reflect.Value {
+	ciphers := []pkcs8.Cipher{pkcs8.AES128CBC, pkcs8.AES192CBC, pkcs8.AES256CBC, pkcs8.TripleDESCBC}
+	opts := &pkcs8.Opts{Cipher: ciphers[r.Intn(len(ciphers))]}
+	saltSize := 8 + r.Intn(25)
+	kdfs := 2
+	if hasScrypt {
+		kdfs++
+	}
+	switch r.Intn(kdfs) {
+	case 0:
+		opts.KDFOpts = pkcs8.PBKDF2Opts{SaltSize: saltSize, IterationCount: 1 + r.Intn(64), HMACHash: crypto.SHA1}
+	case 1:
+		opts.KDFOpts = pkcs8.PBKDF2Opts{SaltSize: saltSize, IterationCount: 1 + r.Intn(64), HMACHash: crypto.SHA256}
+	default:
+		opts.KDFOpts = pkcs8.ScryptOpts{
+			SaltSize: saltSize, CostParameter: 1 << uint(1+r.Intn(4)), BlockSize: 1 + r.Intn(8), ParallelizationParameter: 1,
+		}
+	}
+	return reflect.ValueOf(Opts{opts})
+}
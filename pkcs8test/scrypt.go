@@ -0,0 +1,8 @@
+//go:build !pkcs8_noscrypt
+// +build !pkcs8_noscrypt
+
+package pkcs8test
+
+// hasScrypt reports whether package pkcs8 is built with scrypt, so that Opts
+// generates scrypt options.
+const hasScrypt = true
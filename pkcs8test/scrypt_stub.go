@@ -0,0 +1,8 @@
+//go:build pkcs8_noscrypt
+// +build pkcs8_noscrypt
+
+package pkcs8test
+
+// hasScrypt is false: package pkcs8 is built with the pkcs8_noscrypt tag,
+// and Opts generates PBKDF2 options only.
+const hasScrypt = false
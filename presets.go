@@ -0,0 +1,53 @@
+package pkcs8
+
+import "crypto"
+
+// OpenSSL3DefaultOpts returns the options used by `openssl pkcs8 -topk8`
+// in OpenSSL 3: AES-256-CBC with PBKDF2-HMAC-SHA256, 2048 iterations and an
+// 8-byte salt. Keys encrypted with them are byte-identical to those produced
+// by OpenSSL given the same salt and IV, as golden tests require; they are
+// much cheaper to brute-force than DefaultOpts.
+func OpenSSL3DefaultOpts() *Opts {
+	return &Opts{
+		Cipher: AES256CBC,
+		KDFOpts: PBKDF2Opts{
+			SaltSize:       8,
+			IterationCount: 2048,
+			HMACHash:       crypto.SHA256,
+		},
+		Compat: CompatOpenSSL,
+	}
+}
+
+// OpenSSLScryptOpts returns the options used by `openssl pkcs8 -topk8
+// -scrypt`: AES-256-CBC with scrypt, N=16384, r=8, p=1 and an 8-byte salt.
+// Keys encrypted with them cannot be told apart from those produced by
+// OpenSSL.
+func OpenSSLScryptOpts() *Opts {
+	return &Opts{
+		Cipher: AES256CBC,
+		KDFOpts: ScryptOpts{
+			SaltSize:                 8,
+			CostParameter:            1 << 14,
+			BlockSize:                8,
+			ParallelizationParameter: 1,
+		},
+		Compat: CompatOpenSSL,
+	}
+}
+
+// LegacyDefaults returns the default options of earlier releases:
+// AES-256-CBC with PBKDF2-HMAC-SHA256, 10,000 iterations and an 8-byte salt.
+// They are much cheaper to brute-force than DefaultOpts and should only be
+// used when the keys must be read by consumers that cannot handle the
+// current defaults.
+func LegacyDefaults() *Opts {
+	return &Opts{
+		Cipher: AES256CBC,
+		KDFOpts: PBKDF2Opts{
+			SaltSize:       8,
+			IterationCount: 10000,
+			HMACHash:       crypto.SHA256,
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+var errPublicKeyMismatch = errors.New("pkcs8: embedded public key does not match private key")
+
+// Public returns the public key of a private key returned by the parse
+// functions, or nil if priv has no Public method and is not a DSA key.
+func Public(priv crypto.PrivateKey) crypto.PublicKey {
+	if k, ok := priv.(*dsa.PrivateKey); ok {
+		return &k.PublicKey
+	}
+	if k, ok := priv.(interface{ Public() crypto.PublicKey }); ok {
+		return k.Public()
+	}
+	return nil
+}
+
+// subjectPublicKeyInfo is the SubjectPublicKeyInfo structure of RFC 5280.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// ParsePublicKey parses a DER-encoded SubjectPublicKeyInfo. It accepts the
+// keys crypto/x509.ParsePKIXPublicKey does, EC keys whose algorithm is
+// id-ecDH or id-ecMQV, whose restriction is dropped as ParsePrivateKey does,
+// keys whose algorithm parameters are absent or NULL where
+// crypto/x509 requires the other form, and the key types the package adds,
+// such as Ed448.
+func ParsePublicKey(der []byte) (crypto.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err == nil && len(rest) == 0 {
+		restricted := spki.Algorithm.Algorithm.Equal(oidECDH) || spki.Algorithm.Algorithm.Equal(oidECMQV)
+		if restricted {
+			spki.Algorithm.Algorithm = oidPublicKeyECDSA
+		}
+		if !normalizeParameters(&spki.Algorithm) && !restricted {
+			return parsePKIXPublicKey(der)
+		}
+		normalized, err := asn1.Marshal(spki)
+		if err != nil {
+			return nil, err
+		}
+		return parsePKIXPublicKey(normalized)
+	}
+	return parsePKIXPublicKey(der)
+}
+
+// MarshalPublicKey encodes a public key of any type returned by
+// ParsePublicKey into a DER-encoded SubjectPublicKeyInfo.
+func MarshalPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	return marshalPKIXPublicKey(pub)
+}
+
+// ecPrivateKey is the ECPrivateKey structure of RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// verifyEmbeddedPublicKey checks that the public keys stored in a
+// DER-encoded PrivateKeyInfo, either in the OneAsymmetricKey publicKey field
+// or in the publicKey field of an ECPrivateKey, match the parsed private key.
+func verifyEmbeddedPublicKey(der []byte, key interface{}) error {
+	var info privateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return errors.New("pkcs8: invalid PrivateKeyInfo")
+	}
+	pub := Public(key)
+	if pub == nil {
+		return nil
+	}
+	if info.PublicKey.BitLength > 0 {
+		if err := checkPublicKeyBytes(pub, info.PublicKey.Bytes); err != nil {
+			return err
+		}
+	}
+	if priv, ok := key.(*ecdsa.PrivateKey); ok {
+		var ec ecPrivateKey
+		if _, err := asn1.Unmarshal(info.PrivateKey, &ec); err != nil {
+			return errors.New("pkcs8: invalid EC private key")
+		}
+		if ec.PublicKey.BitLength > 0 {
+			return checkPublicKeyBytes(&priv.PublicKey, ec.PublicKey.Bytes)
+		}
+	}
+	return nil
+}
+
+// checkPublicKeyBytes compares pub with the contents of a subjectPublicKey
+// BIT STRING. EC points may be compressed or not.
+func checkPublicKeyBytes(pub crypto.PublicKey, embedded []byte) error {
+	if ecPub, ok := pub.(*ecdsa.PublicKey); ok {
+		x, y := elliptic.Unmarshal(ecPub.Curve, embedded)
+		if x == nil {
+			x, y = elliptic.UnmarshalCompressed(ecPub.Curve, embedded)
+		}
+		if x == nil || x.Cmp(ecPub.X) != 0 || y.Cmp(ecPub.Y) != 0 {
+			return errPublicKeyMismatch
+		}
+		return nil
+	}
+	spki, err := marshalPKIXPublicKey(pub)
+	if err != nil {
+		// Unknown key type, nothing to compare with.
+		return nil
+	}
+	var parsed subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(spki, &parsed); err != nil {
+		return err
+	}
+	if !bytes.Equal(parsed.PublicKey.Bytes, embedded) {
+		return errPublicKeyMismatch
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+//go:build !pkcs8_nolegacy
+// +build !pkcs8_nolegacy
+
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rc4"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// PVK key specs, the dwKeySpec of the key container the key is imported
+// into.
+const (
+	PVKKeyExchange = 1
+	PVKSignature   = 2
+)
+
+const (
+	pvkMagic          = 0xb0b5f11e
+	pvkHeaderSize     = 24
+	pvkSaltSize       = 16
+	pvkPrivateKeyBlob = 0x07
+	pvkBlobHeaderSize = 8
+	pvkRSA2Magic      = 0x32415352 // "RSA2"
+	pvkCALGRSASign    = 0x2400
+	pvkCALGRSAKeyx    = 0xa400
+)
+
+// PVKOpts contains options for writing a PVK file.
+type PVKOpts struct {
+	// KeySpec is PVKKeyExchange or PVKSignature. The default is
+	// PVKKeyExchange, as written by OpenSSL.
+	KeySpec uint32
+	// Weak selects the 40-bit RC4 key of old exports instead of the
+	// 128-bit one. Only needed by tools that cannot read anything else.
+	Weak bool
+}
+
+// ParsePVK parses a Microsoft PVK file holding an RSA private key, as used
+// with old Authenticode tools. If the key is encrypted, both the 128-bit
+// and the 40-bit RC4 key derived from password are tried.
+//
+// PVK encryption is RC4 keyed with a single SHA-1 of the salt and password,
+// without integrity protection; convert such keys to encrypted PKCS#8 with
+// ConvertPVKToPKCS8.
+func ParsePVK(data []byte, password []byte) (crypto.PrivateKey, error) {
+	if len(data) < pvkHeaderSize {
+		return nil, errors.New("pkcs8: PVK file too short")
+	}
+	if binary.LittleEndian.Uint32(data[0:]) != pvkMagic {
+		return nil, errors.New("pkcs8: not a PVK file")
+	}
+	encrypted := binary.LittleEndian.Uint32(data[12:]) != 0
+	saltLen := binary.LittleEndian.Uint32(data[16:])
+	keyLen := binary.LittleEndian.Uint32(data[20:])
+	if uint64(len(data)) != pvkHeaderSize+uint64(saltLen)+uint64(keyLen) || keyLen < pvkBlobHeaderSize+12 {
+		return nil, errors.New("pkcs8: invalid PVK lengths")
+	}
+	salt := data[pvkHeaderSize : pvkHeaderSize+saltLen]
+	blob := data[pvkHeaderSize+saltLen:]
+	if !encrypted {
+		return parsePrivateKeyBlob(blob)
+	}
+	if password == nil {
+		return nil, errors.New("pkcs8: password required for encrypted key")
+	}
+	for _, weak := range []bool{false, true} {
+		decrypted := append([]byte(nil), blob...)
+		if err := pvkCrypt(decrypted, salt, password, weak); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(decrypted[pvkBlobHeaderSize:]) == pvkRSA2Magic {
+			return parsePrivateKeyBlob(decrypted)
+		}
+	}
+	return nil, errors.New("pkcs8: incorrect password")
+}
+
+// MarshalPVK encodes an RSA private key as a Microsoft PVK file. If password
+// is nil, the key is not encrypted. Opts can be nil.
+func MarshalPVK(priv interface{}, password []byte, opts *PVKOpts) ([]byte, error) {
+	key, ok := priv.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported key type %T for PVK", priv)
+	}
+	if opts == nil {
+		opts = &PVKOpts{}
+	}
+	keySpec := opts.KeySpec
+	if keySpec == 0 {
+		keySpec = PVKKeyExchange
+	}
+	alg := uint32(pvkCALGRSAKeyx)
+	switch keySpec {
+	case PVKKeyExchange:
+	case PVKSignature:
+		alg = pvkCALGRSASign
+	default:
+		return nil, fmt.Errorf("pkcs8: invalid PVK key spec %d", keySpec)
+	}
+	blob, err := marshalPrivateKeyBlob(key, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	var salt []byte
+	if password != nil {
+		salt = make([]byte, pvkSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		if err := pvkCrypt(blob, salt, password, opts.Weak); err != nil {
+			return nil, err
+		}
+	}
+	var encrypted uint32
+	if password != nil {
+		encrypted = 1
+	}
+	header := make([]byte, pvkHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], pvkMagic)
+	binary.LittleEndian.PutUint32(header[8:], keySpec)
+	binary.LittleEndian.PutUint32(header[12:], encrypted)
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(salt)))
+	binary.LittleEndian.PutUint32(header[20:], uint32(len(blob)))
+	return append(append(header, salt...), blob...), nil
+}
+
+// ConvertPVKToPKCS8 decrypts a PVK file with pvkPassword and encrypts the
+// key as PKCS#8 with password and opts.
+func ConvertPVKToPKCS8(pvk []byte, pvkPassword, password []byte, opts *Opts) ([]byte, error) {
+	priv, err := ParsePVK(pvk, pvkPassword)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalPrivateKey(priv, password, opts)
+}
+
+// ConvertPKCS8ToPVK decrypts a PKCS#8 RSA key with password and encodes it
+// as a PVK file encrypted with pvkPassword.
+func ConvertPKCS8ToPVK(der []byte, password, pvkPassword []byte, opts *PVKOpts) ([]byte, error) {
+	priv, _, err := ParsePrivateKey(der, password)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalPVK(priv, pvkPassword, opts)
+}
+
+// pvkCrypt encrypts or decrypts the key blob in place, leaving its
+// BLOBHEADER in the clear. The RC4 key is the SHA-1 of the salt and the
+// password, truncated to 128 bits, or to 40 bits padded with zeros if weak.
+func pvkCrypt(blob, salt, password []byte, weak bool) error {
+	h := sha1.New()
+	h.Write(salt)
+	h.Write(password)
+	key := h.Sum(nil)[:16]
+	if weak {
+		for i := 5; i < len(key); i++ {
+			key[i] = 0
+		}
+	}
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	c.XORKeyStream(blob[pvkBlobHeaderSize:], blob[pvkBlobHeaderSize:])
+	return nil
+}
+
+// parsePrivateKeyBlob parses an RSA PRIVATEKEYBLOB: a BLOBHEADER, an
+// RSAPUBKEY and the key values as little-endian integers.
+func parsePrivateKeyBlob(blob []byte) (*rsa.PrivateKey, error) {
+	if blob[0] != pvkPrivateKeyBlob {
+		return nil, errors.New("pkcs8: PVK file does not hold a private key")
+	}
+	if alg := binary.LittleEndian.Uint32(blob[4:]); alg != pvkCALGRSASign && alg != pvkCALGRSAKeyx {
+		return nil, fmt.Errorf("pkcs8: unsupported PVK key algorithm 0x%x", alg)
+	}
+	if binary.LittleEndian.Uint32(blob[8:]) != pvkRSA2Magic {
+		return nil, errors.New("pkcs8: invalid PVK RSA key")
+	}
+	bitLen := int(binary.LittleEndian.Uint32(blob[12:]))
+	exponent := binary.LittleEndian.Uint32(blob[16:])
+	n, half := (bitLen+7)/8, (bitLen+15)/16
+	if bitLen == 0 || len(blob) != pvkBlobHeaderSize+12+2*n+5*half || exponent > 1<<31-1 {
+		return nil, errors.New("pkcs8: invalid PVK RSA key")
+	}
+	rest := blob[pvkBlobHeaderSize+12:]
+	next := func(size int) *big.Int {
+		v := littleEndianInt(rest[:size])
+		rest = rest[size:]
+		return v
+	}
+	key := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: next(n), E: int(exponent)}}
+	p, q := next(half), next(half)
+	// The CRT values are recomputed from the primes.
+	next(3 * half)
+	key.D = next(n)
+	key.Primes = []*big.Int{p, q}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	key.Precompute()
+	return key, nil
+}
+
+func marshalPrivateKeyBlob(key *rsa.PrivateKey, alg uint32) ([]byte, error) {
+	if len(key.Primes) != 2 {
+		return nil, errors.New("pkcs8: PVK only supports two-prime RSA keys")
+	}
+	if key.E > 1<<31-1 {
+		return nil, errors.New("pkcs8: RSA public exponent too large for PVK")
+	}
+	bitLen := key.N.BitLen()
+	n, half := (bitLen+7)/8, (bitLen+15)/16
+	p, q := key.Primes[0], key.Primes[1]
+	one := big.NewInt(1)
+	dp := new(big.Int).Mod(key.D, new(big.Int).Sub(p, one))
+	dq := new(big.Int).Mod(key.D, new(big.Int).Sub(q, one))
+	qinv := new(big.Int).ModInverse(q, p)
+	if qinv == nil {
+		return nil, errors.New("pkcs8: invalid RSA primes")
+	}
+
+	var buf bytes.Buffer
+	header := make([]byte, pvkBlobHeaderSize+12)
+	header[0] = pvkPrivateKeyBlob
+	header[1] = 2
+	binary.LittleEndian.PutUint32(header[4:], alg)
+	binary.LittleEndian.PutUint32(header[8:], pvkRSA2Magic)
+	binary.LittleEndian.PutUint32(header[12:], uint32(bitLen))
+	binary.LittleEndian.PutUint32(header[16:], uint32(key.E))
+	buf.Write(header)
+	for _, v := range []struct {
+		x    *big.Int
+		size int
+	}{{key.N, n}, {p, half}, {q, half}, {dp, half}, {dq, half}, {qinv, half}, {key.D, n}} {
+		b, err := littleEndianBytes(v.x, v.size)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// littleEndianInt decodes a little-endian unsigned integer.
+func littleEndianInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// littleEndianBytes encodes x as a size-byte little-endian integer.
+func littleEndianBytes(x *big.Int, size int) ([]byte, error) {
+	be := x.Bytes()
+	if len(be) > size {
+		return nil, errors.New("pkcs8: RSA key value too large for PVK")
+	}
+	b := make([]byte, size)
+	for i := range be {
+		b[i] = be[len(be)-1-i]
+	}
+	return b, nil
+}
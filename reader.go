@@ -0,0 +1,39 @@
+package pkcs8
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ParseFrom reads a private key from r, in any form accepted by
+// ParsePrivateKeyPEM, and parses it with opts. Password and opts can be nil.
+//
+// At most limit bytes are read: a larger input is refused before any of it
+// is parsed, so that services accepting uploaded key files cannot be made to
+// buffer arbitrary amounts of data. A small file can still describe an
+// expensive key derivation: key lengths the cipher does not accept are
+// refused before any key is derived, but iteration counts are not bounded,
+// see EstimateKDFCost. The bytes read are zeroed before ParseFrom returns.
+func ParseFrom(r io.Reader, limit int64, password []byte, opts *ParseOpts) (crypto.PrivateKey, KDFParameters, error) {
+	if limit <= 0 {
+		return nil, nil, errors.New("pkcs8: read limit must be positive")
+	}
+	// Read one byte more than allowed to tell a file of exactly limit bytes
+	// from a larger one.
+	n := limit
+	if n < math.MaxInt64 {
+		n++
+	}
+	data, err := io.ReadAll(io.LimitReader(r, n))
+	defer zero(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, nil, fmt.Errorf("pkcs8: key file larger than %d bytes", limit)
+	}
+	return parsePrivateKeyPEM(data, password, opts)
+}
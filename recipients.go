@@ -0,0 +1,285 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// Recipient is one of the credentials able to unlock a multi-recipient key.
+type Recipient struct {
+	// Label identifies the recipient, e.g. "ops" or "ci". It is stored in
+	// clear text.
+	Label string
+	// Password is the passphrase of the recipient.
+	Password []byte
+	// Opts are the options used to wrap the content-encryption key for this
+	// recipient. DefaultOpts are used if nil.
+	Opts *Opts
+}
+
+// multiRecipientKey holds a private key encrypted under a random
+// content-encryption key, which is in turn wrapped once per recipient.
+type multiRecipientKey struct {
+	Version                    int
+	Recipients                 []recipientInfo
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte
+}
+
+type recipientInfo struct {
+	Label        string `asn1:"utf8"`
+	EncryptedKey encryptedPrivateKeyInfo
+}
+
+// MarshalMultiRecipientPrivateKey encrypts a private key so that it can be
+// decrypted with the password of any of the given recipients.
+// The key itself is encrypted once with a random content-encryption key using
+// cipher, or the cipher of DefaultOpts if cipher is nil. The content-encryption
+// key is then wrapped with PBES2 for every recipient, whose labels must be
+// distinct.
+func MarshalMultiRecipientPrivateKey(priv interface{}, recipients []Recipient, cipher Cipher) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("pkcs8: at least one recipient is required")
+	}
+	if cipher == nil {
+		cipher = defaultMarshalOpts().Cipher
+	}
+
+	pkey, err := marshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(pkey)
+
+	cek := make([]byte, cipher.KeySize())
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	defer zero(cek)
+	iv := make([]byte, cipher.IVSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	encryptedContent, err := cipher.Encrypt(cek, iv, pkey)
+	if err != nil {
+		return nil, err
+	}
+	marshalledIV, err := marshalCipherParameters(cipher, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	container := multiRecipientKey{
+		ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  cipher.OID(),
+			Parameters: asn1.RawValue{FullBytes: marshalledIV},
+		},
+		EncryptedContent: encryptedContent,
+	}
+	for _, r := range recipients {
+		if len(r.Password) == 0 {
+			return nil, errors.New("pkcs8: recipient password must not be empty")
+		}
+		if err := container.checkLabel(r.Label); err != nil {
+			return nil, err
+		}
+		opts := r.Opts
+		if opts == nil {
+			opts = defaultMarshalOpts()
+		}
+		wrapped, err := encryptPBES2(cek, false, r.Password, opts)
+		if err != nil {
+			return nil, err
+		}
+		container.Recipients = append(container.Recipients, recipientInfo{
+			Label:        r.Label,
+			EncryptedKey: *wrapped,
+		})
+	}
+	return asn1.Marshal(container)
+}
+
+// ParseMultiRecipientPrivateKey decrypts a key created by
+// MarshalMultiRecipientPrivateKey with the password of any of its recipients.
+// It returns the private key and the label of the recipient it was unlocked for.
+func ParseMultiRecipientPrivateKey(der []byte, password []byte) (crypto.PrivateKey, string, error) {
+	return ParseMultiRecipientPrivateKeyWithOpts(der, password, nil)
+}
+
+// ParseMultiRecipientPrivateKeyWithOpts is like ParseMultiRecipientPrivateKey
+// with the given options, which apply to both the content encryption and the
+// wrapping of the content-encryption key. Opts can be nil.
+func ParseMultiRecipientPrivateKeyWithOpts(der []byte, password []byte, opts *ParseOpts) (crypto.PrivateKey, string, error) {
+	container, err := parseMultiRecipientKey(der)
+	if err != nil {
+		return nil, "", err
+	}
+	key, cek, label, err := container.unlock(password, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	zero(cek)
+	return key, label, nil
+}
+
+// AddMultiRecipient adds a recipient to a key created by
+// MarshalMultiRecipientPrivateKey. The password of an existing recipient is
+// needed to unwrap the content-encryption key, under the given options, which
+// can be nil; the encrypted key itself is left untouched. The label of the
+// new recipient must not be in use.
+func AddMultiRecipient(der []byte, password []byte, recipient Recipient, opts *ParseOpts) ([]byte, error) {
+	container, err := parseMultiRecipientKey(der)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipient.Password) == 0 {
+		return nil, errors.New("pkcs8: recipient password must not be empty")
+	}
+	if err := container.checkLabel(recipient.Label); err != nil {
+		return nil, err
+	}
+	_, cek, _, err := container.unlock(password, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(cek)
+	wrapOpts := recipient.Opts
+	if wrapOpts == nil {
+		wrapOpts = defaultMarshalOpts()
+	}
+	wrapped, err := encryptPBES2(cek, false, recipient.Password, wrapOpts)
+	if err != nil {
+		return nil, err
+	}
+	container.Recipients = append(container.Recipients, recipientInfo{
+		Label:        recipient.Label,
+		EncryptedKey: *wrapped,
+	})
+	return asn1.Marshal(*container)
+}
+
+// RemoveMultiRecipient removes the recipients with the given label from a key
+// created by MarshalMultiRecipientPrivateKey. No password is needed. Note that
+// a removed recipient who kept a copy of the content-encryption key, or of an
+// earlier version of the file, can still decrypt the key.
+func RemoveMultiRecipient(der []byte, label string) ([]byte, error) {
+	container, err := parseMultiRecipientKey(der)
+	if err != nil {
+		return nil, err
+	}
+	var kept []recipientInfo
+	for _, r := range container.Recipients {
+		if r.Label != label {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(container.Recipients) {
+		return nil, fmt.Errorf("pkcs8: no recipient labelled %q", label)
+	}
+	if len(kept) == 0 {
+		return nil, errors.New("pkcs8: at least one recipient is required")
+	}
+	container.Recipients = kept
+	return asn1.Marshal(*container)
+}
+
+func parseMultiRecipientKey(der []byte) (*multiRecipientKey, error) {
+	var container multiRecipientKey
+	if _, err := asn1.Unmarshal(der, &container); err != nil {
+		return nil, errors.New("pkcs8: invalid multi-recipient key")
+	}
+	if container.Version != 0 {
+		return nil, errors.New("pkcs8: unsupported multi-recipient key version")
+	}
+	return &container, nil
+}
+
+// checkLabel returns an error if a recipient of container is labelled label.
+func (container *multiRecipientKey) checkLabel(label string) error {
+	for _, r := range container.Recipients {
+		if r.Label == label {
+			return fmt.Errorf("pkcs8: duplicate recipient label %q", label)
+		}
+	}
+	return nil
+}
+
+// unlock decrypts the key with the password of any recipient allowed by
+// opts. It returns the key, the content-encryption key and the label of the
+// recipient.
+func (container *multiRecipientKey) unlock(password []byte, opts *ParseOpts) (interface{}, []byte, string, error) {
+	contentAlg := container.ContentEncryptionAlgorithm.Algorithm
+	if err := opts.checkLegacy(contentAlg); err != nil {
+		return nil, nil, "", err
+	}
+	if opts != nil && opts.AllowedCiphers != nil && !containsOID(opts.AllowedCiphers, contentAlg) {
+		return nil, nil, "", fmt.Errorf("pkcs8: cipher not allowed (OID: %s)", contentAlg)
+	}
+	cipher, iv, err := opts.registry().parseEncryptionScheme(container.ContentEncryptionAlgorithm)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// A recipient whose scheme is refused by opts is skipped, and the
+	// refusal reported if no other recipient unlocks the key.
+	var refused error
+	for _, r := range container.Recipients {
+		var params pbes2Params
+		if _, err := asn1.Unmarshal(r.EncryptedKey.EncryptionAlgorithm.Parameters.FullBytes, &params); err == nil {
+			if err := opts.checkAllowed(&params); err != nil {
+				refused = err
+				continue
+			}
+		}
+		// The container is marshalled again by AddMultiRecipient, so the
+		// wrapped key is not decrypted in place.
+		cek, _, err := decryptPBES2(&r.EncryptedKey, false, password, opts)
+		if err != nil {
+			continue
+		}
+		if len(cek) != cipher.KeySize() {
+			zero(cek)
+			continue
+		}
+		decrypted, err := cipher.Decrypt(cek, iv, container.EncryptedContent)
+		if err != nil {
+			zero(cek)
+			continue
+		}
+		key, err := parsePKCS8(decrypted, opts)
+		if err != nil {
+			zero(decrypted)
+			zero(cek)
+			continue
+		}
+		err = opts.validate(decrypted, key)
+		zero(decrypted)
+		if err != nil {
+			zero(cek)
+			return nil, nil, "", err
+		}
+		return key, cek, r.Label, nil
+	}
+	if refused != nil {
+		return nil, nil, "", refused
+	}
+	return nil, nil, "", errors.New("pkcs8: incorrect password")
+}
+
+// MultiRecipientLabels returns the labels of the recipients of a key created
+// by MarshalMultiRecipientPrivateKey, without decrypting it.
+func MultiRecipientLabels(der []byte) ([]string, error) {
+	container, err := parseMultiRecipientKey(der)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, len(container.Recipients))
+	for i, r := range container.Recipients {
+		labels[i] = r.Label
+	}
+	return labels, nil
+}
@@ -0,0 +1,192 @@
+package pkcs8
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"sync"
+)
+
+// defaultRegistry holds the KDFs and ciphers registered with RegisterKDF and
+// RegisterCipher, including the built-in ones.
+var defaultRegistry = NewRegistry()
+
+// Registry is a set of KDFs, ciphers and PKCS#12 PBE schemes that can be
+// used to decrypt keys.
+// Unlike RegisterKDF and RegisterCipher, which change the behaviour of the
+// whole program, a Registry only affects the calls it is passed to through
+// ParseOpts.
+//
+// A Registry is safe for concurrent use: it can be modified while keys are
+// being parsed with it. A key being parsed uses the algorithms registered
+// when its cipher and KDF were looked up.
+type Registry struct {
+	mu      sync.RWMutex
+	kdfs    map[string]func() KDFParameters
+	ciphers map[string]func() Cipher
+	pbes    map[string]pkcs12PBE
+	legacy  map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		kdfs:    make(map[string]func() KDFParameters),
+		ciphers: make(map[string]func() Cipher),
+		pbes:    make(map[string]pkcs12PBE),
+		legacy:  make(map[string]bool),
+	}
+}
+
+// DefaultRegistry returns a copy of the default registry, which contains the
+// built-in KDFs, ciphers and PKCS#12 PBE schemes and those registered with
+// RegisterKDF and RegisterCipher. Changes to the copy do not affect the default registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry.Clone()
+}
+
+// Clone returns a copy of r.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := NewRegistry()
+	for oid, params := range r.kdfs {
+		c.kdfs[oid] = params
+	}
+	for oid, cipher := range r.ciphers {
+		c.ciphers[oid] = cipher
+	}
+	for oid, scheme := range r.pbes {
+		c.pbes[oid] = scheme
+	}
+	for oid, legacy := range r.legacy {
+		c.legacy[oid] = legacy
+	}
+	return c
+}
+
+// RegisterKDF registers a function that returns a new instance of the given KDF
+// parameters in r.
+func (r *Registry) RegisterKDF(oid asn1.ObjectIdentifier, params func() KDFParameters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kdfs[oid.String()] = params
+}
+
+// RegisterCipher registers a function that returns a new instance of the given
+// cipher in r.
+func (r *Registry) RegisterCipher(oid asn1.ObjectIdentifier, cipher func() Cipher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ciphers[oid.String()] = cipher
+}
+
+// UnregisterKDF removes the KDF with the given OID from r.
+func (r *Registry) UnregisterKDF(oid asn1.ObjectIdentifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.kdfs, oid.String())
+}
+
+// UnregisterCipher removes the cipher with the given OID from r.
+func (r *Registry) UnregisterCipher(oid asn1.ObjectIdentifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.ciphers, oid.String())
+}
+
+// UnregisterPBE removes the PKCS#12 PBE scheme with the given OID, e.g.
+// pbeWithSHAAnd128BitRC4, from r. The built-in schemes cannot be added to a
+// registry otherwise: registries that should decrypt them must be cloned
+// from DefaultRegistry.
+func (r *Registry) UnregisterPBE(oid asn1.ObjectIdentifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pbes, oid.String())
+}
+
+// registerPBE registers the PKCS#12 PBE scheme with the given OID in r.
+func (r *Registry) registerPBE(oid asn1.ObjectIdentifier, scheme pkcs12PBE) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pbes[oid.String()] = scheme
+}
+
+// SetLegacy marks the algorithm with the given OID, a cipher, KDF or PRF, as
+// legacy or not. Keys using legacy algorithms are refused unless
+// ParseOpts.AllowLegacy is set.
+func (r *Registry) SetLegacy(oid asn1.ObjectIdentifier, legacy bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if legacy {
+		r.legacy[oid.String()] = true
+	} else {
+		delete(r.legacy, oid.String())
+	}
+}
+
+// IsLegacy reports whether the algorithm with the given OID is marked as legacy.
+func (r *Registry) IsLegacy(oid asn1.ObjectIdentifier) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.legacy[oid.String()]
+}
+
+func (r *Registry) lookupKDF(oid string) (func() KDFParameters, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	params, ok := r.kdfs[oid]
+	return params, ok
+}
+
+func (r *Registry) lookupCipher(oid string) (func() Cipher, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cipher, ok := r.ciphers[oid]
+	return cipher, ok
+}
+
+func (r *Registry) lookupPBE(oid asn1.ObjectIdentifier) (pkcs12PBE, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	scheme, ok := r.pbes[oid.String()]
+	return scheme, ok
+}
+
+func (r *Registry) parseKeyDerivationFunc(keyDerivationFunc pkix.AlgorithmIdentifier) (KDFParameters, error) {
+	oid := keyDerivationFunc.Algorithm.String()
+	newParams, ok := r.lookupKDF(oid)
+	if !ok {
+		return nil, fmt.Errorf("pkcs8: unsupported KDF (OID: %s)", oid)
+	}
+	params := newParams()
+	_, err := asn1.Unmarshal(keyDerivationFunc.Parameters.FullBytes, params)
+	if v, ok := params.(interface{ validate() error }); ok && err == nil {
+		// CHOICEs are decoded as raw values, and checked here.
+		err = v.validate()
+	}
+	if err != nil {
+		return nil, newParseError("invalid KDF parameters", "keyDerivationFunc.parameters", keyDerivationFunc.Parameters.FullBytes, err)
+	}
+	return params, nil
+}
+
+func (r *Registry) parseEncryptionScheme(encryptionScheme pkix.AlgorithmIdentifier) (Cipher, []byte, error) {
+	oid := encryptionScheme.Algorithm.String()
+	newCipher, ok := r.lookupCipher(oid)
+	if !ok {
+		return nil, nil, fmt.Errorf("pkcs8: unsupported cipher (OID: %s)", oid)
+	}
+	cipher := newCipher()
+	var iv []byte
+	var err error
+	if p, ok := cipher.(CipherParameters); ok {
+		cipher, iv, err = p.UnmarshalParameters(encryptionScheme.Parameters.FullBytes)
+	} else {
+		_, err = asn1.Unmarshal(encryptionScheme.Parameters.FullBytes, &iv)
+	}
+	if err != nil {
+		return nil, nil, newParseError("invalid cipher parameters", "encryptionScheme.parameters", encryptionScheme.Parameters.FullBytes, err)
+	}
+	return cipher, iv, nil
+}
@@ -0,0 +1,260 @@
+package pkcs8
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileFS is a file system that files can be written to.
+type WriteFileFS interface {
+	fs.FS
+	// WriteFile replaces the named file with data.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// WritableDirFS returns a WriteFileFS for the files under dir. Files are
+// replaced atomically: data is written to a temporary file in the same
+// directory, which is then renamed over the original.
+func WritableDirFS(dir string) WriteFileFS {
+	return writableDirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+type writableDirFS struct {
+	fs.FS
+	dir string
+}
+
+func (d writableDirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	path := filepath.Join(d.dir, filepath.FromSlash(name))
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RewrapOpts contains options for Rewrap.
+type RewrapOpts struct {
+	// NewPassword is the password to encrypt the keys with. The old password
+	// is kept if nil.
+	NewPassword []byte
+	// Opts are the options to encrypt the keys with. DefaultOpts are used if nil.
+	Opts *Opts
+	// ParseOpts are the options to decrypt the keys with, e.g. AllowLegacy
+	// to migrate keys encrypted with legacy algorithms. They can be nil.
+	ParseOpts *ParseOpts
+	// DryRun reports what would be rewrapped without writing any file.
+	DryRun bool
+}
+
+// Statuses of a file in a RewrapReport.
+const (
+	RewrapStatusRewrapped = "rewrapped"
+	RewrapStatusSkipped   = "skipped"
+	RewrapStatusFailed    = "failed"
+)
+
+// RewrapResult is the outcome of rewrapping a single file.
+type RewrapResult struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RewrapReport is the machine-readable report of a Rewrap run.
+// In dry-run mode, "rewrapped" means the file would have been rewrapped.
+type RewrapReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Results []RewrapResult `json:"results"`
+}
+
+// Failed reports whether any file failed to be rewrapped.
+func (r *RewrapReport) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == RewrapStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Rewrap walks fsys and re-encrypts every PKCS#8 private key in the files
+// accepted by match with opts, using ReEncryptWithOpts. A nil match accepts
+// all files. Keys are read as PEM or DER, and written back in the same
+// encoding. Every PKCS#8 block of a PEM file is re-encrypted and the other
+// blocks, such as the certificates of a combined certificate and key file,
+// are kept as they are.
+//
+// Unless opts.DryRun is set, fsys must implement WriteFileFS; WritableDirFS
+// provides atomic replacement of files on disk. Files without a private key
+// are skipped, and failures are recorded in the report rather than stopping
+// the walk. The returned error is only set if the walk itself fails.
+func Rewrap(fsys fs.FS, match func(path string) bool, oldPassword []byte, opts RewrapOpts) (*RewrapReport, error) {
+	wfs, writable := fsys.(WriteFileFS)
+	if !opts.DryRun && !writable {
+		return nil, errors.New("pkcs8: file system is not writable")
+	}
+	newPassword := opts.NewPassword
+	if newPassword == nil {
+		newPassword = oldPassword
+	}
+
+	report := &RewrapReport{DryRun: opts.DryRun}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (match != nil && !match(path)) {
+			return nil
+		}
+		result := RewrapResult{Path: path}
+		status, err := rewrapFile(fsys, wfs, path, oldPassword, newPassword, opts)
+		result.Status = status
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Results = append(report.Results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func rewrapFile(fsys fs.FS, wfs WriteFileFS, path string, oldPassword, newPassword []byte, opts RewrapOpts) (string, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return RewrapStatusFailed, err
+	}
+	info, err := fs.Stat(fsys, path)
+	if err != nil {
+		return RewrapStatusFailed, err
+	}
+
+	var out []byte
+	if rewrapped, found, err := rewrapPEM(data, oldPassword, newPassword, opts); err != nil {
+		return RewrapStatusFailed, err
+	} else if found {
+		out = rewrapped
+	} else if block, err := decodePrivateKeyPEM(data); err == nil {
+		// Only sloppy PEM that encoding/pem rejects gets here. It is
+		// re-encoded whole, so files with other blocks are left alone.
+		if block.Type != "PRIVATE KEY" && block.Type != "ENCRYPTED PRIVATE KEY" {
+			return RewrapStatusSkipped, nil
+		}
+		if n := countPEMBlocks(normalizePEM(data)); n > 1 {
+			return RewrapStatusSkipped, fmt.Errorf("pkcs8: %d malformed PEM blocks, rewrap the key by hand", n)
+		}
+		if err := checkPEMEncryption(block); err != nil {
+			return RewrapStatusFailed, err
+		}
+		der, err := ReEncryptWithOpts(block.Bytes, oldPassword, newPassword, opts.ParseOpts, opts.Opts)
+		if err != nil {
+			return RewrapStatusFailed, err
+		}
+		out = pem.EncodeToMemory(&pem.Block{Type: pemType(newPassword), Bytes: der})
+	} else if isPrivateKeyDER(data) {
+		out, err = ReEncryptWithOpts(data, oldPassword, newPassword, opts.ParseOpts, opts.Opts)
+		if err != nil {
+			return RewrapStatusFailed, err
+		}
+	} else {
+		return RewrapStatusSkipped, nil
+	}
+
+	if opts.DryRun {
+		return RewrapStatusRewrapped, nil
+	}
+	if err := wfs.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return RewrapStatusFailed, err
+	}
+	return RewrapStatusRewrapped, nil
+}
+
+// isPrivateKeyDER reports whether data has the structure of a DER-encoded
+// PrivateKeyInfo, EncryptedPrivateKeyInfo or PKCS#7 EncryptedData, so that
+// other DER files, such as certificates, are skipped rather than failed.
+// Whether the algorithms are supported is left to ReEncryptWithOpts.
+func isPrivateKeyDER(data []byte) bool {
+	if isPKCS7EncryptedData(data) {
+		return true
+	}
+	var encrypted encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(data, &encrypted); err == nil && len(rest) == 0 {
+		return true
+	}
+	var info privateKeyInfo
+	rest, err := asn1.Unmarshal(data, &info)
+	return err == nil && len(rest) == 0
+}
+
+// rewrapPEM re-encrypts every PKCS#8 block of the PEM file data, e.g. a
+// certificate chain followed by its key as read by haproxy or nginx. The
+// other blocks and the text around them are copied byte for byte. It
+// reports whether any PKCS#8 block was found.
+func rewrapPEM(data, oldPassword, newPassword []byte, opts RewrapOpts) ([]byte, bool, error) {
+	var out []byte
+	found := false
+	rest := data
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		consumed := rest[:len(rest)-len(next)]
+		if block.Type != "PRIVATE KEY" && block.Type != "ENCRYPTED PRIVATE KEY" {
+			out = append(out, consumed...)
+			rest = next
+			continue
+		}
+		if err := checkPEMEncryption(block); err != nil {
+			return nil, false, err
+		}
+		der, err := ReEncryptWithOpts(block.Bytes, oldPassword, newPassword, opts.ParseOpts, opts.Opts)
+		if err != nil {
+			return nil, false, err
+		}
+		// pem.Decode skips the text before the block, which is kept.
+		start := bytes.LastIndex(consumed, []byte("-----BEGIN "))
+		out = append(out, consumed[:start]...)
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: pemType(newPassword), Bytes: der})...)
+		found = true
+		rest = next
+	}
+	return append(out, rest...), found, nil
+}
+
+// countPEMBlocks returns the number of PEM blocks in data.
+func countPEMBlocks(data []byte) int {
+	n := 0
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,220 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+var (
+	oidPublicKeyRSAPSS = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidMGF1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA224 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// pssHashes are the hash functions of RSASSA-PSS parameters.
+var pssHashes = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+}{
+	{oidSHA1, crypto.SHA1},
+	{oidSHA224, crypto.SHA224},
+	{oidSHA256, crypto.SHA256},
+	{oidSHA384, crypto.SHA384},
+	{oidSHA512, crypto.SHA512},
+}
+
+// PSSParameters are the RSASSA-PSS-params of an id-RSASSA-PSS key, which
+// restrict the signatures it may make (RFC 4055, section 3.1).
+type PSSParameters struct {
+	// Hash is the hash function of the message digest, and MGFHash the
+	// one of MGF1. Both are crypto.SHA1 if omitted from the encoding.
+	Hash    crypto.Hash
+	MGFHash crypto.Hash
+	// SaltLength is the minimum salt length, 20 if omitted from the
+	// encoding.
+	SaltLength int
+}
+
+// pssParams is the RSASSA-PSS-params structure of RFC 4055.
+type pssParams struct {
+	Hash         pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MGF          pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	SaltLength   int                      `asn1:"optional,explicit,tag:2,default:20"`
+	TrailerField int                      `asn1:"optional,explicit,tag:3,default:1"`
+}
+
+func init() {
+	keyTypes = append(keyTypes, keyType{
+		oid: oidPublicKeyRSAPSS,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			if _, err := parsePSSParameters(pki.PrivateKeyAlgorithm.Parameters); err != nil {
+				return nil, err
+			}
+			key, err := x509.ParsePKCS1PrivateKey(pki.PrivateKey)
+			if err != nil {
+				return nil, errors.New("pkcs8: invalid RSASSA-PSS private key")
+			}
+			return key, nil
+		},
+		parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+			if _, err := parsePSSParameters(spki.Algorithm.Parameters); err != nil {
+				return nil, err
+			}
+			key, err := x509.ParsePKCS1PublicKey(spki.PublicKey.RightAlign())
+			if err != nil {
+				return nil, errors.New("pkcs8: invalid RSASSA-PSS public key")
+			}
+			return key, nil
+		},
+	})
+}
+
+// parsePSSParameters parses the parameters of an id-RSASSA-PSS key, which
+// are nil if absent.
+func parsePSSParameters(raw asn1.RawValue) (*PSSParameters, error) {
+	if len(raw.FullBytes) == 0 {
+		return nil, nil
+	}
+	errInvalid := errors.New("pkcs8: invalid RSASSA-PSS parameters")
+	var params pssParams
+	if rest, err := asn1.Unmarshal(raw.FullBytes, &params); err != nil || len(rest) != 0 {
+		return nil, errInvalid
+	}
+	if params.SaltLength < 0 || params.TrailerField != 1 {
+		return nil, errInvalid
+	}
+	p := &PSSParameters{Hash: crypto.SHA1, MGFHash: crypto.SHA1, SaltLength: params.SaltLength}
+	if len(params.Hash.Algorithm) != 0 {
+		if p.Hash = pssHash(params.Hash.Algorithm); p.Hash == 0 {
+			return nil, errors.New("pkcs8: unsupported RSASSA-PSS hash function")
+		}
+	}
+	if len(params.MGF.Algorithm) != 0 {
+		var mgfHash pkix.AlgorithmIdentifier
+		if !params.MGF.Algorithm.Equal(oidMGF1) {
+			return nil, errors.New("pkcs8: unsupported RSASSA-PSS mask generation function")
+		}
+		if rest, err := asn1.Unmarshal(params.MGF.Parameters.FullBytes, &mgfHash); err != nil || len(rest) != 0 {
+			return nil, errInvalid
+		}
+		if p.MGFHash = pssHash(mgfHash.Algorithm); p.MGFHash == 0 {
+			return nil, errors.New("pkcs8: unsupported RSASSA-PSS hash function")
+		}
+	}
+	return p, nil
+}
+
+func pssHash(oid asn1.ObjectIdentifier) crypto.Hash {
+	for _, h := range pssHashes {
+		if h.oid.Equal(oid) {
+			return h.hash
+		}
+	}
+	return 0
+}
+
+// marshal encodes p as RSASSA-PSS-params, leaving out the fields equal to
+// their default as OpenSSL does.
+func (p *PSSParameters) marshal() (asn1.RawValue, error) {
+	var params pssParams
+	hashAlgorithm := func(h crypto.Hash) (pkix.AlgorithmIdentifier, error) {
+		for _, ph := range pssHashes {
+			if ph.hash == h {
+				return pkix.AlgorithmIdentifier{Algorithm: ph.oid, Parameters: asn1.NullRawValue}, nil
+			}
+		}
+		return pkix.AlgorithmIdentifier{}, errors.New("pkcs8: unsupported RSASSA-PSS hash function")
+	}
+	if p.Hash != crypto.SHA1 {
+		alg, err := hashAlgorithm(p.Hash)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		params.Hash = alg
+	}
+	if p.MGFHash != crypto.SHA1 {
+		alg, err := hashAlgorithm(p.MGFHash)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		der, err := asn1.Marshal(alg)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		params.MGF = pkix.AlgorithmIdentifier{Algorithm: oidMGF1, Parameters: asn1.RawValue{FullBytes: der}}
+	}
+	if p.SaltLength < 0 {
+		return asn1.RawValue{}, errors.New("pkcs8: invalid RSASSA-PSS salt length")
+	}
+	params.SaltLength = p.SaltLength
+	params.TrailerField = 1
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{FullBytes: der}, nil
+}
+
+// ParseRSAPrivateKeyWithPSSParameters parses a DER-encoded PKCS#8 RSA private
+// key and returns the RSASSA-PSS parameters restricting it. They are nil for
+// rsaEncryption keys, and a zero PSSParameters for id-RSASSA-PSS keys
+// without parameters, which may be used with any. Keys whose algorithm is
+// id-RSASSA-PSS are also accepted by ParsePrivateKey, which drops the
+// restriction. Password and opts can be nil.
+func ParseRSAPrivateKeyWithPSSParameters(der []byte, password []byte, opts *ParseOpts) (*rsa.PrivateKey, *PSSParameters, error) {
+	var key interface{}
+	var params *PSSParameters
+	_, _, err := decryptAndParse(der, password, opts, func(pkey []byte, k interface{}) error {
+		var pki privateKeyInfo
+		if _, err := asn1.Unmarshal(pkey, &pki); err != nil {
+			return errors.New("pkcs8: invalid PrivateKeyInfo")
+		}
+		if pki.PrivateKeyAlgorithm.Algorithm.Equal(oidPublicKeyRSAPSS) {
+			p, err := parsePSSParameters(pki.PrivateKeyAlgorithm.Parameters)
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				p = &PSSParameters{}
+			}
+			params = p
+		}
+		key = k
+		return opts.validate(pkey, k)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("pkcs8: key is not an RSA key")
+	}
+	return rsaKey, params, nil
+}
+
+// setPSSAlgorithm rewrites the algorithm of a DER-encoded PrivateKeyInfo
+// into id-RSASSA-PSS with the parameters p, or none if p is zero.
+func setPSSAlgorithm(pkey []byte, p *PSSParameters) ([]byte, error) {
+	var pki privateKeyInfo
+	if _, err := asn1.Unmarshal(pkey, &pki); err != nil {
+		return nil, err
+	}
+	pki.PrivateKeyAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyRSAPSS}
+	if *p != (PSSParameters{}) {
+		params, err := p.marshal()
+		if err != nil {
+			return nil, err
+		}
+		pki.PrivateKeyAlgorithm.Parameters = params
+	}
+	return asn1.Marshal(pki)
+}
@@ -0,0 +1,16 @@
+//go:build !pkcs8_noscrypt
+// +build !pkcs8_noscrypt
+
+package pkcs8
+
+import "golang.org/x/crypto/scrypt"
+
+func init() {
+	RegisterKDF(oidScrypt, func() KDFParameters {
+		return new(scryptParams)
+	})
+}
+
+func scryptKey(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+	return scrypt.Key(password, salt, N, r, p, keyLen)
+}
@@ -0,0 +1,12 @@
+//go:build pkcs8_noscrypt
+// +build pkcs8_noscrypt
+
+package pkcs8
+
+import "errors"
+
+// scryptKey fails: the package is built with the pkcs8_noscrypt tag, and
+// scrypt is not registered, so keys using it are not parsed.
+func scryptKey(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+	return nil, errors.New("pkcs8: scrypt is not supported by this build")
+}
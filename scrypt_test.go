@@ -0,0 +1,263 @@
+//go:build !pkcs8_noscrypt
+// +build !pkcs8_noscrypt
+
+package pkcs8_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+	"github.com/youmark/pkcs8/pkcs8test"
+)
+
+// scryptSupported reports whether the tests run against a build with scrypt.
+const scryptSupported = true
+
+func TestInspectScrypt(t *testing.T) {
+	block, _ := pem.Decode([]byte(encryptedRSA2048scrypt))
+	info, err := pkcs8.Inspect(block.Bytes, []byte("password"))
+	if err != nil {
+		t.Fatalf("Inspect returned: %s", err)
+	}
+	if info.KDF != "scrypt" || info.ScryptN == 0 || info.KeyType != "RSA" || info.Bits != 2048 {
+		t.Errorf("unexpected Inspect result: %+v", info)
+	}
+	if _, err := pkcs8.Inspect(block.Bytes, []byte("wrong")); err == nil {
+		t.Error("expected wrong password to fail")
+	}
+}
+
+func TestCheckKDFScrypt(t *testing.T) {
+	pkcs8test.CheckKDF(t, pkcs8.ScryptOpts{SaltSize: 16, CostParameter: 1 << 2, BlockSize: 8, ParallelizationParameter: 1})
+}
+
+func TestEstimateKDFCostScrypt(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("password"), &pkcs8.Opts{
+		Cipher:  pkcs8.AES256CBC,
+		KDFOpts: pkcs8.ScryptOpts{SaltSize: 16, CostParameter: 1 << 14, BlockSize: 8, ParallelizationParameter: 1},
+	})
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	info, err := pkcs8.Inspect(der, nil)
+	if err != nil {
+		t.Fatalf("Inspect returned: %s", err)
+	}
+	cost, err := pkcs8.EstimateKDFCost(info)
+	if err != nil {
+		t.Fatalf("EstimateKDFCost returned: %s", err)
+	}
+	if want := int64(128 * 8 * (1<<14 + 3)); cost.Memory != want {
+		t.Errorf("scrypt memory estimate is %d, want %d", cost.Memory, want)
+	}
+	if cost.Duration <= 0 {
+		t.Errorf("unexpected scrypt estimate %+v", cost)
+	}
+}
+
+func TestMultiRecipientPrivateKey(t *testing.T) {
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	recipients := []pkcs8.Recipient{
+		{Label: "ops", Password: []byte("ops password")},
+		{Label: "ci", Password: []byte("ci password"), Opts: &pkcs8.Opts{
+			Cipher: pkcs8.AES128CBC,
+			KDFOpts: pkcs8.ScryptOpts{
+				CostParameter: 1 << 2, BlockSize: 8, ParallelizationParameter: 1, SaltSize: 16,
+			},
+		}},
+	}
+	der, err := pkcs8.MarshalMultiRecipientPrivateKey(ecPrivateKey, recipients, nil)
+	if err != nil {
+		t.Fatalf("MarshalMultiRecipientPrivateKey returned: %s", err)
+	}
+	labels, err := pkcs8.MultiRecipientLabels(der)
+	if err != nil || len(labels) != 2 || labels[0] != "ops" || labels[1] != "ci" {
+		t.Fatalf("MultiRecipientLabels returned %v, %v", labels, err)
+	}
+	for _, r := range recipients {
+		key, label, err := pkcs8.ParseMultiRecipientPrivateKey(der, r.Password)
+		if err != nil {
+			t.Fatalf("%s: ParseMultiRecipientPrivateKey returned: %s", r.Label, err)
+		}
+		if label != r.Label {
+			t.Errorf("%s: unlocked for recipient %q", r.Label, label)
+		}
+		if ecPrivateKey.D.Cmp(key.(*ecdsa.PrivateKey).D) != 0 {
+			t.Fatalf("%s: Decoded key does not match original key", r.Label)
+		}
+	}
+	if _, _, err := pkcs8.ParseMultiRecipientPrivateKey(der, []byte("wrong password")); err == nil {
+		t.Error("should have failed")
+	}
+
+	// The options apply to the wrapping of every recipient and to the
+	// content encryption.
+	for _, opts := range []*pkcs8.ParseOpts{
+		{AllowedKDFs: []asn1.ObjectIdentifier{pkcs8.PBKDF2Opts{}.OID()}},
+		{AllowedCiphers: []asn1.ObjectIdentifier{pkcs8.AES128CBC.OID()}},
+	} {
+		if _, _, err := pkcs8.ParseMultiRecipientPrivateKeyWithOpts(der, []byte("ci password"), opts); err == nil ||
+			!strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("ParseMultiRecipientPrivateKeyWithOpts(%+v) returned %v", opts, err)
+		}
+	}
+	opts := &pkcs8.ParseOpts{AllowedKDFs: []asn1.ObjectIdentifier{pkcs8.ScryptOpts{}.OID()}}
+	if _, label, err := pkcs8.ParseMultiRecipientPrivateKeyWithOpts(der, []byte("ci password"), opts); err != nil || label != "ci" {
+		t.Errorf("ParseMultiRecipientPrivateKeyWithOpts returned %q, %v", label, err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	dir := t.TempDir()
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned: %s", err)
+	}
+	keyPEM, err := pkcs8.MarshalPrivateKeyPEM(ecPrivateKey, []byte("old"), nil)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKeyPEM returned: %s", err)
+	}
+	keyDER, err := pkcs8.MarshalPrivateKey(ecPrivateKey, []byte("old"), nil)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	for name, data := range map[string][]byte{
+		"a.pem":      keyPEM,
+		"sub/b.der":  keyDER,
+		"other.pem":  []byte(encryptedEC256aes),
+		"README.txt": []byte("not a key"),
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	match := func(path string) bool { return !strings.HasSuffix(path, ".txt") }
+	opts := pkcs8.RewrapOpts{
+		NewPassword: []byte("new"),
+		Opts: &pkcs8.Opts{
+			Cipher: pkcs8.AES256CBC,
+			KDFOpts: pkcs8.ScryptOpts{
+				CostParameter: 1 << 2, BlockSize: 8, ParallelizationParameter: 1, SaltSize: 16,
+			},
+		},
+		DryRun: true,
+	}
+
+	fsys := pkcs8.WritableDirFS(dir)
+	report, err := pkcs8.Rewrap(fsys, match, []byte("old"), opts)
+	if err != nil {
+		t.Fatalf("Rewrap returned: %s", err)
+	}
+	want := map[string]string{
+		"a.pem":     pkcs8.RewrapStatusRewrapped,
+		"sub/b.der": pkcs8.RewrapStatusRewrapped,
+		"other.pem": pkcs8.RewrapStatusFailed,
+	}
+	if len(report.Results) != len(want) || !report.DryRun || !report.Failed() {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for _, result := range report.Results {
+		if want[result.Path] != result.Status {
+			t.Errorf("%s: status %s, want %s", result.Path, result.Status, want[result.Path])
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.pem")); !bytes.Equal(data, keyPEM) {
+		t.Fatal("dry run modified a file")
+	}
+
+	opts.DryRun = false
+	if _, err := pkcs8.Rewrap(fsys, match, []byte("old"), opts); err != nil {
+		t.Fatalf("Rewrap returned: %s", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "a.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyPEM(data, []byte("new")); err != nil {
+		t.Errorf("a.pem: ParsePrivateKeyPEM returned: %s", err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "sub", "b.der"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(data, []byte("new")); err != nil {
+		t.Errorf("b.der: ParsePrivateKey returned: %s", err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(data, []byte("old")); err == nil {
+		t.Error("b.der: old password still works")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	block, _ := pem.Decode([]byte(encryptedRSA2048scrypt))
+	scrypt := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	restricted := pkcs8.DefaultRegistry()
+	restricted.UnregisterKDF(scrypt)
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), &pkcs8.ParseOpts{Registry: restricted}); err == nil {
+		t.Error("expected unsupported KDF error")
+	}
+	if _, _, err := pkcs8.ParsePrivateKey(block.Bytes, []byte("password")); err != nil {
+		t.Errorf("changing a cloned registry affected the default registry: %s", err)
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), &pkcs8.ParseOpts{Registry: pkcs8.NewRegistry()}); err == nil {
+		t.Error("expected an empty registry to reject the key")
+	}
+	if _, _, err := pkcs8.ParsePrivateKeyWithOpts(block.Bytes, []byte("password"), &pkcs8.ParseOpts{Registry: restricted.Clone()}); err == nil {
+		t.Error("expected a clone to keep the KDF unregistered")
+	}
+}
+
+func TestSetDefaultMarshalOpts(t *testing.T) {
+	saved := *pkcs8.DefaultOpts
+	defer pkcs8.SetDefaultMarshalOpts(saved)
+
+	if err := pkcs8.SetDefaultMarshalOpts(pkcs8.Opts{Cipher: pkcs8.AES128CBC}); err == nil {
+		t.Error("expected options without a KDF to be refused")
+	}
+	err := pkcs8.SetDefaultMarshalOpts(pkcs8.Opts{
+		Cipher: pkcs8.AES128CBC,
+		KDFOpts: pkcs8.ScryptOpts{
+			CostParameter: 1 << 2, BlockSize: 8, ParallelizationParameter: 1, SaltSize: 16,
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetDefaultMarshalOpts returned: %s", err)
+	}
+
+	block, _ := pem.Decode([]byte(ec256))
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey returned: %s", err)
+	}
+	der, err := pkcs8.ConvertPrivateKeyToPKCS8(key, []byte("password"))
+	if err != nil {
+		t.Fatalf("ConvertPrivateKeyToPKCS8 returned: %s", err)
+	}
+	_, params, err := pkcs8.ParsePrivateKey(der, []byte("password"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey returned: %s", err)
+	}
+	if !strings.Contains(fmt.Sprintf("%T", params), "scrypt") {
+		t.Errorf("expected the key to be encrypted with scrypt, got %T", params)
+	}
+}
@@ -0,0 +1,153 @@
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+)
+
+// shamirSecretSize is the size of the random passphrase generated by
+// MarshalPrivateKeyWithShares.
+const shamirSecretSize = 32
+
+// SplitSecret splits secret into n shares using Shamir's secret sharing over
+// GF(2^8), any k of which are sufficient to reconstruct it with CombineShares.
+// Each share is one byte longer than the secret: the first byte holds the
+// share's x coordinate.
+func SplitSecret(secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("pkcs8: cannot split an empty secret")
+	}
+	if k < 2 || k > n {
+		return nil, errors.New("pkcs8: threshold must be at least 2 and at most the number of shares")
+	}
+	if n > 255 {
+		return nil, errors.New("pkcs8: cannot create more than 255 shares")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	coeffs := make([]byte, k)
+	defer zero(coeffs)
+	for j, b := range secret {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[j+1] = gfEvalPolynomial(coeffs, share[0])
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret from shares created by SplitSecret.
+// At least the threshold number of shares must be given; with fewer shares
+// the result is indistinguishable from random.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("pkcs8: at least two shares are required")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("pkcs8: invalid share")
+	}
+	seen := make(map[byte]bool)
+	for _, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("pkcs8: shares have different lengths")
+		}
+		if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("pkcs8: invalid or duplicate share")
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, size-1)
+	for j := range secret {
+		// Lagrange interpolation at x = 0.
+		var value byte
+		for i, si := range shares {
+			basis := byte(1)
+			for m, sm := range shares {
+				if i == m {
+					continue
+				}
+				// In GF(2^8) subtraction is addition, so 0 - x_m == x_m.
+				basis = gfMul(basis, gfMul(sm[0], gfInverse(si[0]^sm[0])))
+			}
+			value ^= gfMul(si[j+1], basis)
+		}
+		secret[j] = value
+	}
+	return secret, nil
+}
+
+// MarshalPrivateKeyWithShares encrypts a private key with a randomly
+// generated passphrase and splits that passphrase into n shares, k of which
+// are needed to decrypt the key again with ParsePrivateKeyWithShares.
+// The passphrase itself is never returned.
+func MarshalPrivateKeyWithShares(priv interface{}, n, k int, opts *Opts) (der []byte, shares [][]byte, err error) {
+	password := make([]byte, shamirSecretSize)
+	if _, err := rand.Read(password); err != nil {
+		return nil, nil, err
+	}
+	defer zero(password)
+
+	shares, err = SplitSecret(password, n, k)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err = MarshalPrivateKey(priv, password, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, shares, nil
+}
+
+// ParsePrivateKeyWithShares reconstructs the passphrase from the given shares
+// and uses it to decrypt a key created by MarshalPrivateKeyWithShares.
+func ParsePrivateKeyWithShares(der []byte, shares [][]byte) (crypto.PrivateKey, KDFParameters, error) {
+	password, err := CombineShares(shares)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zero(password)
+	return ParsePrivateKey(der, password)
+}
+
+func gfEvalPolynomial(coeffs []byte, x byte) byte {
+	// Horner's method, starting from the highest degree coefficient.
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// gfMul multiplies a and b in GF(2^8) with the AES reduction polynomial,
+// without data-dependent branches.
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= a & -(b & 1)
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInverse returns the multiplicative inverse of a, computed as a^254.
+func gfInverse(a byte) byte {
+	b := gfMul(a, a)
+	result := b
+	for i := 0; i < 6; i++ {
+		b = gfMul(b, b)
+		result = gfMul(result, b)
+	}
+	return result
+}
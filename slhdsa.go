@@ -0,0 +1,213 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var (
+	oidSLHDSASHA2_128s  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 20}
+	oidSLHDSASHA2_128f  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 21}
+	oidSLHDSASHA2_192s  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 22}
+	oidSLHDSASHA2_192f  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 23}
+	oidSLHDSASHA2_256s  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 24}
+	oidSLHDSASHA2_256f  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 25}
+	oidSLHDSASHAKE_128s = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 26}
+	oidSLHDSASHAKE_128f = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 27}
+	oidSLHDSASHAKE_192s = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 28}
+	oidSLHDSASHAKE_192f = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 29}
+	oidSLHDSASHAKE_256s = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 30}
+	oidSLHDSASHAKE_256f = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 31}
+)
+
+// SLHDSAParameters is an SLH-DSA parameter set of FIPS 205.
+type SLHDSAParameters int
+
+// The SLH-DSA parameter sets. The s sets have small signatures, the f sets
+// fast signing.
+const (
+	SLHDSA_SHA2_128s SLHDSAParameters = iota + 1
+	SLHDSA_SHA2_128f
+	SLHDSA_SHA2_192s
+	SLHDSA_SHA2_192f
+	SLHDSA_SHA2_256s
+	SLHDSA_SHA2_256f
+	SLHDSA_SHAKE_128s
+	SLHDSA_SHAKE_128f
+	SLHDSA_SHAKE_192s
+	SLHDSA_SHAKE_192f
+	SLHDSA_SHAKE_256s
+	SLHDSA_SHAKE_256f
+)
+
+var slhdsaParameters = []struct {
+	params SLHDSAParameters
+	oid    asn1.ObjectIdentifier
+	n      int
+}{
+	{SLHDSA_SHA2_128s, oidSLHDSASHA2_128s, 16},
+	{SLHDSA_SHA2_128f, oidSLHDSASHA2_128f, 16},
+	{SLHDSA_SHA2_192s, oidSLHDSASHA2_192s, 24},
+	{SLHDSA_SHA2_192f, oidSLHDSASHA2_192f, 24},
+	{SLHDSA_SHA2_256s, oidSLHDSASHA2_256s, 32},
+	{SLHDSA_SHA2_256f, oidSLHDSASHA2_256f, 32},
+	{SLHDSA_SHAKE_128s, oidSLHDSASHAKE_128s, 16},
+	{SLHDSA_SHAKE_128f, oidSLHDSASHAKE_128f, 16},
+	{SLHDSA_SHAKE_192s, oidSLHDSASHAKE_192s, 24},
+	{SLHDSA_SHAKE_192f, oidSLHDSASHAKE_192f, 24},
+	{SLHDSA_SHAKE_256s, oidSLHDSASHAKE_256s, 32},
+	{SLHDSA_SHAKE_256f, oidSLHDSASHAKE_256f, 32},
+}
+
+func (p SLHDSAParameters) String() string {
+	if oid := p.oid(); oid != nil {
+		return OIDName(oid)
+	}
+	return fmt.Sprintf("SLHDSAParameters(%d)", int(p))
+}
+
+// PrivateKeySize returns the size of the private keys of p, SK.seed,
+// SK.prf, PK.seed and PK.root.
+func (p SLHDSAParameters) PrivateKeySize() int {
+	return 4 * p.n()
+}
+
+// PublicKeySize returns the size of the public keys of p, PK.seed and
+// PK.root.
+func (p SLHDSAParameters) PublicKeySize() int {
+	return 2 * p.n()
+}
+
+func (p SLHDSAParameters) n() int {
+	for _, ps := range slhdsaParameters {
+		if ps.params == p {
+			return ps.n
+		}
+	}
+	return 0
+}
+
+func (p SLHDSAParameters) oid() asn1.ObjectIdentifier {
+	for _, ps := range slhdsaParameters {
+		if ps.params == p {
+			return ps.oid
+		}
+	}
+	return nil
+}
+
+// SLHDSAPrivateKey is an SLH-DSA private key. It is returned by the parse
+// functions for the id-slh-dsa-* keys of RFC 9909 and accepted by the
+// marshal functions.
+//
+// The package does not implement SLH-DSA: the key only carries its raw
+// material, to be used with an SLH-DSA implementation.
+type SLHDSAPrivateKey struct {
+	Parameters SLHDSAParameters
+	// Key is the private key of FIPS 205, SK.seed || SK.prf || PK.seed ||
+	// PK.root.
+	Key []byte
+}
+
+// SLHDSAPublicKey is an SLH-DSA public key. It is returned by
+// ParsePublicKey and accepted by MarshalPublicKey.
+type SLHDSAPublicKey struct {
+	Parameters SLHDSAParameters
+	Key        []byte
+}
+
+func init() {
+	for _, ps := range slhdsaParameters {
+		ps := ps
+		keyTypes = append(keyTypes, keyType{
+			oid: ps.oid,
+			parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+				key, err := parseSLHDSAPrivateKey(pki, ps.params.PrivateKeySize())
+				if err != nil {
+					return nil, err
+				}
+				return &SLHDSAPrivateKey{Parameters: ps.params, Key: key}, nil
+			},
+			parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+				key, err := parseCurvePublicKey(spki, ps.params.PublicKeySize())
+				if err != nil {
+					return nil, err
+				}
+				return &SLHDSAPublicKey{Parameters: ps.params, Key: key}, nil
+			},
+		})
+	}
+}
+
+// parseSLHDSAPrivateKey returns the private key of size bytes of an SLH-DSA
+// PrivateKeyInfo. RFC 9909 stores the key as is in the privateKey field, but
+// early implementations wrapped it in an OCTET STRING as RFC 8410 does, so
+// both are accepted. The key is not copied: pki.PrivateKey is already a copy
+// of the decoded PrivateKeyInfo.
+func parseSLHDSAPrivateKey(pki *privateKeyInfo, size int) ([]byte, error) {
+	name := OIDName(pki.PrivateKeyAlgorithm.Algorithm)
+	if len(pki.PrivateKeyAlgorithm.Parameters.FullBytes) != 0 {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key parameters", name)
+	}
+	if len(pki.PrivateKey) == size {
+		return pki.PrivateKey[:size:size], nil
+	}
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(pki.PrivateKey, &raw); err != nil || len(rest) != 0 ||
+		raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagOctetString || raw.IsCompound {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", name)
+	}
+	if len(raw.Bytes) != size {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key size %d", name, len(raw.Bytes))
+	}
+	return raw.Bytes[:size:size], nil
+}
+
+func (priv *SLHDSAPrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	oid := priv.Parameters.oid()
+	if oid == nil {
+		return nil, errors.New("pkcs8: unsupported SLH-DSA parameters")
+	}
+	if len(priv.Key) != priv.Parameters.PrivateKeySize() {
+		return nil, fmt.Errorf("pkcs8: invalid %s private key", priv.Parameters)
+	}
+	return &privateKeyInfo{
+		PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey:          priv.Key,
+	}, nil
+}
+
+// Public returns the public key of priv, PK.seed and PK.root, the second
+// half of Key. The returned key shares its memory with priv.
+func (priv *SLHDSAPrivateKey) Public() crypto.PublicKey {
+	size := priv.Parameters.PrivateKeySize()
+	if size == 0 || len(priv.Key) != size {
+		return nil
+	}
+	return &SLHDSAPublicKey{Parameters: priv.Parameters, Key: priv.Key[size/2 : size : size]}
+}
+
+// Equal reports whether priv and x are the same key.
+func (priv *SLHDSAPrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*SLHDSAPrivateKey)
+	return ok && priv.Parameters == other.Parameters && subtle.ConstantTimeCompare(priv.Key, other.Key) == 1
+}
+
+func (pub *SLHDSAPublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	oid := pub.Parameters.oid()
+	if oid == nil || len(pub.Key) != pub.Parameters.PublicKeySize() {
+		return nil, errors.New("pkcs8: invalid SLH-DSA public key")
+	}
+	return marshalCurvePublicKey(oid, pub.Key), nil
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub *SLHDSAPublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*SLHDSAPublicKey)
+	return ok && pub.Parameters == other.Parameters && bytes.Equal(pub.Key, other.Key)
+}
@@ -0,0 +1,45 @@
+package pkcs8
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"math/big"
+	"sync"
+)
+
+var oidNamedCurveSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+
+var (
+	sm2Once  sync.Once
+	sm2Curve *elliptic.CurveParams
+)
+
+func init() {
+	registerCurve(oidNamedCurveSM2, SM2P256())
+}
+
+// SM2P256 returns the curve of SM2 keys, sm2p256v1 of GB/T 32918.5-2017.
+// SM2 keys are parsed into *ecdsa.PrivateKey and *ecdsa.PublicKey on this
+// curve, and such keys are accepted by the marshal functions.
+//
+// The curve is implemented with the generic, non constant time arithmetic
+// of elliptic.CurveParams, and SM2 signatures differ from ECDSA ones: use
+// the keys with an SM2 implementation, passing it D, X and Y.
+func SM2P256() elliptic.Curve {
+	sm2Once.Do(func() {
+		hex := func(s string) *big.Int {
+			n, _ := new(big.Int).SetString(s, 16)
+			return n
+		}
+		sm2Curve = &elliptic.CurveParams{
+			Name:    "SM2",
+			BitSize: 256,
+			P:       hex("fffffffeffffffffffffffffffffffffffffffff00000000ffffffffffffffff"),
+			N:       hex("fffffffeffffffffffffffffffffffff7203df6b21c6052b53bbf40939d54123"),
+			B:       hex("28e9fa9e9d9f5e344d5a9e4bcf6509a7f39789f515ab8f92ddbcbd414d940e93"),
+			Gx:      hex("32c4ae2c1f1981195f9904466a39c9948fe30bbff2660be1715a4589334c74c7"),
+			Gy:      hex("bc3736a2f4f6779c59bdcee36b692153d0a9877cc62a474002df32e52139f0a0"),
+		}
+	})
+	return sm2Curve
+}
@@ -0,0 +1,118 @@
+package pkcs8
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// sm3Size and sm3BlockSize are the digest and block sizes of SM3, see
+// GB/T 32905-2016.
+const (
+	sm3Size      = 32
+	sm3BlockSize = 64
+)
+
+var sm3IV = [8]uint32{
+	0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600,
+	0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e,
+}
+
+// sm3Digest computes SM3, only used as the hash of the hmacWithSM3 PBKDF2
+// PRF of GmSSL, as it has no crypto.Hash value.
+type sm3Digest struct {
+	h   [8]uint32
+	buf [sm3BlockSize]byte
+	n   int
+	len uint64
+}
+
+func newSM3() hash.Hash {
+	d := new(sm3Digest)
+	d.Reset()
+	return d
+}
+
+func (d *sm3Digest) Size() int      { return sm3Size }
+func (d *sm3Digest) BlockSize() int { return sm3BlockSize }
+
+func (d *sm3Digest) Reset() {
+	d.h = sm3IV
+	d.n = 0
+	d.len = 0
+}
+
+func (d *sm3Digest) Write(p []byte) (int, error) {
+	written := len(p)
+	d.len += uint64(written)
+	if d.n > 0 {
+		c := copy(d.buf[d.n:], p)
+		d.n += c
+		p = p[c:]
+		if d.n < sm3BlockSize {
+			return written, nil
+		}
+		d.block(d.buf[:])
+		d.n = 0
+	}
+	for len(p) >= sm3BlockSize {
+		d.block(p[:sm3BlockSize])
+		p = p[sm3BlockSize:]
+	}
+	d.n = copy(d.buf[:], p)
+	return written, nil
+}
+
+func (d *sm3Digest) Sum(in []byte) []byte {
+	// Pad a copy, so that the caller can keep writing.
+	c := *d
+	var pad [sm3BlockSize + 8]byte
+	pad[0] = 0x80
+	padLen := sm3BlockSize - int((c.len+8)%sm3BlockSize)
+	binary.BigEndian.PutUint64(pad[padLen:], c.len*8)
+	c.Write(pad[:padLen+8])
+	var digest [sm3Size]byte
+	for i, v := range c.h {
+		binary.BigEndian.PutUint32(digest[4*i:], v)
+	}
+	return append(in, digest[:]...)
+}
+
+func (d *sm3Digest) block(p []byte) {
+	var w [68]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[4*i:])
+	}
+	for i := 16; i < 68; i++ {
+		x := w[i-16] ^ w[i-9] ^ bits.RotateLeft32(w[i-3], 15)
+		w[i] = x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) ^ bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+	}
+	a, b, c, e, f, g, h := d.h[0], d.h[1], d.h[2], d.h[4], d.h[5], d.h[6], d.h[7]
+	dd := d.h[3]
+	for i := 0; i < 64; i++ {
+		t := uint32(0x79cc4519)
+		if i >= 16 {
+			t = 0x7a879d8a
+		}
+		ss1 := bits.RotateLeft32(bits.RotateLeft32(a, 12)+e+bits.RotateLeft32(t, i%32), 7)
+		ss2 := ss1 ^ bits.RotateLeft32(a, 12)
+		var ff, gg uint32
+		if i < 16 {
+			ff, gg = a^b^c, e^f^g
+		} else {
+			ff, gg = (a&b)|(a&c)|(b&c), (e&f)|(^e&g)
+		}
+		tt1 := ff + dd + ss2 + (w[i] ^ w[i+4])
+		tt2 := gg + h + ss1 + w[i]
+		dd, c, b, a = c, bits.RotateLeft32(b, 9), a, tt1
+		h, g, f, e = g, bits.RotateLeft32(f, 19), e, tt2^bits.RotateLeft32(tt2, 9)^bits.RotateLeft32(tt2, 17)
+	}
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
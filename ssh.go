@@ -0,0 +1,87 @@
+//go:build !pkcs8_nointegrations
+// +build !pkcs8_nointegrations
+
+package pkcs8
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// MarshalAuthorizedKey returns the OpenSSH public key line, as used in
+// authorized_keys files, for the public half of a private key, e.g.
+// "ssh-ed25519 AAAA... comment". The comment is omitted if empty, and must
+// not contain control characters such as newlines, which would end the line.
+// RSA, ECDSA (P-256, P-384 and P-521) and Ed25519 keys are supported.
+func MarshalAuthorizedKey(priv interface{}, comment string) ([]byte, error) {
+	if strings.IndexFunc(comment, unicode.IsControl) >= 0 {
+		return nil, errors.New("pkcs8: SSH comment contains a control character")
+	}
+	var algo string
+	var blob []byte
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		algo = "ssh-rsa"
+		blob = sshString(nil, []byte(algo))
+		blob = sshMPInt(blob, big.NewInt(int64(k.E)))
+		blob = sshMPInt(blob, k.N)
+	case *ecdsa.PrivateKey:
+		var curve string
+		switch k.Curve {
+		case elliptic.P256():
+			curve = "nistp256"
+		case elliptic.P384():
+			curve = "nistp384"
+		case elliptic.P521():
+			curve = "nistp521"
+		default:
+			return nil, errors.New("pkcs8: unsupported curve for SSH")
+		}
+		algo = "ecdsa-sha2-" + curve
+		blob = sshString(nil, []byte(algo))
+		blob = sshString(blob, []byte(curve))
+		blob = sshString(blob, elliptic.Marshal(k.Curve, k.X, k.Y))
+	case ed25519.PrivateKey:
+		algo = "ssh-ed25519"
+		blob = sshString(nil, []byte(algo))
+		blob = sshString(blob, k.Public().(ed25519.PublicKey))
+	default:
+		return nil, errors.New("pkcs8: unsupported key type for SSH")
+	}
+
+	var line strings.Builder
+	line.WriteString(algo)
+	line.WriteByte(' ')
+	line.WriteString(base64.StdEncoding.EncodeToString(blob))
+	if comment != "" {
+		line.WriteByte(' ')
+		line.WriteString(comment)
+	}
+	line.WriteByte('\n')
+	return []byte(line.String()), nil
+}
+
+// sshString appends s to b as an SSH wire-format string (RFC 4251).
+func sshString(b, s []byte) []byte {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(s)))
+	b = append(b, length[:]...)
+	return append(b, s...)
+}
+
+// sshMPInt appends the non-negative n to b as an SSH wire-format mpint.
+func sshMPInt(b []byte, n *big.Int) []byte {
+	v := n.Bytes()
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		v = append([]byte{0}, v...)
+	}
+	return sshString(b, v)
+}
@@ -0,0 +1,42 @@
+package pkcs8
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// ParsedKey is a key found by ParseAll.
+type ParsedKey struct {
+	Key  crypto.PrivateKey
+	Info *KeyInfo
+	// Start and End delimit the encoding of the key in the input.
+	Start, End int
+}
+
+// ParseAll parses a stream of DER-encoded PKCS#8 private keys concatenated
+// back to back, as exported by Java and some HSMs. Each key may be encrypted
+// with password or not; password can be nil if none is. Parsing stops at the
+// first key that fails, the error giving its offset.
+func ParseAll(der []byte, password []byte) ([]ParsedKey, error) {
+	if len(der) == 0 {
+		return nil, errors.New("pkcs8: no key found")
+	}
+	var keys []ParsedKey
+	for start := 0; start < len(der); {
+		var raw asn1.RawValue
+		rest, err := asn1.Unmarshal(der[start:], &raw)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs8: invalid DER at offset %d: %w", start, err)
+		}
+		end := len(der) - len(rest)
+		key, info, err := ParsePrivateKeyWithInfo(der[start:end], password)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs8: key at offset %d: %w", start, err)
+		}
+		keys = append(keys, ParsedKey{Key: key, Info: info, Start: start, End: end})
+		start = end
+	}
+	return keys, nil
+}
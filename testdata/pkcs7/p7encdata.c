@@ -0,0 +1,36 @@
+/*
+ * p7encdata wraps the unencrypted PKCS#8 key read from stdin in a KeyBag and
+ * encrypts it into a PKCS#7 EncryptedData with PKCS12_pack_p7encdata, the
+ * function openssl pkcs12 encrypts its safes with, writing the DER-encoded
+ * ContentInfo to stdout.
+ *
+ *	cc -o p7encdata p7encdata.c -lcrypto
+ *	./p7encdata PBE-SHA1-3DES < ../openssl/plain-ec.pem > openssl-pbe-sha1-3des-ec.der
+ */
+#include <stdio.h>
+#include <openssl/objects.h>
+#include <openssl/pem.h>
+#include <openssl/pkcs12.h>
+#include <openssl/provider.h>
+
+int main(int argc, char **argv)
+{
+	PKCS8_PRIV_KEY_INFO *p8;
+	STACK_OF(PKCS12_SAFEBAG) *bags = sk_PKCS12_SAFEBAG_new_null();
+	PKCS7 *p7;
+	unsigned char *der = NULL;
+	int nid, len;
+
+	if (argc != 2 || (nid = OBJ_txt2nid(argv[1])) == NID_undef)
+		return 2;
+	OSSL_PROVIDER_load(NULL, "legacy");
+	OSSL_PROVIDER_load(NULL, "default");
+	if ((p8 = PEM_read_PKCS8_PRIV_KEY_INFO(stdin, NULL, NULL, NULL)) == NULL)
+		return 1;
+	sk_PKCS12_SAFEBAG_push(bags, PKCS12_SAFEBAG_create0_p8inf(p8));
+	p7 = PKCS12_pack_p7encdata(nid, "password", -1, NULL, 0, 2048, bags);
+	if (p7 == NULL || (len = i2d_PKCS7(p7, &der)) <= 0)
+		return 1;
+	fwrite(der, 1, len, stdout);
+	return 0;
+}
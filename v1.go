@@ -0,0 +1,95 @@
+package pkcs8
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+)
+
+// The functions below are the API of the first releases. They are kept as
+// thin adapters over ParsePrivateKey and MarshalPrivateKey so that existing
+// callers keep working unchanged.
+
+// ParsePKCS8PrivateKey parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+//
+// Unlike x509.ParsePKCS8PrivateKey, it takes an optional password. Calls
+// written for x509.ParsePKCS8PrivateKey compile unchanged, but the function
+// cannot also have its exact signature without breaking the callers of the
+// first releases, so code that needs a func([]byte) (interface{}, error)
+// value should use x509compat.ParsePKCS8PrivateKey instead.
+func ParsePKCS8PrivateKey(der []byte, v ...[]byte) (interface{}, error) {
+	var password []byte
+	if len(v) > 0 {
+		password = v[0]
+	}
+	privateKey, _, err := ParsePrivateKey(der, password)
+	return privateKey, err
+}
+
+// ParsePKCS8PrivateKeyRSA parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+func ParsePKCS8PrivateKeyRSA(der []byte, v ...[]byte) (*rsa.PrivateKey, error) {
+	key, err := ParsePKCS8PrivateKey(der, v...)
+	if err != nil {
+		return nil, err
+	}
+	typedKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key block is not of type RSA")
+	}
+	return typedKey, nil
+}
+
+// ParsePKCS8PrivateKeyECDSA parses encrypted/unencrypted private keys in PKCS#8 format. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+func ParsePKCS8PrivateKeyECDSA(der []byte, v ...[]byte) (*ecdsa.PrivateKey, error) {
+	key, err := ParsePKCS8PrivateKey(der, v...)
+	if err != nil {
+		return nil, err
+	}
+	typedKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key block is not of type ECDSA")
+	}
+	return typedKey, nil
+}
+
+// ParsePKCS8PrivateKeyEd25519 parses encrypted/unencrypted Ed25519 private keys in PKCS#8 format, such as those of `openssl genpkey -algorithm ed25519`. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+func ParsePKCS8PrivateKeyEd25519(der []byte, v ...[]byte) (ed25519.PrivateKey, error) {
+	key, err := ParsePKCS8PrivateKey(der, v...)
+	if err != nil {
+		return nil, err
+	}
+	typedKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("key block is not of type Ed25519")
+	}
+	return typedKey, nil
+}
+
+// ParsePKCS8PrivateKeySM2 parses encrypted/unencrypted SM2 private keys in PKCS#8 format, such as those written by GmSSL or `openssl genpkey -algorithm SM2`. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+//
+// SM2 keys are parsed into *ecdsa.PrivateKey on the SM2P256 curve.
+func ParsePKCS8PrivateKeySM2(der []byte, v ...[]byte) (*ecdsa.PrivateKey, error) {
+	key, err := ParsePKCS8PrivateKey(der, v...)
+	if err != nil {
+		return nil, err
+	}
+	typedKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || typedKey.Curve != SM2P256() {
+		return nil, errors.New("key block is not of type SM2")
+	}
+	return typedKey, nil
+}
+
+// ConvertPrivateKeyToPKCS8 converts the private key into PKCS#8 format.
+// To encrypt the private key, the password of []byte type should be provided as the second parameter.
+//
+// The supported key types are RSA, ECDSA, Ed25519 and, with Go 1.20 or later, X25519 (*rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey or *ecdh.PrivateKey for priv),
+// as well as the key types the package adds, such as *dsa.PrivateKey and *ecdsa.PrivateKey on the SM2P256 or Brainpool curves.
+func ConvertPrivateKeyToPKCS8(priv interface{}, v ...[]byte) ([]byte, error) {
+	var password []byte
+	if len(v) > 0 {
+		password = v[0]
+	}
+	return MarshalPrivateKey(priv, password, nil)
+}
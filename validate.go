@@ -0,0 +1,40 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+)
+
+// validateKey runs the consistency checks of ParseOpts.FullValidation on a
+// parsed private key. Keys of other types are accepted as is.
+func validateKey(key interface{}) error {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if err := k.Validate(); err != nil {
+			return errors.New("pkcs8: invalid RSA private key: " + err.Error())
+		}
+		if k.Precomputed.Dp == nil {
+			k.Precompute()
+		}
+	case *ecdsa.PrivateKey:
+		params := k.Curve.Params()
+		if k.D.Sign() <= 0 || k.D.Cmp(params.N) >= 0 || !k.Curve.IsOnCurve(k.X, k.Y) {
+			return errors.New("pkcs8: invalid EC private key")
+		}
+		x, y := k.Curve.ScalarBaseMult(k.D.Bytes())
+		if x.Cmp(k.X) != 0 || y.Cmp(k.Y) != 0 {
+			return errors.New("pkcs8: EC public key does not match private key")
+		}
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return errors.New("pkcs8: invalid Ed25519 private key")
+		}
+		if !bytes.Equal(ed25519.NewKeyFromSeed(k.Seed()), k) {
+			return errors.New("pkcs8: Ed25519 public key does not match private key")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package pkcs8
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// WrapToPKCS8 converts a DER-encoded PKCS#1 RSAPrivateKey or SEC 1
+// ECPrivateKey into PKCS#8, encrypted with password and opts unless password
+// is empty.
+//
+// Unlike parsing the key and calling MarshalPrivateKey, the key is not
+// decoded: its encoding is wrapped as is, only moving the curve of an EC key
+// to the algorithm identifier. This avoids materializing the key values as
+// big.Ints in bulk conversion jobs, but also means the key is not validated.
+func WrapToPKCS8(der []byte, password []byte, opts *Opts) ([]byte, error) {
+	var fields []asn1.RawValue
+	if rest, err := asn1.Unmarshal(der, &fields); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+	if len(fields) < 2 || fields[0].Class != asn1.ClassUniversal || fields[0].Tag != asn1.TagInteger {
+		return nil, errors.New("pkcs8: not a PKCS#1 or SEC 1 private key")
+	}
+
+	var pki privateKeyInfo
+	switch second := fields[1]; {
+	case second.Class == asn1.ClassUniversal && second.Tag == asn1.TagInteger && len(fields) >= 9:
+		pki.PrivateKeyAlgorithm.Algorithm = oidPublicKeyRSA
+		if opts == nil || !opts.OmitRSANullParameters {
+			pki.PrivateKeyAlgorithm.Parameters = asn1.NullRawValue
+		}
+		pki.PrivateKey = der
+	case second.Class == asn1.ClassUniversal && second.Tag == asn1.TagOctetString:
+		algorithm, inner, err := unwrapSEC1(fields)
+		if err != nil {
+			return nil, err
+		}
+		// inner is a copy of the key values, unlike der.
+		defer zero(inner)
+		pki.PrivateKeyAlgorithm = algorithm
+		pki.PrivateKey = inner
+	default:
+		return nil, errors.New("pkcs8: not a PKCS#1 or SEC 1 private key")
+	}
+	pkey, err := asn1.Marshal(pki)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) != 0 {
+		// pkey is returned as is without a password.
+		defer zero(pkey)
+	}
+	return encryptPrivateKeyInfo(pkey, password, opts)
+}
+
+// unwrapSEC1 returns the algorithm identifier of the ECPrivateKey made of
+// fields and its encoding without the curve parameters, as PKCS#8 keys
+// carry them in the algorithm identifier.
+func unwrapSEC1(fields []asn1.RawValue) (pkix.AlgorithmIdentifier, []byte, error) {
+	var curve asn1.ObjectIdentifier
+	var inner []byte
+	for _, f := range fields {
+		if f.Class == asn1.ClassContextSpecific && f.Tag == 0 {
+			if rest, err := asn1.Unmarshal(f.Bytes, &curve); err != nil || len(rest) != 0 {
+				return pkix.AlgorithmIdentifier{}, nil, errors.New("pkcs8: EC private key with unsupported curve parameters")
+			}
+			continue
+		}
+		inner = append(inner, f.FullBytes...)
+	}
+	if curve == nil {
+		return pkix.AlgorithmIdentifier{}, nil, errors.New("pkcs8: EC private key without curve parameters")
+	}
+	params, err := asn1.Marshal(curve)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	fieldBytes := inner
+	inner, err = asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: inner})
+	zero(fieldBytes)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, inner, nil
+}
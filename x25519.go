@@ -0,0 +1,25 @@
+//go:build go1.20
+// +build go1.20
+
+package pkcs8
+
+import (
+	"crypto/ecdh"
+	"errors"
+)
+
+// ParsePKCS8PrivateKeyX25519 parses encrypted/unencrypted X25519 private keys in PKCS#8 format, as used by HPKE and WireGuard tooling. To parse encrypted private keys, a password of []byte type should be provided to the function as the second parameter.
+//
+// X25519 keys are parsed into *ecdh.PrivateKey, and only supported when
+// built with Go 1.20 or later.
+func ParsePKCS8PrivateKeyX25519(der []byte, v ...[]byte) (*ecdh.PrivateKey, error) {
+	key, err := ParsePKCS8PrivateKey(der, v...)
+	if err != nil {
+		return nil, err
+	}
+	typedKey, ok := key.(*ecdh.PrivateKey)
+	if !ok || typedKey.Curve() != ecdh.X25519() {
+		return nil, errors.New("key block is not of type X25519")
+	}
+	return typedKey, nil
+}
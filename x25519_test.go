@@ -0,0 +1,155 @@
+//go:build go1.20
+// +build go1.20
+
+package pkcs8_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestX25519(t *testing.T) {
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata", "x25519", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		return block.Bytes
+	}
+	want, err := pkcs8.ParsePKCS8PrivateKeyX25519(read("plain.pem"))
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKeyX25519 returned: %s", err)
+	}
+	key, err := pkcs8.ParsePKCS8PrivateKeyX25519(read("openssl-3.0-default.pem"), []byte("password"))
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKeyX25519 returned: %s", err)
+	}
+	if !want.Equal(key) {
+		t.Error("Decoded key does not match original key")
+	}
+	pub, err := pkcs8.ParsePublicKey(read("public.pem"))
+	if err != nil {
+		t.Fatalf("ParsePublicKey returned: %s", err)
+	}
+	if !key.PublicKey().Equal(pub) {
+		t.Error("public key does not match OpenSSL's")
+	}
+
+	generated, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range [][]byte{nil, []byte("password")} {
+		der, err := pkcs8.ConvertPrivateKeyToPKCS8(generated, password)
+		if err != nil {
+			t.Fatalf("ConvertPrivateKeyToPKCS8 returned: %s", err)
+		}
+		decoded, err := pkcs8.ParsePKCS8PrivateKeyX25519(der, password)
+		if err != nil {
+			t.Fatalf("ParsePKCS8PrivateKeyX25519 returned: %s", err)
+		}
+		if !generated.Equal(decoded) {
+			t.Error("Decoded key does not match original key")
+		}
+	}
+
+	// A OneAsymmetricKey with the public key, as written by HPKE tooling.
+	type oneAsymmetricKey struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+		PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+	}
+	curvePrivateKey, _ := asn1.Marshal(generated.Bytes())
+	for _, test := range []struct {
+		public *ecdh.PublicKey
+		ok     bool
+	}{
+		{generated.PublicKey(), true},
+		{want.PublicKey(), false},
+	} {
+		der, err := asn1.Marshal(oneAsymmetricKey{
+			Version:    1,
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 3, 101, 110}},
+			PrivateKey: curvePrivateKey,
+			PublicKey:  asn1.BitString{Bytes: test.public.Bytes(), BitLength: 256},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = pkcs8.ParsePrivateKeyWithOpts(der, nil, &pkcs8.ParseOpts{VerifyPublicKey: true})
+		if (err == nil) != test.ok {
+			t.Errorf("ParsePrivateKeyWithOpts returned %v, want success %v", err, test.ok)
+		}
+	}
+
+	if _, err := pkcs8.ParsePKCS8PrivateKeyX25519(read("../openssl/plain-ed25519.pem")); err == nil {
+		t.Error("should have failed for an Ed25519 key")
+	}
+}
+
+func TestX25519MLKEM768(t *testing.T) {
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := bytes.Repeat([]byte{0x5a}, pkcs8.MLKEMSeedSize)
+	key := &pkcs8.X25519MLKEM768PrivateKey{
+		MLKEM:  &pkcs8.MLKEMPrivateKey{Parameters: pkcs8.MLKEM768, Seed: seed},
+		X25519: x,
+	}
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("password"), pkcs8.LegacyDefaults())
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	parsed, _, err := pkcs8.ParsePrivateKey(der, []byte("password"))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey returned: %s", err)
+	}
+	if !key.Equal(parsed) {
+		t.Error("Decoded key does not match original key")
+	}
+	info, err := pkcs8.Inspect(der, []byte("password"))
+	if err != nil || info.KeyType != "X25519MLKEM768" {
+		t.Errorf("Inspect returned %+v, %v", info, err)
+	}
+
+	// Both keys are concatenated in the privateKey field, the ML-KEM seed
+	// first.
+	der, err = pkcs8.MarshalPrivateKey(key, nil, nil)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey returned: %s", err)
+	}
+	prefix, _ := hex.DecodeString("3071020100300a06082b0601050507063a0460")
+	want := append(append(prefix, seed...), x.Bytes()...)
+	if !bytes.Equal(der, want) {
+		t.Errorf("got %x", der)
+	}
+	expanded := &pkcs8.MLKEMPrivateKey{Parameters: pkcs8.MLKEM768, ExpandedKey: make([]byte, pkcs8.MLKEM768.ExpandedKeySize())}
+	if _, err := pkcs8.MarshalPrivateKey(&pkcs8.X25519MLKEM768PrivateKey{MLKEM: expanded, X25519: x}, nil, nil); err == nil {
+		t.Error("MarshalPrivateKey accepted an ML-KEM key without a seed")
+	}
+
+	pub := &pkcs8.X25519MLKEM768PublicKey{
+		MLKEM:  &pkcs8.MLKEMPublicKey{Parameters: pkcs8.MLKEM768, Key: make([]byte, pkcs8.MLKEM768.PublicKeySize())},
+		X25519: x.PublicKey(),
+	}
+	spki, err := pkcs8.MarshalPublicKey(pub)
+	if err != nil {
+		t.Fatalf("MarshalPublicKey returned: %s", err)
+	}
+	if parsed, err := pkcs8.ParsePublicKey(spki); err != nil || !pub.Equal(parsed) {
+		t.Errorf("ParsePublicKey returned %v", err)
+	}
+}
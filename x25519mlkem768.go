@@ -0,0 +1,112 @@
+//go:build go1.20
+// +build go1.20
+
+package pkcs8
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/x509/pkix"
+	"errors"
+)
+
+// x25519Size is the size of X25519 private and public keys.
+const x25519Size = 32
+
+// X25519MLKEM768PrivateKey is a composite ML-KEM-768 and X25519 private key,
+// the id-MLKEM768-X25519-SHA3-256 key of the LAMPS composite KEM draft used
+// by hybrid key exchanges such as the X25519MLKEM768 group of TLS. It is
+// returned by the parse functions and accepted by the marshal functions,
+// which store both keys in one PrivateKeyInfo, the ML-KEM seed followed by
+// the raw X25519 key.
+type X25519MLKEM768PrivateKey struct {
+	// MLKEM is the ML-KEM-768 key, of which only the seed is stored.
+	MLKEM  *MLKEMPrivateKey
+	X25519 *ecdh.PrivateKey
+}
+
+// X25519MLKEM768PublicKey is a composite ML-KEM-768 and X25519 public key.
+// It is returned by ParsePublicKey and accepted by MarshalPublicKey.
+type X25519MLKEM768PublicKey struct {
+	MLKEM  *MLKEMPublicKey
+	X25519 *ecdh.PublicKey
+}
+
+func init() {
+	keyTypes = append(keyTypes, keyType{
+		oid: oidMLKEM768X25519,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			if len(pki.PrivateKeyAlgorithm.Parameters.FullBytes) != 0 {
+				return nil, errors.New("pkcs8: invalid X25519MLKEM768 private key parameters")
+			}
+			key := pki.PrivateKey
+			if len(key) != MLKEMSeedSize+x25519Size {
+				return nil, errors.New("pkcs8: invalid X25519MLKEM768 private key")
+			}
+			x, err := ecdh.X25519().NewPrivateKey(key[MLKEMSeedSize:])
+			if err != nil {
+				return nil, err
+			}
+			return &X25519MLKEM768PrivateKey{
+				MLKEM:  &MLKEMPrivateKey{Parameters: MLKEM768, Seed: key[:MLKEMSeedSize:MLKEMSeedSize]},
+				X25519: x,
+			}, nil
+		},
+		parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+			size := MLKEM768.PublicKeySize()
+			key, err := parseCurvePublicKey(spki, size+x25519Size)
+			if err != nil {
+				return nil, err
+			}
+			x, err := ecdh.X25519().NewPublicKey(key[size:])
+			if err != nil {
+				return nil, err
+			}
+			return &X25519MLKEM768PublicKey{
+				MLKEM:  &MLKEMPublicKey{Parameters: MLKEM768, Key: key[:size:size]},
+				X25519: x,
+			}, nil
+		},
+	})
+}
+
+func (priv *X25519MLKEM768PrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	if priv.MLKEM == nil || priv.MLKEM.Parameters != MLKEM768 || len(priv.MLKEM.Seed) != MLKEMSeedSize {
+		return nil, errors.New("pkcs8: X25519MLKEM768 key requires an ML-KEM-768 seed")
+	}
+	if priv.X25519 == nil || priv.X25519.Curve() != ecdh.X25519() {
+		return nil, errors.New("pkcs8: X25519MLKEM768 key requires an X25519 key")
+	}
+	key := make([]byte, 0, MLKEMSeedSize+x25519Size)
+	key = append(key, priv.MLKEM.Seed...)
+	key = append(key, priv.X25519.Bytes()...)
+	return &privateKeyInfo{
+		PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidMLKEM768X25519},
+		PrivateKey:          key,
+	}, nil
+}
+
+// Equal reports whether priv and x are the same key.
+func (priv *X25519MLKEM768PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*X25519MLKEM768PrivateKey)
+	return ok && priv.MLKEM != nil && priv.X25519 != nil && other.MLKEM != nil && other.X25519 != nil &&
+		priv.MLKEM.Equal(other.MLKEM) && priv.X25519.Equal(other.X25519)
+}
+
+func (pub *X25519MLKEM768PublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	if pub.MLKEM == nil || pub.MLKEM.Parameters != MLKEM768 || len(pub.MLKEM.Key) != MLKEM768.PublicKeySize() ||
+		pub.X25519 == nil || pub.X25519.Curve() != ecdh.X25519() {
+		return nil, errors.New("pkcs8: invalid X25519MLKEM768 public key")
+	}
+	key := make([]byte, 0, len(pub.MLKEM.Key)+x25519Size)
+	key = append(key, pub.MLKEM.Key...)
+	key = append(key, pub.X25519.Bytes()...)
+	return marshalCurvePublicKey(oidMLKEM768X25519, key), nil
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub *X25519MLKEM768PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*X25519MLKEM768PublicKey)
+	return ok && pub.MLKEM != nil && pub.X25519 != nil && other.MLKEM != nil && other.X25519 != nil &&
+		pub.MLKEM.Equal(other.MLKEM) && pub.X25519.Equal(other.X25519)
+}
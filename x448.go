@@ -0,0 +1,72 @@
+package pkcs8
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+)
+
+// X448KeySize is the size of X448 private and public keys, see RFC 7748.
+const X448KeySize = 56
+
+// X448PublicKey is an X448 public key, a little-endian u-coordinate.
+type X448PublicKey []byte
+
+// X448PrivateKey is an X448 private key, the scalar of RFC 7748. It is
+// returned by the parse functions for id-X448 keys and accepted by the
+// marshal functions.
+//
+// The package does not implement X448: the key only carries its raw
+// material, to be used with an X448 implementation such as
+// github.com/cloudflare/circl/dh/x448. It has no Public method.
+type X448PrivateKey []byte
+
+var oidPublicKeyX448 = asn1.ObjectIdentifier{1, 3, 101, 111}
+
+func init() {
+	keyTypes = append(keyTypes, keyType{
+		oid: oidPublicKeyX448,
+		parsePrivate: func(pki *privateKeyInfo) (crypto.PrivateKey, error) {
+			key, err := parseCurvePrivateKey(pki, X448KeySize)
+			if err != nil {
+				return nil, err
+			}
+			return X448PrivateKey(key), nil
+		},
+		parsePublic: func(spki *subjectPublicKeyInfo) (crypto.PublicKey, error) {
+			key, err := parseCurvePublicKey(spki, X448KeySize)
+			if err != nil {
+				return nil, err
+			}
+			return X448PublicKey(key), nil
+		},
+	})
+}
+
+// Equal reports whether priv and x are the same key.
+func (priv X448PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(X448PrivateKey)
+	return ok && subtle.ConstantTimeCompare(priv, other) == 1
+}
+
+func (priv X448PrivateKey) marshalPKCS8() (*privateKeyInfo, error) {
+	if len(priv) != X448KeySize {
+		return nil, errors.New("pkcs8: invalid X448 private key")
+	}
+	return marshalCurvePrivateKey(oidPublicKeyX448, priv)
+}
+
+// Equal reports whether pub and x are the same key.
+func (pub X448PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(X448PublicKey)
+	return ok && bytes.Equal(pub, other)
+}
+
+func (pub X448PublicKey) marshalPKIX() (*subjectPublicKeyInfo, error) {
+	if len(pub) != X448KeySize {
+		return nil, errors.New("pkcs8: invalid X448 public key")
+	}
+	return marshalCurvePublicKey(oidPublicKeyX448, pub), nil
+}
@@ -0,0 +1,11 @@
+package pkcs8
+
+// MarshalPKCS8PrivateKey encodes an unencrypted private key into DER-encoded
+// PKCS#8. It has the signature of x509.MarshalPKCS8PrivateKey and produces
+// the same output, so that code can switch imports without changing call
+// sites; use MarshalPrivateKey to encrypt the key. ParsePKCS8PrivateKey
+// keeps its optional password: the parser with the signature of
+// x509.ParsePKCS8PrivateKey is x509compat.ParsePKCS8PrivateKey.
+func MarshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
+	return MarshalPrivateKey(key, nil, nil)
+}
@@ -0,0 +1,37 @@
+// Package x509compat provides the key encoding functions of crypto/x509
+// with identical signatures, backed by package pkcs8, so that a codebase
+// can route all of its key parsing through one layer by switching imports.
+//
+// The functions accept everything their crypto/x509 counterparts do, plus
+// the algorithms package pkcs8 adds, such as EC keys restricted to ECDH or
+// ECMQV. Encrypted keys are not accepted here; use package pkcs8 directly.
+package x509compat
+
+import (
+	"github.com/youmark/pkcs8"
+)
+
+// ParsePKCS8PrivateKey parses an unencrypted private key in PKCS#8,
+// ASN.1 DER form, as x509.ParsePKCS8PrivateKey does.
+func ParsePKCS8PrivateKey(der []byte) (key interface{}, err error) {
+	key, _, err = pkcs8.ParsePrivateKey(der, nil)
+	return key, err
+}
+
+// MarshalPKCS8PrivateKey converts a private key to PKCS#8, ASN.1 DER form,
+// as x509.MarshalPKCS8PrivateKey does.
+func MarshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
+	return pkcs8.MarshalPKCS8PrivateKey(key)
+}
+
+// ParsePKIXPublicKey parses a public key in PKIX, ASN.1 DER form, as
+// x509.ParsePKIXPublicKey does.
+func ParsePKIXPublicKey(derBytes []byte) (pub interface{}, err error) {
+	return pkcs8.ParsePublicKey(derBytes)
+}
+
+// MarshalPKIXPublicKey converts a public key to PKIX, ASN.1 DER form, as
+// x509.MarshalPKIXPublicKey does.
+func MarshalPKIXPublicKey(pub interface{}) ([]byte, error) {
+	return pkcs8.MarshalPublicKey(pub)
+}